@@ -0,0 +1,71 @@
+package activity
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CameronHonis/service"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sink receives the server's domain events, for the parts of the stack that follow what the bots
+// are doing
+type Sink interface {
+	Write(event service.EventI) error
+	Close() error
+}
+
+// Record is one line written by a JSONLinesSink
+type Record struct {
+	Time    time.Time            `json:"time"`
+	Variant service.EventVariant `json:"variant"`
+	Payload interface{}          `json:"payload"`
+}
+
+// JSONLinesSink appends each event to a JSON lines file. Unlike the journal, lines are not synced
+// to disk one by one, so the last few events may be lost if the host crashes.
+type JSONLinesSink struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+func OpenJSONLinesSink(path string) (*JSONLinesSink, error) {
+	file, openErr := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if openErr != nil {
+		return nil, fmt.Errorf("could not open event sink %s: %s", path, openErr)
+	}
+	return &JSONLinesSink{file: file}, nil
+}
+
+func (s *JSONLinesSink) Write(event service.EventI) error {
+	recordJson, marshalErr := json.Marshal(&Record{
+		Time:    time.Now(),
+		Variant: event.Variant(),
+		Payload: event.Payload(),
+	})
+	if marshalErr != nil {
+		return fmt.Errorf("could not marshal %s event: %s", event.Variant(), marshalErr)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return fmt.Errorf("event sink is closed")
+	}
+	if _, writeErr := s.file.Write(append(recordJson, '\n')); writeErr != nil {
+		return fmt.Errorf("could not write %s event: %s", event.Variant(), writeErr)
+	}
+	return nil
+}
+
+func (s *JSONLinesSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	closeErr := s.file.Close()
+	s.file = nil
+	return closeErr
+}
@@ -0,0 +1,72 @@
+package activity_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/CameronHonis/chess-bot-server/activity"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	"github.com/CameronHonis/service"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+)
+
+func ReadRecords(path string) []map[string]interface{} {
+	file, openErr := os.Open(path)
+	Expect(openErr).ToNot(HaveOccurred())
+	defer file.Close()
+	records := make([]map[string]interface{}, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		record := make(map[string]interface{})
+		Expect(json.Unmarshal(scanner.Bytes(), &record)).To(Succeed())
+		records = append(records, record)
+	}
+	return records
+}
+
+var _ = Describe("JSONLinesSink", func() {
+	var path string
+	var sink *activity.JSONLinesSink
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "events.jsonl")
+		var openErr error
+		sink, openErr = activity.OpenJSONLinesSink(path)
+		Expect(openErr).ToNot(HaveOccurred())
+	})
+	It("writes each event as a line", func() {
+		eval := 35
+		Expect(sink.Write(service.NewEvent(bot_manager.MOVE_GENERATED, &bot_manager.MoveGeneratedPayload{
+			BotName: "stockfish",
+			MatchId: "match",
+			Move:    "e2e4",
+			Eval:    &eval,
+		}))).To(Succeed())
+		Expect(sink.Write(service.NewEvent(bot_manager.ENGINE_FALLBACK, &bot_manager.EngineFallbackPayload{
+			BotName: "stockfish",
+			Reason:  "primary engine failed",
+		}))).To(Succeed())
+		Expect(sink.Close()).To(Succeed())
+
+		records := ReadRecords(path)
+		Expect(records).To(HaveLen(2))
+		Expect(records[0]["variant"]).To(Equal("MOVE_GENERATED"))
+		Expect(records[0]["payload"]).To(HaveKeyWithValue("move", "e2e4"))
+		Expect(records[0]["payload"]).To(HaveKeyWithValue("eval", 35.))
+		Expect(records[1]["variant"]).To(Equal("ENGINE_FALLBACK"))
+	})
+	It("appends to an existing file", func() {
+		Expect(sink.Write(service.NewEvent(bot_manager.ENGINE_CRASHED, &bot_manager.EngineCrashedPayload{}))).To(Succeed())
+		Expect(sink.Close()).To(Succeed())
+		reopened, openErr := activity.OpenJSONLinesSink(path)
+		Expect(openErr).ToNot(HaveOccurred())
+		Expect(reopened.Write(service.NewEvent(bot_manager.ENGINE_CRASHED, &bot_manager.EngineCrashedPayload{}))).To(Succeed())
+		Expect(reopened.Close()).To(Succeed())
+		Expect(ReadRecords(path)).To(HaveLen(2))
+	})
+	It("refuses events once closed", func() {
+		Expect(sink.Close()).To(Succeed())
+		Expect(sink.Write(service.NewEvent(bot_manager.ENGINE_CRASHED, &bot_manager.EngineCrashedPayload{}))).ToNot(Succeed())
+	})
+})
@@ -1,11 +1,28 @@
 package app
 
 import (
+	"fmt"
 	"github.com/CameronHonis/chess-bot-server/arbitrator_client"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	"github.com/CameronHonis/log"
 	. "github.com/CameronHonis/marker"
 	"github.com/CameronHonis/service"
 )
 
+const ENV_APP = "APP"
+
+// DOMAIN_EVENTS are the events written to the sink, which report what the bots are doing
+var DOMAIN_EVENTS = []service.EventVariant{
+	arbitrator_client.CHALLENGE_RECEIVED,
+	arbitrator_client.CHALLENGE_ACCEPTED,
+	arbitrator_client.CHALLENGE_DECLINED,
+	arbitrator_client.MATCH_STARTED,
+	arbitrator_client.MATCH_FINISHED,
+	bot_manager.MOVE_GENERATED,
+	bot_manager.ENGINE_FALLBACK,
+	bot_manager.ENGINE_CRASHED,
+}
+
 type AppServiceI interface {
 	service.ServiceI
 }
@@ -14,6 +31,7 @@ type AppService struct {
 	service.Service
 	__dependencies__ Marker
 	ArbitratorClient arbitrator_client.ArbitratorClientI
+	LogService       log.LoggerServiceI
 
 	__state__ Marker
 }
@@ -23,3 +41,31 @@ func NewAppService(config *AppServiceConfig) *AppService {
 	s.Service = *service.NewService(s, config)
 	return s
 }
+
+func (s *AppService) OnBuild() {
+	if s.Config().(*AppServiceConfig).Sink() == nil {
+		return
+	}
+	for _, variant := range DOMAIN_EVENTS {
+		s.AddEventListener(variant, WriteToSink)
+	}
+}
+
+// OnStop closes the sink once the server has drained
+func (s *AppService) OnStop() {
+	sink := s.Config().(*AppServiceConfig).Sink()
+	if sink == nil {
+		return
+	}
+	if closeErr := sink.Close(); closeErr != nil {
+		s.LogService.LogRed(ENV_APP, fmt.Sprintf("could not close event sink: %s", closeErr))
+	}
+}
+
+var WriteToSink = func(self service.ServiceI, event service.EventI) bool {
+	s := self.(*AppService)
+	if writeErr := s.Config().(*AppServiceConfig).Sink().Write(event); writeErr != nil {
+		s.LogService.LogRed(ENV_APP, writeErr.Error())
+	}
+	return true
+}
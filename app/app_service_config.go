@@ -1,11 +1,23 @@
 package app
 
-import "github.com/CameronHonis/service"
+import (
+	"github.com/CameronHonis/chess-bot-server/activity"
+	"github.com/CameronHonis/service"
+)
 
 type AppServiceConfig struct {
 	service.ConfigI
+	sink activity.Sink
 }
 
-func NewAppServiceConfig() *AppServiceConfig {
-	return &AppServiceConfig{}
+// NewAppServiceConfig configures where the server's domain events are written. A nil sink drops
+// them.
+func NewAppServiceConfig(sink activity.Sink) *AppServiceConfig {
+	return &AppServiceConfig{
+		sink: sink,
+	}
+}
+
+func (c *AppServiceConfig) Sink() activity.Sink {
+	return c.sink
 }
@@ -2,10 +2,20 @@ package app
 
 import (
 	"fmt"
+	"github.com/CameronHonis/chess-bot-server/activity"
 	arbc "github.com/CameronHonis/chess-bot-server/arbitrator_client"
 	botmgr "github.com/CameronHonis/chess-bot-server/bot_manager"
+	cpolicy "github.com/CameronHonis/chess-bot-server/challenge_policy"
+	gpolicy "github.com/CameronHonis/chess-bot-server/game_policy"
+	"github.com/CameronHonis/chess-bot-server/journal"
+	"github.com/CameronHonis/chess-bot-server/matchmaking"
+	rsched "github.com/CameronHonis/chess-bot-server/resource_scheduler"
 	"github.com/CameronHonis/log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func LoggerConfig() *log.LoggerConfig {
@@ -13,12 +23,23 @@ func LoggerConfig() *log.LoggerConfig {
 	logConfigBuilder.WithDecorator(arbc.ENV_ARBITRATOR_CLIENT, log.WrapGreen)
 	logConfigBuilder.WithDecorator(botmgr.ENV_BOT_CLIENT, log.WrapBlue)
 	logConfigBuilder.WithDecorator(botmgr.ENV_BOT_MANAGER, log.WrapCyan)
+	logConfigBuilder.WithDecorator(rsched.ENV_RESOURCE_SCHEDULER, log.WrapMagenta)
+	logConfigBuilder.WithDecorator(cpolicy.ENV_CHALLENGE_POLICY, log.WrapYellow)
+	logConfigBuilder.WithDecorator(gpolicy.ENV_GAME_POLICY, log.WrapYellow)
+	logConfigBuilder.WithDecorator(journal.ENV_JOURNAL, log.WrapMagenta)
+	logConfigBuilder.WithDecorator(ENV_APP, log.WrapYellow)
 	//logConfigBuilder.WithMutedEnv("arbitrator_client")
 	//logConfigBuilder.WithMutedEnv("bot_manager")
 
 	return logConfigBuilder.Build()
 }
 
+// ArbitratorClientConfig reads the arbitrator's address from ARBITRATOR_DOMAIN and
+// ARBITRATOR_PORT, and the games the server seeks on its own from the JSON schedule at
+// MATCHMAKING_SCHEDULE_PATH. The schedule is checked every MATCHMAKING_INTERVAL, a duration like
+// "5s". Reconnects back off from ARBITRATOR_BACKOFF_INITIAL up to ARBITRATOR_BACKOFF_MAX.
+// ARBITRATOR_FAILURE_POLICY chooses how to respond when the server cannot play, formatted like
+// "auth_upgrade_denied=retry,subscribe_denied=abandon_match".
 func ArbitratorClientConfig() *arbc.ArbitratorClientConfig {
 	domainVal, domainExists := os.LookupEnv("ARBITRATOR_DOMAIN")
 	if !domainExists {
@@ -28,21 +49,272 @@ func ArbitratorClientConfig() *arbc.ArbitratorClientConfig {
 	if !portExists {
 		portVal = "8080"
 	}
-	return arbc.NewArbitratorClientConfig("secret", fmt.Sprintf("%s:%s", domainVal, portVal))
+	var schedule *matchmaking.Schedule
+	if schedulePath, schedulePathExists := os.LookupEnv("MATCHMAKING_SCHEDULE_PATH"); schedulePathExists {
+		var loadErr error
+		schedule, loadErr = matchmaking.LoadSchedule(schedulePath)
+		if loadErr != nil {
+			panic(fmt.Sprintf("could not load matchmaking schedule: %s", loadErr))
+		}
+	}
+	var seekInterval time.Duration
+	if intervalVal, intervalExists := os.LookupEnv("MATCHMAKING_INTERVAL"); intervalExists {
+		if parsedInterval, parseErr := time.ParseDuration(intervalVal); parseErr == nil {
+			seekInterval = parsedInterval
+		}
+	}
+	backoff := &arbc.Backoff{}
+	for envName, delay := range map[string]*time.Duration{
+		"ARBITRATOR_BACKOFF_INITIAL": &backoff.Initial,
+		"ARBITRATOR_BACKOFF_MAX":     &backoff.Max,
+	} {
+		if delayVal, delayExists := os.LookupEnv(envName); delayExists {
+			if parsedDelay, parseErr := time.ParseDuration(delayVal); parseErr == nil {
+				*delay = parsedDelay
+			}
+		}
+	}
+	var failures arbc.FailurePolicy
+	if policyVal, policyExists := os.LookupEnv("ARBITRATOR_FAILURE_POLICY"); policyExists {
+		var parseErr error
+		failures, parseErr = arbc.ParseFailurePolicy(policyVal)
+		if parseErr != nil {
+			panic(fmt.Sprintf("could not parse failure policy: %s", parseErr))
+		}
+	}
+	return arbc.NewArbitratorClientConfig("secret", fmt.Sprintf("%s:%s", domainVal, portVal), schedule, seekInterval,
+		backoff, DialOptions(), Heartbeat(), failures)
+}
+
+// DialOptions connects over wss when ARBITRATOR_TLS is set or any certificate is configured.
+// ARBITRATOR_CA_PATH trusts an extra PEM bundle, ARBITRATOR_CLIENT_CERT_PATH and
+// ARBITRATOR_CLIENT_KEY_PATH present a client certificate, ARBITRATOR_PATH overrides the /ws
+// endpoint and ARBITRATOR_HEADERS adds handshake headers formatted like "X-Token=abc,X-Env=prod".
+func DialOptions() *arbc.DialOptions {
+	_, isSecure := os.LookupEnv("ARBITRATOR_TLS")
+	options := &arbc.DialOptions{
+		Path:           os.Getenv("ARBITRATOR_PATH"),
+		CACertPath:     os.Getenv("ARBITRATOR_CA_PATH"),
+		ClientCertPath: os.Getenv("ARBITRATOR_CLIENT_CERT_PATH"),
+		ClientKeyPath:  os.Getenv("ARBITRATOR_CLIENT_KEY_PATH"),
+		Headers:        make(http.Header),
+	}
+	options.IsSecure = isSecure || options.CACertPath != "" || options.ClientCertPath != ""
+	if headersVal, headersExist := os.LookupEnv("ARBITRATOR_HEADERS"); headersExist {
+		for _, header := range strings.Split(headersVal, ",") {
+			headerName, headerVal, isPair := strings.Cut(strings.TrimSpace(header), "=")
+			if isPair && headerName != "" {
+				options.Headers.Add(headerName, headerVal)
+			}
+		}
+	}
+	return options
+}
+
+// Heartbeat reads ARBITRATOR_PING_INTERVAL, ARBITRATOR_PONG_TIMEOUT and ARBITRATOR_WRITE_TIMEOUT
+// as durations like "10s". Unset intervals take their defaults.
+func Heartbeat() *arbc.Heartbeat {
+	heartbeat := &arbc.Heartbeat{}
+	for envName, interval := range map[string]*time.Duration{
+		"ARBITRATOR_PING_INTERVAL": &heartbeat.PingInterval,
+		"ARBITRATOR_PONG_TIMEOUT":  &heartbeat.PongTimeout,
+		"ARBITRATOR_WRITE_TIMEOUT": &heartbeat.WriteTimeout,
+	} {
+		if intervalVal, intervalExists := os.LookupEnv(envName); intervalExists {
+			if parsedInterval, parseErr := time.ParseDuration(intervalVal); parseErr == nil {
+				*interval = parsedInterval
+			}
+		}
+	}
+	return heartbeat
+}
+
+// ResourceSchedulerConfig defaults to every core on the host and a quarter of its memory for
+// engine hash tables. ENGINE_THREADS and ENGINE_HASH_MB override the detected budget.
+func ResourceSchedulerConfig() *rsched.ResourceSchedulerConfig {
+	threads := rsched.HostThreads()
+	if threadsVal, threadsExists := os.LookupEnv("ENGINE_THREADS"); threadsExists {
+		if parsedThreads, parseErr := strconv.ParseUint(threadsVal, 10, 32); parseErr == nil {
+			threads = uint(parsedThreads)
+		}
+	}
+
+	var hashMb uint = 16
+	if memMb, memErr := rsched.HostMemoryMb(); memErr == nil {
+		hashMb = memMb / 4
+	}
+	if hashVal, hashExists := os.LookupEnv("ENGINE_HASH_MB"); hashExists {
+		if parsedHash, parseErr := strconv.ParseUint(hashVal, 10, 32); parseErr == nil {
+			hashMb = uint(parsedHash)
+		}
+	}
+
+	_, favorLowClock := os.LookupEnv("ENGINE_FAVOR_LOW_CLOCK")
+	return rsched.NewResourceSchedulerConfig(threads, hashMb, favorLowClock)
+}
+
+// BotManagerConfig reads the bot that takes over when an engine fails to move from
+// ENGINE_FALLBACK, and the fraction of the clock each engine gets from ENGINE_TIME_FRACTION.
+// Setting ENGINE_EASY_MOVES lets bots play obvious recaptures without searching.
+func BotManagerConfig() *botmgr.BotManagerConfig {
+	fallbackBotName, fallbackExists := os.LookupEnv("ENGINE_FALLBACK")
+	if !fallbackExists {
+		fallbackBotName = "alphabeta"
+	}
+
+	timeFraction := 0.
+	if fractionVal, fractionExists := os.LookupEnv("ENGINE_TIME_FRACTION"); fractionExists {
+		if parsedFraction, parseErr := strconv.ParseFloat(fractionVal, 64); parseErr == nil {
+			timeFraction = parsedFraction
+		}
+	}
+	_, isEasyMoves := os.LookupEnv("ENGINE_EASY_MOVES")
+	return botmgr.NewBotManagerConfig(fallbackBotName, timeFraction, isEasyMoves, AdmissionLimits(), ReaperTimeouts())
+}
+
+// ReaperTimeouts reads BOTS_PENDING_TIMEOUT, BOTS_CLOSED_GRACE, BOTS_STALLED_TIMEOUT,
+// BOTS_STALLED_UNTIMED_TIMEOUT, BOTS_STALE_RESUME_TIMEOUT and BOTS_REAP_INTERVAL as durations like
+// "90s". Unset timeouts take their defaults.
+func ReaperTimeouts() *botmgr.ReaperTimeouts {
+	timeouts := &botmgr.ReaperTimeouts{}
+	for envName, timeout := range map[string]*time.Duration{
+		"BOTS_PENDING_TIMEOUT":         &timeouts.PendingChallenge,
+		"BOTS_CLOSED_GRACE":            &timeouts.ClosedChallengeGrace,
+		"BOTS_STALLED_TIMEOUT":         &timeouts.StalledMatch,
+		"BOTS_STALLED_UNTIMED_TIMEOUT": &timeouts.StalledUntimedMatch,
+		"BOTS_STALE_RESUME_TIMEOUT":    &timeouts.StaleResume,
+		"BOTS_REAP_INTERVAL":           &timeouts.Interval,
+	} {
+		if timeoutVal, timeoutExists := os.LookupEnv(envName); timeoutExists {
+			if parsedTimeout, parseErr := time.ParseDuration(timeoutVal); parseErr == nil {
+				*timeout = parsedTimeout
+			}
+		}
+	}
+	return timeouts
+}
+
+// AdmissionLimits reads BOTS_MAX, BOTS_MAX_PER_CHALLENGER, BOTS_MAX_HOST_LOAD and
+// BOTS_MAX_BY_ENGINE, which is formatted like "stockfish=2,mila=1". Unset limits are unlimited.
+func AdmissionLimits() *botmgr.AdmissionLimits {
+	limits := &botmgr.AdmissionLimits{
+		MaxBotsByEngine: make(map[string]uint),
+	}
+	if maxVal, maxExists := os.LookupEnv("BOTS_MAX"); maxExists {
+		if parsedMax, parseErr := strconv.ParseUint(maxVal, 10, 32); parseErr == nil {
+			limits.MaxBots = uint(parsedMax)
+		}
+	}
+	if maxVal, maxExists := os.LookupEnv("BOTS_MAX_PER_CHALLENGER"); maxExists {
+		if parsedMax, parseErr := strconv.ParseUint(maxVal, 10, 32); parseErr == nil {
+			limits.MaxBotsPerChallenger = uint(parsedMax)
+		}
+	}
+	if loadVal, loadExists := os.LookupEnv("BOTS_MAX_HOST_LOAD"); loadExists {
+		if parsedLoad, parseErr := strconv.ParseFloat(loadVal, 64); parseErr == nil {
+			limits.MaxHostLoad = parsedLoad
+		}
+	}
+	if byEngineVal, byEngineExists := os.LookupEnv("BOTS_MAX_BY_ENGINE"); byEngineExists {
+		for _, engineLimit := range strings.Split(byEngineVal, ",") {
+			engineName, maxVal, _ := strings.Cut(strings.TrimSpace(engineLimit), "=")
+			if parsedMax, parseErr := strconv.ParseUint(maxVal, 10, 32); parseErr == nil {
+				limits.MaxBotsByEngine[engineName] = uint(parsedMax)
+			}
+		}
+	}
+	return limits
+}
+
+// ChallengePolicyConfig loads the challenge acceptance rules from the JSON file at
+// CHALLENGE_POLICY_PATH. Without a policy file, every challenge is accepted.
+func ChallengePolicyConfig() *cpolicy.ChallengePolicyConfig {
+	policyPath, policyPathExists := os.LookupEnv("CHALLENGE_POLICY_PATH")
+	if !policyPathExists {
+		return cpolicy.NewChallengePolicyConfig(nil)
+	}
+	policy, loadErr := cpolicy.LoadPolicy(policyPath)
+	if loadErr != nil {
+		panic(fmt.Sprintf("could not load challenge policy: %s", loadErr))
+	}
+	return cpolicy.NewChallengePolicyConfig(policy)
+}
+
+// GamePolicyConfig loads each bot's draw, resignation and rematch policy from the JSON file at
+// GAME_POLICY_PATH. Without a policy file, the bots decline draws and rematches and never resign.
+func GamePolicyConfig() *gpolicy.GamePolicyConfig {
+	policyPath, policyPathExists := os.LookupEnv("GAME_POLICY_PATH")
+	if !policyPathExists {
+		return gpolicy.NewGamePolicyConfig(nil)
+	}
+	policies, loadErr := gpolicy.LoadPolicies(policyPath)
+	if loadErr != nil {
+		panic(fmt.Sprintf("could not load game policy: %s", loadErr))
+	}
+	return gpolicy.NewGamePolicyConfig(policies)
+}
+
+// JournalConfig reads the journal path from JOURNAL_PATH, defaulting to journal.jsonl in the
+// working directory. Setting JOURNAL_PATH to an empty string disables the journal. The journal is
+// compacted after growing by JOURNAL_COMPACT_BYTES, defaulting to journal.DEFAULT_COMPACT_BYTES.
+func JournalConfig() *journal.JournalConfig {
+	journalPath, journalPathExists := os.LookupEnv("JOURNAL_PATH")
+	if !journalPathExists {
+		journalPath = "journal.jsonl"
+	}
+	var compactBytes int64
+	if compactVal, compactExists := os.LookupEnv("JOURNAL_COMPACT_BYTES"); compactExists {
+		if parsedCompact, parseErr := strconv.ParseInt(compactVal, 10, 64); parseErr == nil {
+			compactBytes = parsedCompact
+		}
+	}
+	return journal.NewJournalConfig(journalPath, compactBytes)
+}
+
+// EventSink writes the domain events to the JSON lines file at EVENTS_PATH. Without EVENTS_PATH,
+// the events are dropped.
+func EventSink() activity.Sink {
+	eventsPath, eventsPathExists := os.LookupEnv("EVENTS_PATH")
+	if !eventsPathExists || eventsPath == "" {
+		return nil
+	}
+	sink, openErr := activity.OpenJSONLinesSink(eventsPath)
+	if openErr != nil {
+		panic(fmt.Sprintf("could not open event sink: %s", openErr))
+	}
+	return sink
 }
 
 func Setup() *AppService {
 	logService := log.NewLoggerService(LoggerConfig())
 
-	botManager := botmgr.NewBotManager(botmgr.NewBotManagerConfig())
+	resourceScheduler := rsched.NewResourceScheduler(ResourceSchedulerConfig())
+	resourceScheduler.AddDependency(logService)
+
+	botJournal := journal.NewJournal(JournalConfig())
+	botJournal.AddDependency(logService)
+
+	botManager := botmgr.NewBotManager(BotManagerConfig())
 	botManager.AddDependency(logService)
+	botManager.AddDependency(resourceScheduler)
+	botManager.AddDependency(botJournal)
+
+	challengePolicy := cpolicy.NewChallengePolicy(ChallengePolicyConfig())
+	challengePolicy.AddDependency(logService)
+
+	gamePolicy := gpolicy.NewGamePolicy(GamePolicyConfig())
+	gamePolicy.AddDependency(logService)
 
 	arbClient := arbc.NewArbitratorClient(ArbitratorClientConfig())
 	arbClient.AddDependency(botManager)
+	arbClient.AddDependency(challengePolicy)
+	arbClient.AddDependency(gamePolicy)
+	arbClient.AddDependency(botJournal)
 	arbClient.AddDependency(logService)
 
-	appService := NewAppService(NewAppServiceConfig())
+	appService := NewAppService(NewAppServiceConfig(EventSink()))
 	appService.AddDependency(arbClient)
+	appService.AddDependency(logService)
 
 	appService.Build()
 	return appService
@@ -9,12 +9,14 @@ import (
 
 type Sender func(msg *models.Message) error
 
-func RefreshAuthCreds(send Sender) error {
+// RefreshAuthCreds requests creds from the arbitrator. If the existing creds are still valid, the
+// arbitrator assigns them again.
+func RefreshAuthCreds(send Sender, existingAuth *models.AuthMessageContent) error {
 	msg := &models.Message{
 		Topic:       "",
 		ContentType: models.CONTENT_TYPE_REFRESH_AUTH,
 		Content: &models.RefreshAuthMessageContent{
-			ExistingAuth: nil,
+			ExistingAuth: existingAuth,
 		},
 	}
 	return send(msg)
@@ -54,6 +56,22 @@ func DeclineChallengeRequest(send Sender, topic models.MessageTopic, challengerK
 	return send(msg)
 }
 
+// DeclineChallengeWithReason declines the challenge like DeclineChallengeRequest, with a reason
+// the challenger's client can act on
+func DeclineChallengeWithReason(send Sender, topic models.MessageTopic, challengerKey mods.PlrClientKey,
+	reason mods.DeclineReason, detail string) error {
+	msg := &models.Message{
+		Topic:       topic,
+		ContentType: models.CONTENT_TYPE_DECLINE_CHALLENGE,
+		Content: &mods.DeclineChallengeWithReasonMessageContent{
+			ChallengerClientKey: challengerKey,
+			Reason:              reason,
+			Detail:              detail,
+		},
+	}
+	return send(msg)
+}
+
 func AcceptChallengeRequest(send Sender, topic models.MessageTopic, challengerKey mods.PlrClientKey) error {
 	msg := &models.Message{
 		Topic:       topic,
@@ -76,3 +94,103 @@ func SendMove(send Sender, matchId string, move *chess.Move) error {
 	}
 	return send(msg)
 }
+
+// Resign resigns the match on behalf of the server's bot
+func Resign(send Sender, matchId string) error {
+	msg := &models.Message{
+		Topic:       models.MessageTopic(fmt.Sprintf("match-%s", matchId)),
+		ContentType: models.CONTENT_TYPE_RESIGN_MATCH,
+		Content: &models.ResignMessageContent{
+			MatchId: matchId,
+		},
+	}
+	return send(msg)
+}
+
+// Abort calls off a match before each side has made its first move, so that it has no result
+func Abort(send Sender, matchId string) error {
+	msg := &models.Message{
+		Topic:       models.MessageTopic(fmt.Sprintf("match-%s", matchId)),
+		ContentType: mods.CONTENT_TYPE_ABORT_MATCH,
+		Content: &mods.AbortMessageContent{
+			MatchId: matchId,
+		},
+	}
+	return send(msg)
+}
+
+// AnswerDrawOffer accepts or declines the opponent's draw offer
+func AnswerDrawOffer(send Sender, matchId string, isAccepted bool) error {
+	contentType := mods.CONTENT_TYPE_DECLINE_DRAW
+	if isAccepted {
+		contentType = mods.CONTENT_TYPE_ACCEPT_DRAW
+	}
+	msg := &models.Message{
+		Topic:       models.MessageTopic(fmt.Sprintf("match-%s", matchId)),
+		ContentType: contentType,
+		Content: &mods.DrawMessageContent{
+			MatchId: matchId,
+		},
+	}
+	return send(msg)
+}
+
+// AnswerRematchOffer accepts or declines the opponent's offer to replay the finished match. The
+// rematch itself is only created from a challenge.
+func AnswerRematchOffer(send Sender, matchId string, isAccepted bool) error {
+	contentType := mods.CONTENT_TYPE_DECLINE_REMATCH
+	if isAccepted {
+		contentType = mods.CONTENT_TYPE_ACCEPT_REMATCH
+	}
+	msg := &models.Message{
+		Topic:       models.MessageTopic(fmt.Sprintf("match-%s", matchId)),
+		ContentType: contentType,
+		Content: &mods.RematchMessageContent{
+			MatchId: matchId,
+		},
+	}
+	return send(msg)
+}
+
+// RequestChallenge challenges the challenge's challenged player on behalf of the server
+func RequestChallenge(send Sender, challenge *models.Challenge) error {
+	msg := &models.Message{
+		Topic:       "",
+		ContentType: models.CONTENT_TYPE_CHALLENGE_REQUEST,
+		Content: &models.ChallengeRequestMessageContent{
+			Challenge: challenge,
+		},
+	}
+	return send(msg)
+}
+
+func RevokeChallengeRequest(send Sender, challengedKey mods.PlrClientKey) error {
+	msg := &models.Message{
+		Topic:       "",
+		ContentType: models.CONTENT_TYPE_REVOKE_CHALLENGE,
+		Content: &models.RevokeChallengeMessageContent{
+			ChallengedClientKey: challengedKey,
+		},
+	}
+	return send(msg)
+}
+
+func JoinMatchmaking(send Sender, timeControl *models.TimeControl) error {
+	msg := &models.Message{
+		Topic:       "",
+		ContentType: models.CONTENT_TYPE_JOIN_MATCHMAKING,
+		Content: &models.FindMatchMessageContent{
+			TimeControl: timeControl,
+		},
+	}
+	return send(msg)
+}
+
+func LeaveMatchmaking(send Sender) error {
+	msg := &models.Message{
+		Topic:       "",
+		ContentType: models.CONTENT_TYPE_LEAVE_MATCHMAKING,
+		Content:     &models.NoMessageContent{},
+	}
+	return send(msg)
+}
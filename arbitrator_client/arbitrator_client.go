@@ -1,13 +1,22 @@
 package arbitrator_client
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/CameronHonis/chess-arbitrator/models"
 	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	"github.com/CameronHonis/chess-bot-server/challenge_policy"
+	"github.com/CameronHonis/chess-bot-server/game_policy"
+	"github.com/CameronHonis/chess-bot-server/journal"
+	mods "github.com/CameronHonis/chess-bot-server/models"
 	"github.com/CameronHonis/log"
 	. "github.com/CameronHonis/marker"
 	"github.com/CameronHonis/service"
 	"github.com/gorilla/websocket"
+	"math/rand"
+	"net"
+	"sync"
 	"time"
 )
 
@@ -17,6 +26,7 @@ type ArbitratorClientI interface {
 	service.ServiceI
 	PublicKey() models.Key
 	SendMessage(msg *models.Message) error
+	Drain()
 }
 
 type ArbitratorClient struct {
@@ -25,16 +35,56 @@ type ArbitratorClient struct {
 	__dependencies__ Marker
 	LogService       log.LoggerServiceI
 	BotMngr          *bot_manager.BotManager
+	Policy           *challenge_policy.ChallengePolicy
+	GamePolicy       *game_policy.GamePolicy
+	Journal          *journal.Journal
 
 	__state__ Marker
 	conn      *websocket.Conn
-	pubKey    models.Key
-	priKey    models.Key
+	connMu    sync.Mutex
+	outbox    *Outbox
+	// writerStop stops the writer of the current connection
+	writerStop chan struct{}
+	pubKey     models.Key
+	priKey     models.Key
+	connState  ConnState
+	// isDraining outlasts reconnects, so that a drain resumes once the server is authorized again
+	isDraining bool
+	// isReadOnly stops the server from taking on new games, after a failure the policy degrades on
+	isReadOnly bool
+	// retryAt holds off the next dial after a failure the policy retries
+	retryAt time.Time
+	// dialAttempt counts the failed dials since the server was last ready
+	dialAttempt uint
+	// isFailureRetry is set while the connection is retried for a failure, which does not reset
+	// the backoff once the server is ready again, since the failure may recur as soon as it is
+	isFailureRetry bool
+	// pongLatency is the round trip of the last ping
+	pongLatency time.Duration
+	handlers    *HandlerRegistry
+	stats       *MessageStats
+	// unknownCounts counts the messages received with each content type the client does not handle
+	unknownCounts map[models.ContentType]uint
+	stateMu       sync.Mutex
+	// workers play the matches in progress, by match id
+	workers   map[string]*MatchWorker
+	workersMu sync.Mutex
+	// recentMatches are the finished matches the opponents may ask to replay
+	recentMatches *RecentMatches
 }
 
 func NewArbitratorClient(config *ArbitratorClientConfig) *ArbitratorClient {
-	s := &ArbitratorClient{}
+	s := &ArbitratorClient{
+		connState:     CONN_STATE_DISCONNECTED,
+		outbox:        NewOutbox(DEFAULT_OUTBOX_SIZE),
+		workers:       make(map[string]*MatchWorker),
+		handlers:      NewHandlerRegistry(),
+		stats:         NewMessageStats(),
+		unknownCounts: make(map[models.ContentType]uint),
+		recentMatches: NewRecentMatches(DEFAULT_RECENT_MATCHES),
+	}
 	s.Service = *service.NewService(s, config)
+	s.registerHandlers()
 	return s
 }
 
@@ -42,69 +92,521 @@ func (ac *ArbitratorClient) OnBuild() {
 	ac.AddEventListener(CONN_SUCCESS, OnConnSuccess)
 }
 
+// OnStart connects to the arbitrator and plays until drained, reconnecting whenever the
+// connection drops
 func (ac *ArbitratorClient) OnStart() {
-	for {
-		ac.Connect()
+	ac.Restore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ac.BotMngr.RunReaper(ctx, ac.OnBotReaped)
+	go ac.RunSeeker(ctx)
+	for ac.Connect() {
 		ac.ListenOnWebsocket()
 	}
+	ac.stopMatchWorkers()
+	ac.LogService.Log(ENV_ARBITRATOR_CLIENT, "drained")
+	if closeErr := ac.Journal.Close(); closeErr != nil {
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not close journal: %s", closeErr))
+	}
+}
+
+// Handlers is the registry of handlers for the messages received from the arbitrator, where other
+// services can add their own
+func (ac *ArbitratorClient) Handlers() *HandlerRegistry {
+	return ac.handlers
+}
+
+// MessageStats counts the messages handled, and failed, for each content type
+func (ac *ArbitratorClient) MessageStats() *MessageStats {
+	return ac.stats
 }
 
 func (ac *ArbitratorClient) PublicKey() models.Key {
 	return ac.pubKey
 }
 
+// SendMessage queues the message for the writer and waits for the result, for up to
+// DEFAULT_SEND_TIMEOUT, after which a message still queued is withdrawn. Moves and challenge messages that cannot be written until the server is
+// ready count as sent, since they are written once it is. Other messages are refused until then.
 func (ac *ArbitratorClient) SendMessage(msg *models.Message) error {
-	if ac.conn == nil {
-		return fmt.Errorf("cannot send message, connection is nil")
+	result, pushErr := ac.outbox.Push(msg)
+	if pushErr != nil {
+		return pushErr
 	}
-	ac.SignMessage(msg)
-	msgBytes, marshalErr := msg.Marshal()
-	if marshalErr != nil {
-		return marshalErr
+	timer := time.NewTimer(DEFAULT_SEND_TIMEOUT)
+	defer timer.Stop()
+	var sendErr error
+	select {
+	case sendErr = <-result:
+	case <-timer.C:
+		if ac.outbox.Withdraw(result) {
+			return ErrSendTimeout
+		}
+		// the writer took the message as the timer fired, and reports on it once written
+		sendErr = <-result
 	}
-	ac.LogService.Log(ENV_ARBITRATOR_CLIENT, "<< ", string(msgBytes))
-	return ac.conn.WriteMessage(websocket.TextMessage, msgBytes)
+	if errors.Is(sendErr, ErrSendDeferred) {
+		return nil
+	}
+	return sendErr
 }
 
-func (ac *ArbitratorClient) Connect() {
+// RunWriter is the only goroutine writing to the connection, since the websocket allows one
+// writer at a time. Messages are signed as they are written, so that messages replayed after a
+// reconnect carry the current creds.
+func (ac *ArbitratorClient) RunWriter(conn *websocket.Conn, stop <-chan struct{}) {
+	heartbeat := ac.Config().(*ArbitratorClientConfig).Heartbeat()
+	for {
+		entry := ac.outbox.Pop(stop)
+		if entry == nil {
+			return
+		}
+		ac.SignMessage(entry.Msg)
+		msgBytes, marshalErr := entry.Msg.Marshal()
+		if marshalErr != nil {
+			ac.outbox.Fail(entry, marshalErr)
+			continue
+		}
+		ac.LogService.Log(ENV_ARBITRATOR_CLIENT, "<< ", string(msgBytes))
+		writeErr := conn.SetWriteDeadline(time.Now().Add(heartbeat.WriteTimeout))
+		if writeErr == nil {
+			writeErr = conn.WriteMessage(websocket.TextMessage, msgBytes)
+		}
+		ac.outbox.Written(entry, writeErr)
+		if writeErr != nil {
+			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not write %s message: %s", entry.Msg.ContentType, writeErr))
+			// the listener notices the closed connection and reconnects
+			_ = conn.Close()
+			return
+		}
+	}
+}
+
+// Connect dials the arbitrator until it succeeds, backing off exponentially with jitter between
+// attempts. Each failed attempt dispatches a CONN_FAILED event. Connect returns false without
+// connecting once the server is drained.
+func (ac *ArbitratorClient) Connect() bool {
 	config := ac.Config().(*ArbitratorClientConfig)
-	wsUrl := fmt.Sprintf("ws://%s/ws", config.Url())
-	for ac.conn == nil {
-		var err error
-		ac.conn, _, err = websocket.DefaultDialer.Dial(wsUrl, nil)
-		if err != nil {
-			ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not Connect to arbitrator, retrying in 1 second: %s", err))
-			ac.conn = nil
-			time.Sleep(time.Second)
+	dialOptions := config.DialOptions()
+	ac.setConnState(CONN_STATE_CONNECTING)
+	if retryIn := time.Until(ac.takeRetryAt()); retryIn > 0 {
+		time.Sleep(retryIn)
+	}
+	for {
+		if ac.IsDrained() {
+			ac.setConnState(CONN_STATE_DISCONNECTED)
+			return false
+		}
+		conn, dialErr := dialOptions.Dial(config.Url())
+		if dialErr == nil {
+			ac.setConn(conn)
+			break
 		}
+		attempt := ac.nextDialAttempt()
+		retryIn := config.Backoff().Delay(attempt, rand.Float64())
+		ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not connect to arbitrator (attempt %d), retrying in %s: %s",
+			attempt, retryIn.Round(time.Millisecond), dialErr))
+		ac.Dispatch(NewConnFailedEvent(attempt, dialErr, retryIn))
+		time.Sleep(retryIn)
 	}
-	ac.LogService.Log(ENV_ARBITRATOR_CLIENT, "successfully connected to arbitrator")
+	ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("successfully connected to arbitrator at %s", dialOptions.Url(config.Url())))
+	ac.setConnState(CONN_STATE_AUTHENTICATING)
+	ac.outbox.SetConnected(true)
+	ac.writerStop = make(chan struct{})
+	go ac.RunWriter(ac.conn, ac.writerStop)
+	go ac.RunPinger(ac.conn, ac.writerStop)
 	go ac.Dispatch(NewConnSuccessEvent())
+	return true
 }
 
+// ListenOnWebsocket handles messages until the connection fails. The connection is declared dead
+// if it delivers neither a message nor a pong within the pong timeout.
 func (ac *ArbitratorClient) ListenOnWebsocket() {
 	ac.LogService.Log(ENV_ARBITRATOR_CLIENT, "listening on arbitrator websocket connection...")
+	heartbeat := ac.Config().(*ArbitratorClientConfig).Heartbeat()
+	_ = heartbeat.Arm(ac.conn, ac.setPongLatency)
 	for {
-		_, rawMsg, readErr := ac.conn.ReadMessage()
+		// the deadline runs from when the client starts waiting, so slow handlers are not mistaken
+		// for a silent connection
+		readErr := heartbeat.Extend(ac.conn)
+		var rawMsg []byte
+		if readErr == nil {
+			_, rawMsg, readErr = ac.conn.ReadMessage()
+		}
 		if readErr != nil {
-			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("error reading message from websocket: %s", readErr))
+			if netErr, ok := readErr.(net.Error); ok && netErr.Timeout() {
+				ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("arbitrator silent for %s, declaring connection dead", heartbeat.PongTimeout))
+			} else {
+				ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("error reading message from websocket: %s", readErr))
+			}
 			// assume all readErrs are disconnects
-			ac.conn = nil
+			_ = ac.conn.Close()
+			ac.outbox.SetConnected(false)
+			close(ac.writerStop)
+			ac.setConn(nil)
+			ac.setConnState(CONN_STATE_DISCONNECTED)
 			break
 		}
 		ac.LogService.Log(ENV_ARBITRATOR_CLIENT, ">> ", string(rawMsg))
 
-		msg, unmarshalErr := models.UnmarshalToMessage(rawMsg)
+		msg, unmarshalErr := mods.UnmarshalToMessage(rawMsg)
 		if unmarshalErr != nil {
 			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not unmarshal message: %s", unmarshalErr))
 			continue
 		}
-		handleMsgErr := ac.HandleMsg(msg)
-		if handleMsgErr != nil {
-			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not handle message: %s", handleMsgErr))
+		ac.HandleMsg(msg)
+	}
+}
+
+// RunPinger keeps the connection alive until stop is closed. A failed ping closes the connection,
+// which forces a reconnect.
+func (ac *ArbitratorClient) RunPinger(conn *websocket.Conn, stop <-chan struct{}) {
+	heartbeat := ac.Config().(*ArbitratorClientConfig).Heartbeat()
+	if pingErr := heartbeat.RunPinger(conn, stop); pingErr != nil {
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, pingErr.Error())
+	}
+}
+
+// PongLatency is the round trip of the last ping answered by the arbitrator, or zero if none has
+// been answered
+func (ac *ArbitratorClient) PongLatency() time.Duration {
+	ac.stateMu.Lock()
+	defer ac.stateMu.Unlock()
+	return ac.pongLatency
+}
+
+func (ac *ArbitratorClient) setPongLatency(latency time.Duration) {
+	ac.stateMu.Lock()
+	defer ac.stateMu.Unlock()
+	ac.pongLatency = latency
+}
+
+// ResumeSession picks up every match in progress once the server is authorized again, whether
+// after a reconnect or a restart. Subscriptions do not survive the connection, so every match
+// topic is subscribed to again, and each match resumes once its subscription is granted.
+func (ac *ArbitratorClient) ResumeSession() {
+	for _, botClient := range ac.BotMngr.Clients() {
+		matchId := botClient.MatchId()
+		if matchId == "" {
 			continue
 		}
+		if subErr := RequestSubscribe(ac.SendMessage, models.MessageTopic(fmt.Sprintf("match-%s", matchId))); subErr != nil {
+			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not resubscribe to match %s: %s", matchId, subErr))
+		}
+	}
+}
+
+// resumeMatch plays on from the last update the bot saw, which the next update of the match
+// brings up to date. A bot whose turn it was resumes searching, unless its move is already
+// searched or queued for resending. Where the opponent was to move, the opponent may have moved
+// while the server was disconnected, and the arbitrator has no way to request the match's state,
+// so the bot is reaped if no update follows.
+func (ac *ArbitratorClient) resumeMatch(botClient *bot_manager.BotClient) {
+	match := botClient.LastMatch()
+	if match == nil || match.Uuid != botClient.MatchId() || match.Result != models.MATCH_RESULT_IN_PROGRESS {
+		return
 	}
+	isBotWhite := match.WhiteClientKey == ac.PublicKey()
+	if match.Board.IsWhiteTurn != isBotWhite {
+		botClient.AwaitResumedMatch(time.Now())
+		return
+	}
+	if botClient.IsSearching() || botClient.Record().HasBotMove(bot_manager.Ply(match)) {
+		return
+	}
+	ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("resuming match %s at ply %d", match.Uuid, bot_manager.Ply(match)))
+	ac.dispatchMatchUpdate(botClient, &MatchUpdate{Match: match, IsResumed: true})
+}
+
+// dispatchMatchUpdate passes the update to the worker for its match, starting the worker if the
+// match has none
+func (ac *ArbitratorClient) dispatchMatchUpdate(botClient *bot_manager.BotClient, update *MatchUpdate) {
+	ac.workersMu.Lock()
+	worker, ok := ac.workers[update.Match.Uuid]
+	if !ok {
+		worker = NewMatchWorker(update.Match.Uuid)
+		ac.workers[worker.MatchId] = worker
+		go ac.runMatchWorker(worker, botClient)
+	}
+	ac.workersMu.Unlock()
+	if !worker.Offer(update) {
+		ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("dropped update for match %s at ply %d, superseded by a queued update",
+			update.Match.Uuid, bot_manager.Ply(update.Match)))
+	}
+}
+
+func (ac *ArbitratorClient) runMatchWorker(worker *MatchWorker, botClient *bot_manager.BotClient) {
+	worker.Run(func(update *MatchUpdate) bool {
+		return ac.PlayMatchUpdate(botClient, update)
+	})
+	ac.workersMu.Lock()
+	if ac.workers[worker.MatchId] == worker {
+		delete(ac.workers, worker.MatchId)
+	}
+	ac.workersMu.Unlock()
+}
+
+// stopMatchWorker stops the worker for the match, if it has one
+func (ac *ArbitratorClient) stopMatchWorker(matchId string) {
+	ac.workersMu.Lock()
+	worker, ok := ac.workers[matchId]
+	delete(ac.workers, matchId)
+	ac.workersMu.Unlock()
+	if ok {
+		worker.Stop()
+	}
+}
+
+func (ac *ArbitratorClient) stopMatchWorkers() {
+	ac.workersMu.Lock()
+	workers := ac.workers
+	ac.workers = make(map[string]*MatchWorker)
+	ac.workersMu.Unlock()
+	for _, worker := range workers {
+		worker.Stop()
+	}
+}
+
+// Restore reloads the creds and bots from the journal. The creds are presented when connecting,
+// so that the arbitrator recognizes the server as the player in its matches, and the bots resume
+// play once the server is authorized as a bot.
+func (ac *ArbitratorClient) Restore() {
+	snapshot, loadErr := ac.Journal.Load()
+	if loadErr != nil {
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not load journal, starting fresh: %s", loadErr))
+		return
+	}
+	if snapshot.Creds == nil {
+		return
+	}
+	ac.SetPublicPrivateKey(snapshot.Creds.PublicKey, snapshot.Creds.PrivateKey)
+	restoredBots := ac.BotMngr.RestoreBots(snapshot.Creds.PublicKey, snapshot.Bots)
+	ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("restored %d bots from journal", len(restoredBots)))
+}
+
+// Creds are the keys assigned by the arbitrator, or nil before the first auth
+func (ac *ArbitratorClient) Creds() *models.AuthMessageContent {
+	if ac.pubKey == "" {
+		return nil
+	}
+	return &models.AuthMessageContent{
+		PublicKey:  ac.pubKey,
+		PrivateKey: ac.priKey,
+	}
+}
+
+func (ac *ArbitratorClient) ConnState() ConnState {
+	ac.stateMu.Lock()
+	defer ac.stateMu.Unlock()
+	return ac.connState
+}
+
+// setConnState moves the connection to the state and dispatches a CONN_STATE_CHANGED event,
+// refusing transitions the state machine does not allow
+func (ac *ArbitratorClient) setConnState(to ConnState) {
+	ac.stateMu.Lock()
+	from := ac.connState
+	if from == to {
+		ac.stateMu.Unlock()
+		return
+	}
+	if !CanTransition(from, to) {
+		ac.stateMu.Unlock()
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("refused connection state change from %s to %s", from, to))
+		return
+	}
+	ac.connState = to
+	if to == CONN_STATE_READY {
+		if !ac.isFailureRetry {
+			ac.dialAttempt = 0
+		}
+		ac.isFailureRetry = false
+	}
+	ac.stateMu.Unlock()
+	// messages beyond the handshake wait on the server being authorized
+	ac.outbox.SetOpen(to == CONN_STATE_READY || to == CONN_STATE_DRAINING)
+	ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("connection %s -> %s", from, to))
+	ac.Dispatch(NewConnStateChangedEvent(from, to))
+}
+
+func (ac *ArbitratorClient) nextDialAttempt() uint {
+	ac.stateMu.Lock()
+	defer ac.stateMu.Unlock()
+	ac.dialAttempt++
+	return ac.dialAttempt
+}
+
+// setAuthorized marks the server as authorized as a bot, which leaves it ready, or draining if a
+// drain was started
+func (ac *ArbitratorClient) setAuthorized() {
+	if ac.IsDraining() {
+		ac.setConnState(CONN_STATE_DRAINING)
+		return
+	}
+	ac.setConnState(CONN_STATE_READY)
+}
+
+// isAuthorized reports whether the server is authorized as a bot on the current connection, which
+// is when messages besides handshakes can be sent
+func (ac *ArbitratorClient) isAuthorized() bool {
+	connState := ac.ConnState()
+	return connState == CONN_STATE_READY || connState == CONN_STATE_DRAINING
+}
+
+func (ac *ArbitratorClient) IsDraining() bool {
+	ac.stateMu.Lock()
+	defer ac.stateMu.Unlock()
+	return ac.isDraining
+}
+
+// IsDrained reports whether the server is draining and every match has ended
+func (ac *ArbitratorClient) IsDrained() bool {
+	return ac.IsDraining() && len(ac.BotMngr.Clients()) == 0
+}
+
+// Drain stops the server from taking on new games. Challenges are declined, the server's own
+// challenges and matchmaking are abandoned, matches no one has moved in yet are aborted, and once
+// every match in progress is over the connection is closed and OnStart returns.
+func (ac *ArbitratorClient) Drain() {
+	ac.stateMu.Lock()
+	ac.isDraining = true
+	isReady := ac.connState == CONN_STATE_READY
+	ac.stateMu.Unlock()
+	if isReady {
+		ac.setConnState(CONN_STATE_DRAINING)
+	}
+	ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("draining %d bots", len(ac.BotMngr.Clients())))
+	ac.AbandonSeeks()
+	ac.AbortUnstartedMatches()
+	ac.closeIfDrained()
+}
+
+// OnFailure responds to the failure as the policy says
+func (ac *ArbitratorClient) OnFailure(failureErr *FailureError) {
+	action := ac.Config().(*ArbitratorClientConfig).Failures().Action(failureErr.Failure)
+	ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("%s, responding with %s", failureErr, action))
+	switch action {
+	case FAILURE_ACTION_RETRY:
+		ac.retryConnection(failureErr)
+	case FAILURE_ACTION_READ_ONLY:
+		ac.setReadOnly()
+	case FAILURE_ACTION_SHUTDOWN:
+		ac.Shutdown()
+	case FAILURE_ACTION_ABANDON_MATCH:
+		ac.abandonMatch(failureErr.Topic)
+	}
+}
+
+// abandonMatch removes the bot playing the match on the topic, leaving the server's other games
+// and its connection as they are
+func (ac *ArbitratorClient) abandonMatch(topic models.MessageTopic) {
+	matchId, isMatchTopic := matchIdFromTopic(topic)
+	if !isMatchTopic {
+		return
+	}
+	botClient, _ := ac.BotMngr.ClientByMatchId(matchId)
+	if botClient == nil {
+		return
+	}
+	defer ac.closeIfDrained()
+	ac.stopMatchWorker(matchId)
+	if removeErr := ac.BotMngr.RemoveBot(botClient.Key()); removeErr != nil {
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not remove bot for match %s: %s", matchId, removeErr))
+		return
+	}
+	ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("abandoned match %s", matchId))
+}
+
+// retryConnection closes the connection, holding off the next dial for the backoff of another
+// failed attempt
+func (ac *ArbitratorClient) retryConnection(cause error) {
+	attempt := ac.nextDialAttempt()
+	retryIn := ac.Config().(*ArbitratorClientConfig).Backoff().Delay(attempt, rand.Float64())
+	ac.stateMu.Lock()
+	ac.retryAt = time.Now().Add(retryIn)
+	ac.isFailureRetry = true
+	ac.stateMu.Unlock()
+	ac.Dispatch(NewConnFailedEvent(attempt, cause, retryIn))
+
+	ac.connMu.Lock()
+	defer ac.connMu.Unlock()
+	if ac.conn != nil {
+		_ = ac.conn.Close()
+	}
+}
+
+func (ac *ArbitratorClient) takeRetryAt() time.Time {
+	ac.stateMu.Lock()
+	defer ac.stateMu.Unlock()
+	retryAt := ac.retryAt
+	ac.retryAt = time.Time{}
+	return retryAt
+}
+
+func (ac *ArbitratorClient) IsReadOnly() bool {
+	ac.stateMu.Lock()
+	defer ac.stateMu.Unlock()
+	return ac.isReadOnly
+}
+
+// setReadOnly stops the server from taking on new games for as long as it runs. Its own
+// challenges and matchmaking are abandoned, but matches in progress play on.
+func (ac *ArbitratorClient) setReadOnly() {
+	ac.stateMu.Lock()
+	wasReadOnly := ac.isReadOnly
+	ac.isReadOnly = true
+	ac.stateMu.Unlock()
+	if !wasReadOnly {
+		ac.AbandonSeeks()
+	}
+}
+
+// Shutdown drains the server. Unless the server is authorized, its matches cannot be played, so
+// they are abandoned rather than waited on.
+func (ac *ArbitratorClient) Shutdown() {
+	ac.Drain()
+	if !ac.isAuthorized() {
+		ac.stopMatchWorkers()
+		ac.BotMngr.RemoveAllBots()
+		ac.closeIfDrained()
+	}
+}
+
+// UnknownMessageCounts counts the messages received with each content type the client does not
+// handle
+func (ac *ArbitratorClient) UnknownMessageCounts() map[models.ContentType]uint {
+	ac.stateMu.Lock()
+	defer ac.stateMu.Unlock()
+	counts := make(map[models.ContentType]uint, len(ac.unknownCounts))
+	for contentType, count := range ac.unknownCounts {
+		counts[contentType] = count
+	}
+	return counts
+}
+
+func (ac *ArbitratorClient) countUnknownMessage(contentType models.ContentType) uint {
+	ac.stateMu.Lock()
+	defer ac.stateMu.Unlock()
+	ac.unknownCounts[contentType]++
+	return ac.unknownCounts[contentType]
+}
+
+// closeIfDrained closes the connection once the drain is complete, which ends the listen loop
+func (ac *ArbitratorClient) closeIfDrained() {
+	if !ac.IsDrained() {
+		return
+	}
+	ac.connMu.Lock()
+	defer ac.connMu.Unlock()
+	if ac.conn != nil {
+		_ = ac.conn.Close()
+	}
+}
+
+func (ac *ArbitratorClient) setConn(conn *websocket.Conn) {
+	ac.connMu.Lock()
+	defer ac.connMu.Unlock()
+	ac.conn = conn
 }
 
 func (ac *ArbitratorClient) SetPublicPrivateKey(publicKey models.Key, privateKey models.Key) {
@@ -1,17 +1,59 @@
 package arbitrator_client
 
-import "github.com/CameronHonis/service"
+import (
+	"github.com/CameronHonis/chess-bot-server/matchmaking"
+	"github.com/CameronHonis/service"
+	"time"
+)
+
+const DEFAULT_SEEK_INTERVAL = 5 * time.Second
 
 type ArbitratorClientConfig struct {
 	service.ConfigI
-	authSecret string
-	url        string
+	authSecret   string
+	url          string
+	schedule     *matchmaking.Schedule
+	seekInterval time.Duration
+	backoff      *Backoff
+	dialOptions  *DialOptions
+	heartbeat    *Heartbeat
+	failures     FailurePolicy
 }
 
-func NewArbitratorClientConfig(authSecret, url string) *ArbitratorClientConfig {
+// NewArbitratorClientConfig configures the connection to the arbitrator and the games the server
+// seeks on its own. The url is the arbitrator's "host:port". A nil schedule seeks no games, a zero
+// interval takes the default, a nil backoff takes the default backoff, nil dial options connect
+// in plaintext to the default path, a nil heartbeat takes the default intervals and a nil failure
+// policy takes the default action for every failure.
+func NewArbitratorClientConfig(authSecret, url string, schedule *matchmaking.Schedule, seekInterval time.Duration,
+	backoff *Backoff, dialOptions *DialOptions, heartbeat *Heartbeat, failures FailurePolicy) *ArbitratorClientConfig {
+	if schedule == nil {
+		schedule = matchmaking.EmptySchedule()
+	}
+	if seekInterval <= 0 {
+		seekInterval = DEFAULT_SEEK_INTERVAL
+	}
+	if backoff == nil {
+		backoff = &Backoff{}
+	}
+	if dialOptions == nil {
+		dialOptions = &DialOptions{}
+	}
+	if heartbeat == nil {
+		heartbeat = &Heartbeat{}
+	}
+	if failures == nil {
+		failures = make(FailurePolicy)
+	}
 	return &ArbitratorClientConfig{
-		authSecret: authSecret,
-		url:        url,
+		authSecret:   authSecret,
+		url:          url,
+		schedule:     schedule,
+		seekInterval: seekInterval,
+		backoff:      backoff.withDefaults(),
+		dialOptions:  dialOptions.withDefaults(),
+		heartbeat:    heartbeat.withDefaults(),
+		failures:     failures,
 	}
 }
 
@@ -22,3 +64,30 @@ func (c *ArbitratorClientConfig) AuthSecret() string {
 func (c *ArbitratorClientConfig) Url() string {
 	return c.url
 }
+
+func (c *ArbitratorClientConfig) Schedule() *matchmaking.Schedule {
+	return c.schedule
+}
+
+// SeekInterval is how often the schedule is checked for games to seek
+func (c *ArbitratorClientConfig) SeekInterval() time.Duration {
+	return c.seekInterval
+}
+
+// Backoff spaces out the attempts to reconnect
+func (c *ArbitratorClientConfig) Backoff() *Backoff {
+	return c.backoff
+}
+
+func (c *ArbitratorClientConfig) DialOptions() *DialOptions {
+	return c.dialOptions
+}
+
+func (c *ArbitratorClientConfig) Heartbeat() *Heartbeat {
+	return c.heartbeat
+}
+
+// Failures is the policy for failures that keep the server from playing
+func (c *ArbitratorClientConfig) Failures() FailurePolicy {
+	return c.failures
+}
@@ -0,0 +1,13 @@
+package arbitrator_client_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestArbitratorClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ArbitratorClient Suite")
+}
@@ -0,0 +1,88 @@
+package arbitrator_client
+
+import (
+	"math"
+	"time"
+)
+
+// ConnState is the lifecycle of the connection to the arbitrator
+type ConnState string
+
+const (
+	CONN_STATE_DISCONNECTED ConnState = "disconnected"
+	// CONN_STATE_CONNECTING dials the arbitrator, backing off between failed attempts
+	CONN_STATE_CONNECTING ConnState = "connecting"
+	// CONN_STATE_AUTHENTICATING waits on the arbitrator to grant the creds and the bot role
+	CONN_STATE_AUTHENTICATING ConnState = "authenticating"
+	// CONN_STATE_READY plays matches, takes challenges and seeks games
+	CONN_STATE_READY ConnState = "ready"
+	// CONN_STATE_DRAINING plays out the matches in progress, but takes on no new games
+	CONN_STATE_DRAINING ConnState = "draining"
+)
+
+var nextConnStates = map[ConnState][]ConnState{
+	CONN_STATE_DISCONNECTED:   {CONN_STATE_CONNECTING},
+	CONN_STATE_CONNECTING:     {CONN_STATE_AUTHENTICATING, CONN_STATE_DISCONNECTED},
+	CONN_STATE_AUTHENTICATING: {CONN_STATE_READY, CONN_STATE_DRAINING, CONN_STATE_DISCONNECTED},
+	CONN_STATE_READY:          {CONN_STATE_DRAINING, CONN_STATE_DISCONNECTED},
+	CONN_STATE_DRAINING:       {CONN_STATE_DISCONNECTED},
+}
+
+// CanTransition reports whether the connection may go from one state to the other
+func CanTransition(from, to ConnState) bool {
+	for _, nextState := range nextConnStates[from] {
+		if nextState == to {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	DEFAULT_BACKOFF_INITIAL    = 500 * time.Millisecond
+	DEFAULT_BACKOFF_MAX        = 30 * time.Second
+	DEFAULT_BACKOFF_MULTIPLIER = 2.
+	DEFAULT_BACKOFF_JITTER     = 0.5
+)
+
+// Backoff spaces out the attempts to reconnect to the arbitrator. A zero field takes its default.
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// Jitter is the fraction of each delay that is randomized, so that servers disconnected
+	// together do not reconnect together
+	Jitter float64
+}
+
+func (b *Backoff) withDefaults() *Backoff {
+	backoff := *b
+	if backoff.Initial == 0 {
+		backoff.Initial = DEFAULT_BACKOFF_INITIAL
+	}
+	if backoff.Max == 0 {
+		backoff.Max = DEFAULT_BACKOFF_MAX
+	}
+	if backoff.Multiplier == 0 {
+		backoff.Multiplier = DEFAULT_BACKOFF_MULTIPLIER
+	}
+	if backoff.Jitter == 0 {
+		backoff.Jitter = DEFAULT_BACKOFF_JITTER
+	}
+	return &backoff
+}
+
+// Delay is the wait before retrying after the given number of failed attempts, counted from one.
+// The delay grows exponentially up to the max, then the jittered fraction of it is scaled by
+// random, a number in [0, 1).
+func (b *Backoff) Delay(attempt uint, random float64) time.Duration {
+	if attempt == 0 {
+		attempt = 1
+	}
+	delay := float64(b.Initial) * math.Pow(b.Multiplier, float64(attempt-1))
+	if delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	jitter := math.Min(math.Max(b.Jitter, 0), 1)
+	return time.Duration(delay*(1-jitter) + delay*jitter*random)
+}
@@ -0,0 +1,48 @@
+package arbitrator_client_test
+
+import (
+	. "github.com/CameronHonis/chess-bot-server/arbitrator_client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+var _ = Describe("ConnState", func() {
+	It("allows the connection lifecycle", func() {
+		Expect(CanTransition(CONN_STATE_DISCONNECTED, CONN_STATE_CONNECTING)).To(BeTrue())
+		Expect(CanTransition(CONN_STATE_CONNECTING, CONN_STATE_AUTHENTICATING)).To(BeTrue())
+		Expect(CanTransition(CONN_STATE_AUTHENTICATING, CONN_STATE_READY)).To(BeTrue())
+		Expect(CanTransition(CONN_STATE_READY, CONN_STATE_DRAINING)).To(BeTrue())
+		Expect(CanTransition(CONN_STATE_DRAINING, CONN_STATE_DISCONNECTED)).To(BeTrue())
+	})
+	It("allows dropping the connection before it is ready", func() {
+		Expect(CanTransition(CONN_STATE_AUTHENTICATING, CONN_STATE_DISCONNECTED)).To(BeTrue())
+	})
+	It("refuses to skip authentication", func() {
+		Expect(CanTransition(CONN_STATE_CONNECTING, CONN_STATE_READY)).To(BeFalse())
+	})
+	It("refuses to undo a drain", func() {
+		Expect(CanTransition(CONN_STATE_DRAINING, CONN_STATE_READY)).To(BeFalse())
+	})
+})
+
+var _ = Describe("Backoff", func() {
+	backoff := &Backoff{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.5}
+	It("doubles the delay after each failed attempt", func() {
+		Expect(backoff.Delay(1, 1)).To(Equal(time.Second))
+		Expect(backoff.Delay(2, 1)).To(Equal(2 * time.Second))
+		Expect(backoff.Delay(3, 1)).To(Equal(4 * time.Second))
+	})
+	It("caps the delay", func() {
+		Expect(backoff.Delay(10, 1)).To(Equal(10 * time.Second))
+	})
+	It("randomizes the jittered fraction of the delay", func() {
+		Expect(backoff.Delay(2, 0)).To(Equal(time.Second))
+		Expect(backoff.Delay(2, 0.5)).To(Equal(1500 * time.Millisecond))
+	})
+	It("fills in the defaults", func() {
+		config := NewArbitratorClientConfig("", "", nil, 0, nil, nil, nil, nil)
+		Expect(config.Backoff().Initial).To(Equal(DEFAULT_BACKOFF_INITIAL))
+		Expect(config.Backoff().Max).To(Equal(DEFAULT_BACKOFF_MAX))
+	})
+})
@@ -0,0 +1,98 @@
+package arbitrator_client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const DEFAULT_WS_PATH = "/ws"
+
+// DialOptions describe how to reach the arbitrator's websocket. Without IsSecure the connection
+// is plaintext and the certificate paths are ignored.
+type DialOptions struct {
+	IsSecure bool
+	// Path is the websocket endpoint on the arbitrator, defaulting to /ws
+	Path string
+	// CACertPath is a PEM bundle trusted in addition to the system roots
+	CACertPath string
+	// ClientCertPath and ClientKeyPath are the PEM certificate and key presented to arbitrators
+	// that require mutual TLS. Both or neither must be set.
+	ClientCertPath string
+	ClientKeyPath  string
+	// Headers are added to the websocket handshake, such as an auth token for a proxy
+	Headers http.Header
+}
+
+func (o *DialOptions) withDefaults() *DialOptions {
+	options := *o
+	if options.Path == "" {
+		options.Path = DEFAULT_WS_PATH
+	}
+	if !strings.HasPrefix(options.Path, "/") {
+		options.Path = "/" + options.Path
+	}
+	if options.Headers == nil {
+		options.Headers = make(http.Header)
+	}
+	return &options
+}
+
+// Url is the websocket url of the arbitrator at the host, given as "host:port"
+func (o *DialOptions) Url(host string) string {
+	scheme := "ws"
+	if o.IsSecure {
+		scheme = "wss"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, host, o.Path)
+}
+
+// TLSConfig loads the CA bundle and client certificate, returning nil for plaintext connections
+func (o *DialOptions) TLSConfig() (*tls.Config, error) {
+	if !o.IsSecure {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.CACertPath != "" {
+		caPem, readErr := os.ReadFile(o.CACertPath)
+		if readErr != nil {
+			return nil, fmt.Errorf("could not read CA bundle %s: %s", o.CACertPath, readErr)
+		}
+		rootCAs, _ := x509.SystemCertPool()
+		if rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(caPem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", o.CACertPath)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	if o.ClientCertPath != "" || o.ClientKeyPath != "" {
+		if o.ClientCertPath == "" || o.ClientKeyPath == "" {
+			return nil, fmt.Errorf("client certificate and key must be set together")
+		}
+		clientCert, loadErr := tls.LoadX509KeyPair(o.ClientCertPath, o.ClientKeyPath)
+		if loadErr != nil {
+			return nil, fmt.Errorf("could not load client certificate %s: %s", o.ClientCertPath, loadErr)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+	return tlsConfig, nil
+}
+
+// Dial opens the websocket to the arbitrator at the host, given as "host:port". The certificates
+// are reloaded on every dial, so that rotated certificates are picked up on reconnect.
+func (o *DialOptions) Dial(host string) (*websocket.Conn, error) {
+	tlsConfig, tlsErr := o.TLSConfig()
+	if tlsErr != nil {
+		return nil, tlsErr
+	}
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = tlsConfig
+	conn, _, dialErr := dialer.Dial(o.Url(host), o.Headers)
+	return conn, dialErr
+}
@@ -0,0 +1,152 @@
+package arbitrator_client_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	. "github.com/CameronHonis/chess-bot-server/arbitrator_client"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WsServer accepts websockets on any path, recording the path and headers of the last handshake.
+// Each connection is passed to onConn, or closed straight away if onConn is nil.
+type WsServer struct {
+	*httptest.Server
+	LastPath    string
+	LastHeaders http.Header
+}
+
+func NewWsServer(onConn func(conn *websocket.Conn)) *WsServer {
+	wsServer := &WsServer{}
+	wsServer.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsServer.LastPath = r.URL.Path
+		wsServer.LastHeaders = r.Header
+		conn, upgradeErr := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if upgradeErr != nil {
+			return
+		}
+		if onConn != nil {
+			onConn(conn)
+		}
+		_ = conn.Close()
+	}))
+	return wsServer
+}
+
+func (s *WsServer) Host() string {
+	return strings.TrimPrefix(strings.TrimPrefix(s.URL, "https://"), "http://")
+}
+
+func WritePem(path, blockType string, der []byte) {
+	Expect(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)).To(Succeed())
+}
+
+// WriteClientCert writes a self-signed client certificate and its key, returning the certificate
+func WriteClientCert(certPath, keyPath string) *x509.Certificate {
+	key, keyErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(keyErr).ToNot(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "bot-server"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certDer, certErr := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(certErr).ToNot(HaveOccurred())
+	keyDer, marshalErr := x509.MarshalECPrivateKey(key)
+	Expect(marshalErr).ToNot(HaveOccurred())
+	WritePem(certPath, "CERTIFICATE", certDer)
+	WritePem(keyPath, "EC PRIVATE KEY", keyDer)
+	cert, parseErr := x509.ParseCertificate(certDer)
+	Expect(parseErr).ToNot(HaveOccurred())
+	return cert
+}
+
+var _ = Describe("DialOptions", func() {
+	var dir string
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+	It("connects in plaintext to /ws by default", func() {
+		config := NewArbitratorClientConfig("", "arbitrator:8080", nil, 0, nil, nil, nil, nil)
+		Expect(config.DialOptions().Url(config.Url())).To(Equal("ws://arbitrator:8080/ws"))
+	})
+	When("the arbitrator serves TLS", func() {
+		var wsServer *WsServer
+		var caPath string
+		BeforeEach(func() {
+			wsServer = NewWsServer(nil)
+			wsServer.StartTLS()
+			DeferCleanup(wsServer.Close)
+			caPath = filepath.Join(dir, "ca.pem")
+			WritePem(caPath, "CERTIFICATE", wsServer.Certificate().Raw)
+		})
+		It("connects with the custom CA, path and headers", func() {
+			headers := http.Header{}
+			headers.Set("X-Token", "abc")
+			config := NewArbitratorClientConfig("", wsServer.Host(), nil, 0, nil,
+				&DialOptions{IsSecure: true, Path: "arbitrator/ws", CACertPath: caPath, Headers: headers}, nil, nil)
+			conn, dialErr := config.DialOptions().Dial(config.Url())
+			Expect(dialErr).ToNot(HaveOccurred())
+			_ = conn.Close()
+			Expect(wsServer.LastPath).To(Equal("/arbitrator/ws"))
+			Expect(wsServer.LastHeaders.Get("X-Token")).To(Equal("abc"))
+		})
+		It("refuses a server the CA bundle does not vouch for", func() {
+			config := NewArbitratorClientConfig("", wsServer.Host(), nil, 0, nil, &DialOptions{IsSecure: true}, nil, nil)
+			Expect(config.DialOptions().Dial(config.Url())).Error().To(HaveOccurred())
+		})
+		It("fails on an unreadable CA bundle", func() {
+			options := &DialOptions{IsSecure: true, CACertPath: filepath.Join(dir, "missing.pem")}
+			Expect(options.TLSConfig()).Error().To(HaveOccurred())
+		})
+	})
+	When("the arbitrator requires a client certificate", func() {
+		var wsServer *WsServer
+		var caPath, certPath, keyPath string
+		BeforeEach(func() {
+			certPath = filepath.Join(dir, "client.pem")
+			keyPath = filepath.Join(dir, "client-key.pem")
+			clientCAs := x509.NewCertPool()
+			clientCAs.AddCert(WriteClientCert(certPath, keyPath))
+
+			wsServer = NewWsServer(nil)
+			wsServer.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+			wsServer.StartTLS()
+			DeferCleanup(wsServer.Close)
+			caPath = filepath.Join(dir, "ca.pem")
+			WritePem(caPath, "CERTIFICATE", wsServer.Certificate().Raw)
+		})
+		It("connects with the client certificate", func() {
+			options := &DialOptions{IsSecure: true, Path: "/ws", CACertPath: caPath, ClientCertPath: certPath, ClientKeyPath: keyPath}
+			conn, dialErr := options.Dial(wsServer.Host())
+			Expect(dialErr).ToNot(HaveOccurred())
+			_ = conn.Close()
+		})
+		It("is refused without the client certificate", func() {
+			options := &DialOptions{IsSecure: true, Path: "/ws", CACertPath: caPath}
+			Expect(options.Dial(wsServer.Host())).Error().To(HaveOccurred())
+		})
+		It("requires the certificate and key together", func() {
+			options := &DialOptions{IsSecure: true, ClientCertPath: certPath}
+			Expect(options.TLSConfig()).Error().To(HaveOccurred())
+		})
+	})
+})
@@ -7,7 +7,7 @@ import (
 var OnConnSuccess = func(self service.ServiceI, event service.EventI) bool {
 	ac := self.(*ArbitratorClient)
 	for i := 0; i < 3; i++ {
-		sendErr := RefreshAuthCreds(ac.SendMessage)
+		sendErr := RefreshAuthCreds(ac.SendMessage, ac.Creds())
 		if sendErr == nil {
 			break
 		}
@@ -1,10 +1,25 @@
 package arbitrator_client
 
-import "github.com/CameronHonis/service"
+import (
+	"github.com/CameronHonis/chess-arbitrator/models"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+	"github.com/CameronHonis/service"
+	"time"
+)
+
+const (
+	CONN_SUCCESS       service.EventVariant = "CONN_SUCCESS"
+	CONN_FAILED                             = "CONN_FAILED"
+	CONN_STATE_CHANGED                      = "CONN_STATE_CHANGED"
+)
 
+// the domain events report what the server does with the challenges and matches it sees
 const (
-	CONN_SUCCESS service.EventVariant = "CONN_SUCCESS"
-	CONN_FAILED                       = "CONN_FAILED"
+	CHALLENGE_RECEIVED service.EventVariant = "CHALLENGE_RECEIVED"
+	CHALLENGE_ACCEPTED service.EventVariant = "CHALLENGE_ACCEPTED"
+	CHALLENGE_DECLINED service.EventVariant = "CHALLENGE_DECLINED"
+	MATCH_STARTED      service.EventVariant = "MATCH_STARTED"
+	MATCH_FINISHED     service.EventVariant = "MATCH_FINISHED"
 )
 
 type ConnSuccessPayload struct {
@@ -17,3 +32,110 @@ func NewConnSuccessEvent() *ConnSuccessEvent {
 		Event: *service.NewEvent(CONN_SUCCESS, &ConnSuccessPayload{}),
 	}
 }
+
+type ConnFailedPayload struct {
+	// Attempt counts the failed attempts since the server was last ready
+	Attempt uint
+	Err     error
+	RetryIn time.Duration
+}
+
+type ConnFailedEvent struct{ service.Event }
+
+func NewConnFailedEvent(attempt uint, err error, retryIn time.Duration) *ConnFailedEvent {
+	return &ConnFailedEvent{
+		Event: *service.NewEvent(CONN_FAILED, &ConnFailedPayload{
+			Attempt: attempt,
+			Err:     err,
+			RetryIn: retryIn,
+		}),
+	}
+}
+
+type ConnStateChangedPayload struct {
+	From ConnState
+	To   ConnState
+}
+
+type ConnStateChangedEvent struct{ service.Event }
+
+func NewConnStateChangedEvent(from, to ConnState) *ConnStateChangedEvent {
+	return &ConnStateChangedEvent{
+		Event: *service.NewEvent(CONN_STATE_CHANGED, &ConnStateChangedPayload{
+			From: from,
+			To:   to,
+		}),
+	}
+}
+
+type ChallengePayload struct {
+	Challenge *models.Challenge `json:"challenge"`
+}
+
+type ChallengeReceivedEvent struct{ service.Event }
+
+func NewChallengeReceivedEvent(challenge *models.Challenge) *ChallengeReceivedEvent {
+	return &ChallengeReceivedEvent{
+		Event: *service.NewEvent(CHALLENGE_RECEIVED, &ChallengePayload{
+			Challenge: challenge,
+		}),
+	}
+}
+
+type ChallengeAcceptedEvent struct{ service.Event }
+
+func NewChallengeAcceptedEvent(challenge *models.Challenge) *ChallengeAcceptedEvent {
+	return &ChallengeAcceptedEvent{
+		Event: *service.NewEvent(CHALLENGE_ACCEPTED, &ChallengePayload{
+			Challenge: challenge,
+		}),
+	}
+}
+
+type ChallengeDeclinedPayload struct {
+	Challenge *models.Challenge  `json:"challenge"`
+	Reason    mods.DeclineReason `json:"reason"`
+	Detail    string             `json:"detail"`
+}
+
+type ChallengeDeclinedEvent struct{ service.Event }
+
+func NewChallengeDeclinedEvent(challenge *models.Challenge, reason mods.DeclineReason, detail string) *ChallengeDeclinedEvent {
+	return &ChallengeDeclinedEvent{
+		Event: *service.NewEvent(CHALLENGE_DECLINED, &ChallengeDeclinedPayload{
+			Challenge: challenge,
+			Reason:    reason,
+			Detail:    detail,
+		}),
+	}
+}
+
+type MatchPayload struct {
+	BotName     string        `json:"botName"`
+	ChallengeId string        `json:"challengeId"`
+	Match       *models.Match `json:"match"`
+}
+
+type MatchStartedEvent struct{ service.Event }
+
+func NewMatchStartedEvent(botName, challengeId string, match *models.Match) *MatchStartedEvent {
+	return &MatchStartedEvent{
+		Event: *service.NewEvent(MATCH_STARTED, &MatchPayload{
+			BotName:     botName,
+			ChallengeId: challengeId,
+			Match:       match,
+		}),
+	}
+}
+
+type MatchFinishedEvent struct{ service.Event }
+
+func NewMatchFinishedEvent(botName, challengeId string, match *models.Match) *MatchFinishedEvent {
+	return &MatchFinishedEvent{
+		Event: *service.NewEvent(MATCH_FINISHED, &MatchPayload{
+			BotName:     botName,
+			ChallengeId: challengeId,
+			Match:       match,
+		}),
+	}
+}
@@ -0,0 +1,96 @@
+package arbitrator_client
+
+import (
+	"fmt"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"strings"
+)
+
+// Failure is a refusal by the arbitrator, or a misconfiguration, that keeps the server from
+// playing as a bot
+type Failure string
+
+const (
+	FAILURE_AUTH_UPGRADE_DENIED Failure = "auth_upgrade_denied"
+	FAILURE_SUBSCRIBE_DENIED    Failure = "subscribe_denied"
+	FAILURE_MISSING_BOT_SECRET  Failure = "missing_bot_secret"
+)
+
+type FailureAction string
+
+const (
+	// FAILURE_ACTION_RETRY drops the connection and reconnects after backing off
+	FAILURE_ACTION_RETRY FailureAction = "retry"
+	// FAILURE_ACTION_READ_ONLY stays connected but takes on no new games
+	FAILURE_ACTION_READ_ONLY FailureAction = "read_only"
+	// FAILURE_ACTION_SHUTDOWN drains the server, abandoning matches it is not authorized to play
+	FAILURE_ACTION_SHUTDOWN FailureAction = "shutdown"
+	// FAILURE_ACTION_ABANDON_MATCH stays connected and gives up only the match the failure is
+	// about, such as the match whose topic could not be subscribed to
+	FAILURE_ACTION_ABANDON_MATCH FailureAction = "abandon_match"
+)
+
+// FailureError is returned by message handlers for failures the policy acts on
+type FailureError struct {
+	Failure Failure
+	Detail  string
+	// Topic is the topic the failure is about, if any
+	Topic models.MessageTopic
+}
+
+func (e *FailureError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Failure, e.Detail)
+}
+
+// FailurePolicy chooses the action for each failure. Failures it does not list take the default
+// action: a missing bot secret shuts down, since reconnecting will not set it, a denied
+// subscription abandons only its match, since reconnecting would subscribe to it again, and a
+// denied upgrade is retried.
+type FailurePolicy map[Failure]FailureAction
+
+func (p FailurePolicy) Action(failure Failure) FailureAction {
+	if action, ok := p[failure]; ok {
+		return action
+	}
+	switch failure {
+	case FAILURE_MISSING_BOT_SECRET:
+		return FAILURE_ACTION_SHUTDOWN
+	case FAILURE_SUBSCRIBE_DENIED:
+		return FAILURE_ACTION_ABANDON_MATCH
+	default:
+		return FAILURE_ACTION_RETRY
+	}
+}
+
+// ParseFailurePolicy reads a policy formatted like "auth_upgrade_denied=retry,subscribe_denied=read_only"
+func ParseFailurePolicy(policyStr string) (FailurePolicy, error) {
+	policy := make(FailurePolicy)
+	for _, rule := range strings.Split(policyStr, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		failureStr, actionStr, isPair := strings.Cut(rule, "=")
+		if !isPair {
+			return nil, fmt.Errorf("failure policy rule %q is not formatted like failure=action", rule)
+		}
+		failure := Failure(strings.TrimSpace(failureStr))
+		switch failure {
+		case FAILURE_AUTH_UPGRADE_DENIED, FAILURE_SUBSCRIBE_DENIED, FAILURE_MISSING_BOT_SECRET:
+		default:
+			return nil, fmt.Errorf("unknown failure %q", failure)
+		}
+		action := FailureAction(strings.TrimSpace(actionStr))
+		switch action {
+		case FAILURE_ACTION_RETRY, FAILURE_ACTION_READ_ONLY, FAILURE_ACTION_SHUTDOWN:
+		case FAILURE_ACTION_ABANDON_MATCH:
+			if failure != FAILURE_SUBSCRIBE_DENIED {
+				return nil, fmt.Errorf("failure %s is not about a match, so it cannot abandon one", failure)
+			}
+		default:
+			return nil, fmt.Errorf("unknown action %q for failure %s", action, failure)
+		}
+		policy[failure] = action
+	}
+	return policy, nil
+}
@@ -0,0 +1,35 @@
+package arbitrator_client_test
+
+import (
+	. "github.com/CameronHonis/chess-bot-server/arbitrator_client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FailurePolicy", func() {
+	It("retries a denied upgrade by default", func() {
+		Expect(FailurePolicy{}.Action(FAILURE_AUTH_UPGRADE_DENIED)).To(Equal(FAILURE_ACTION_RETRY))
+	})
+	It("abandons only the match of a denied subscription by default", func() {
+		Expect(FailurePolicy{}.Action(FAILURE_SUBSCRIBE_DENIED)).To(Equal(FAILURE_ACTION_ABANDON_MATCH))
+	})
+	It("shuts down without a bot secret by default", func() {
+		Expect(FailurePolicy{}.Action(FAILURE_MISSING_BOT_SECRET)).To(Equal(FAILURE_ACTION_SHUTDOWN))
+	})
+	It("parses the action for each failure", func() {
+		policy, parseErr := ParseFailurePolicy("auth_upgrade_denied=shutdown, subscribe_denied=read_only")
+		Expect(parseErr).ToNot(HaveOccurred())
+		Expect(policy.Action(FAILURE_AUTH_UPGRADE_DENIED)).To(Equal(FAILURE_ACTION_SHUTDOWN))
+		Expect(policy.Action(FAILURE_SUBSCRIBE_DENIED)).To(Equal(FAILURE_ACTION_READ_ONLY))
+		Expect(policy.Action(FAILURE_MISSING_BOT_SECRET)).To(Equal(FAILURE_ACTION_SHUTDOWN))
+	})
+	It("refuses unknown failures and actions", func() {
+		Expect(ParseFailurePolicy("auth_denied=retry")).Error().To(HaveOccurred())
+		Expect(ParseFailurePolicy("subscribe_denied=ignore")).Error().To(HaveOccurred())
+		Expect(ParseFailurePolicy("subscribe_denied")).Error().To(HaveOccurred())
+	})
+	It("only abandons matches for failures about a match", func() {
+		Expect(ParseFailurePolicy("subscribe_denied=abandon_match")).Error().ToNot(HaveOccurred())
+		Expect(ParseFailurePolicy("auth_upgrade_denied=abandon_match")).Error().To(HaveOccurred())
+	})
+})
@@ -0,0 +1,106 @@
+package arbitrator_client
+
+import (
+	"fmt"
+	mainMods "github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+)
+
+// HandleDrawOfferMessage answers the opponent's draw offer as the bot's policy decides
+func (ac *ArbitratorClient) HandleDrawOfferMessage(msg *mainMods.Message) error {
+	content, ok := msg.Content.(*mods.DrawMessageContent)
+	if !ok {
+		return fmt.Errorf("could not cast message to DrawMessageContent")
+	}
+	if msg.SenderKey == ac.PublicKey() {
+		return nil
+	}
+	botClient, botClientErr := ac.BotMngr.ClientByMatchId(content.MatchId)
+	if botClientErr != nil {
+		return botClientErr
+	}
+	isAccepted := ac.GamePolicy.AcceptsDraw(botClient)
+	return AnswerDrawOffer(ac.SendMessage, content.MatchId, isAccepted)
+}
+
+// HandleRematchOfferMessage answers the opponent's offer to replay a finished match. The offer is
+// accepted by challenging the opponent to the rematch, so it is declined whenever the server takes
+// on no new games.
+func (ac *ArbitratorClient) HandleRematchOfferMessage(msg *mainMods.Message) error {
+	content, ok := msg.Content.(*mods.RematchMessageContent)
+	if !ok {
+		return fmt.Errorf("could not cast message to RematchMessageContent")
+	}
+	if msg.SenderKey == ac.PublicKey() {
+		return nil
+	}
+	recent := ac.recentMatches.Get(content.MatchId)
+	if recent == nil {
+		return fmt.Errorf("no finished match %s to rematch", content.MatchId)
+	}
+	isAccepted := !ac.IsDraining() && !ac.IsReadOnly() && ac.GamePolicy.AcceptsRematch(recent.BotName, content.MatchId)
+	if isAccepted {
+		if proposeErr := ac.ProposeRematch(recent); proposeErr != nil {
+			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not accept rematch of match %s: %s", content.MatchId, proposeErr))
+			isAccepted = false
+		}
+	}
+	return AnswerRematchOffer(ac.SendMessage, content.MatchId, isAccepted)
+}
+
+// ProposeRematch starts a bot for another game against the finished match's opponent, with the
+// colors swapped, and challenges the opponent to it. The bot is removed if the challenge cannot be
+// sent.
+func (ac *ArbitratorClient) ProposeRematch(recent *RecentMatch) error {
+	seek := recent.Rematch()
+	botClient, botInitErr := ac.BotMngr.InitSeekBot(seek)
+	if botInitErr != nil {
+		return botInitErr
+	}
+	if sendErr := RequestChallenge(ac.SendMessage, seek.WireChallenge(ac.PublicKey())); sendErr != nil {
+		_ = ac.BotMngr.RemoveBot(botClient.Key())
+		return sendErr
+	}
+	return nil
+}
+
+// recordFinishedMatch keeps the match for rematch offers, and proposes a rematch if the bot's
+// policy calls for one
+func (ac *ArbitratorClient) recordFinishedMatch(botName string, match *mainMods.Match, isBotWhite bool) {
+	recent := ac.recentMatches.Add(botName, match, isBotWhite)
+	if ac.IsDraining() || ac.IsReadOnly() || !ac.GamePolicy.ProposesRematch(botName, recent.PriorGames) {
+		return
+	}
+	if proposeErr := ac.ProposeRematch(recent); proposeErr != nil {
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not propose rematch of match %s: %s", match.Uuid, proposeErr))
+	}
+}
+
+// resign resigns the bot's match, returning false if the resignation could not be sent, in which
+// case the bot plays on
+func (ac *ArbitratorClient) resign(match *mainMods.Match) bool {
+	if resignErr := Resign(ac.SendMessage, match.Uuid); resignErr != nil {
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not resign match %s: %s", match.Uuid, resignErr))
+		return false
+	}
+	return true
+}
+
+// AbortUnstartedMatches aborts the matches in which a side has yet to make its first move
+func (ac *ArbitratorClient) AbortUnstartedMatches() {
+	for _, botClient := range ac.BotMngr.Clients() {
+		match := botClient.LastMatch()
+		if match == nil || !IsAbortable(match) {
+			continue
+		}
+		if abortErr := Abort(ac.SendMessage, match.Uuid); abortErr != nil {
+			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not abort match %s: %s", match.Uuid, abortErr))
+		}
+	}
+}
+
+// IsAbortable reports whether the match is in progress and a side has yet to make its first move
+func IsAbortable(match *mainMods.Match) bool {
+	return match.Result == mainMods.MATCH_RESULT_IN_PROGRESS && bot_manager.Ply(match) < 2
+}
@@ -0,0 +1,44 @@
+package arbitrator_client_test
+
+import (
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	. "github.com/CameronHonis/chess-bot-server/arbitrator_client"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IsAbortable", func() {
+	It("aborts matches until each side has moved", func() {
+		match := builders.NewMatchBuilder().Build()
+		match.Result = models.MATCH_RESULT_IN_PROGRESS
+		Expect(IsAbortable(match)).To(BeTrue())
+		move, _ := chess.MoveFromLongAlgebraic("e2e4", match.Board)
+		match.Board = chess.GetBoardFromMove(match.Board, move)
+		Expect(IsAbortable(match)).To(BeTrue())
+		move, _ = chess.MoveFromLongAlgebraic("e7e5", match.Board)
+		match.Board = chess.GetBoardFromMove(match.Board, move)
+		Expect(IsAbortable(match)).To(BeFalse())
+	})
+	It("does not abort finished matches", func() {
+		match := builders.NewMatchBuilder().Build()
+		match.Result = models.MATCH_RESULT_WHITE_WINS_BY_RESIGNATION
+		Expect(IsAbortable(match)).To(BeFalse())
+	})
+})
+
+var _ = Describe("UnmarshalToMessage", func() {
+	It("parses the content types the arbitrator does not know yet", func() {
+		msg, unmarshalErr := mods.UnmarshalToMessage([]byte(`{"topic": "match-m", "contentType": "OFFER_DRAW", "content": {"matchId": "m"}}`))
+		Expect(unmarshalErr).ToNot(HaveOccurred())
+		Expect(msg.Topic).To(Equal(models.MessageTopic("match-m")))
+		Expect(msg.Content).To(Equal(&mods.DrawMessageContent{MatchId: "m"}))
+	})
+	It("parses the arbitrator's own content types", func() {
+		msg, unmarshalErr := mods.UnmarshalToMessage([]byte(`{"contentType": "RESIGN_MATCH", "content": {"matchId": "m"}}`))
+		Expect(unmarshalErr).ToNot(HaveOccurred())
+		Expect(msg.ContentType).To(Equal(models.CONTENT_TYPE_RESIGN_MATCH))
+	})
+})
@@ -0,0 +1,140 @@
+package arbitrator_client
+
+import (
+	"fmt"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"sync"
+)
+
+// MessageHandler handles a message of a content type it was registered for
+type MessageHandler func(msg *models.Message) error
+
+// Middleware wraps the handlers of a content type. It can act before and after calling next, or
+// not call it at all to drop the message.
+type Middleware func(contentType models.ContentType, next MessageHandler) MessageHandler
+
+// HandlerRegistry routes messages to the handlers registered for their content type, so that
+// services can hook into the message stream without knowing of each other. Each handler runs
+// inside the middleware for every content type, in the order it was added, then inside the
+// middleware for its own content type.
+type HandlerRegistry struct {
+	mu               sync.RWMutex
+	handlersByType   map[models.ContentType][]MessageHandler
+	middleware       []Middleware
+	middlewareByType map[models.ContentType][]Middleware
+}
+
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlersByType:   make(map[models.ContentType][]MessageHandler),
+		middlewareByType: make(map[models.ContentType][]Middleware),
+	}
+}
+
+// Handle adds a handler for the content type, after those already registered for it
+func (r *HandlerRegistry) Handle(contentType models.ContentType, handler MessageHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlersByType[contentType] = append(r.handlersByType[contentType], handler)
+}
+
+// Use adds middleware around the handlers of every content type
+func (r *HandlerRegistry) Use(middleware Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, middleware)
+}
+
+// UseFor adds middleware around the handlers of the content type
+func (r *HandlerRegistry) UseFor(contentType models.ContentType, middleware Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middlewareByType[contentType] = append(r.middlewareByType[contentType], middleware)
+}
+
+// IsHandled reports whether any handler is registered for the content type
+func (r *HandlerRegistry) IsHandled(contentType models.ContentType) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.handlersByType[contentType]) > 0
+}
+
+// Dispatch passes the message to every handler for its content type, in the order they were
+// registered, returning the errors of those that failed. A failed handler does not stop the rest.
+func (r *HandlerRegistry) Dispatch(msg *models.Message) []error {
+	r.mu.RLock()
+	handlers := r.handlersByType[msg.ContentType]
+	middleware := append(append([]Middleware{}, r.middleware...), r.middlewareByType[msg.ContentType]...)
+	r.mu.RUnlock()
+
+	errs := make([]error, 0)
+	for _, handler := range handlers {
+		for idx := len(middleware) - 1; idx >= 0; idx-- {
+			handler = middleware[idx](msg.ContentType, handler)
+		}
+		if handleErr := handler(msg); handleErr != nil {
+			errs = append(errs, handleErr)
+		}
+	}
+	return errs
+}
+
+// IgnoreMessage is registered for content types the server receives but has no use for, so that
+// they are not mistaken for unknown content types
+func IgnoreMessage(msg *models.Message) error {
+	return nil
+}
+
+// RecoverPanics turns a panicking handler into a failed one, so that one bad message cannot take
+// down the server's other games
+func RecoverPanics(contentType models.ContentType, next MessageHandler) MessageHandler {
+	return func(msg *models.Message) (handleErr error) {
+		defer func() {
+			if r := recover(); r != nil {
+				handleErr = fmt.Errorf("handler for %s panicked: %v", contentType, r)
+			}
+		}()
+		return next(msg)
+	}
+}
+
+// MessageStats counts the handled and failed messages of each content type
+type MessageStats struct {
+	mu            sync.Mutex
+	handledByType map[models.ContentType]uint
+	failedByType  map[models.ContentType]uint
+}
+
+func NewMessageStats() *MessageStats {
+	return &MessageStats{
+		handledByType: make(map[models.ContentType]uint),
+		failedByType:  make(map[models.ContentType]uint),
+	}
+}
+
+// Middleware counts each handler's result
+func (s *MessageStats) Middleware(contentType models.ContentType, next MessageHandler) MessageHandler {
+	return func(msg *models.Message) error {
+		handleErr := next(msg)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if handleErr != nil {
+			s.failedByType[contentType]++
+		} else {
+			s.handledByType[contentType]++
+		}
+		return handleErr
+	}
+}
+
+func (s *MessageStats) Handled(contentType models.ContentType) uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handledByType[contentType]
+}
+
+func (s *MessageStats) Failed(contentType models.ContentType) uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failedByType[contentType]
+}
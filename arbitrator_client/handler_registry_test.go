@@ -0,0 +1,73 @@
+package arbitrator_client_test
+
+import (
+	"errors"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	. "github.com/CameronHonis/chess-bot-server/arbitrator_client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HandlerRegistry", func() {
+	var registry *HandlerRegistry
+	var calls []string
+	Record := func(name string) MessageHandler {
+		return func(msg *models.Message) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	Wrap := func(name string) Middleware {
+		return func(contentType models.ContentType, next MessageHandler) MessageHandler {
+			return func(msg *models.Message) error {
+				calls = append(calls, name)
+				return next(msg)
+			}
+		}
+	}
+	BeforeEach(func() {
+		registry = NewHandlerRegistry()
+		calls = make([]string, 0)
+	})
+	It("runs every handler for the content type in order", func() {
+		registry.Handle(models.CONTENT_TYPE_MOVE, Record("first"))
+		registry.Handle(models.CONTENT_TYPE_MOVE, Record("second"))
+		registry.Handle(models.CONTENT_TYPE_AUTH, Record("auth"))
+		Expect(registry.Dispatch(NewMessage(models.CONTENT_TYPE_MOVE))).To(BeEmpty())
+		Expect(calls).To(Equal([]string{"first", "second"}))
+	})
+	It("runs handlers inside the shared middleware, then the content type's", func() {
+		registry.UseFor(models.CONTENT_TYPE_MOVE, Wrap("move"))
+		registry.Use(Wrap("outer"))
+		registry.Use(Wrap("inner"))
+		registry.Handle(models.CONTENT_TYPE_MOVE, Record("handler"))
+		registry.Dispatch(NewMessage(models.CONTENT_TYPE_MOVE))
+		Expect(calls).To(Equal([]string{"outer", "inner", "move", "handler"}))
+	})
+	It("keeps dispatching after a handler fails", func() {
+		handleErr := errors.New("bad move")
+		registry.Handle(models.CONTENT_TYPE_MOVE, func(msg *models.Message) error {
+			return handleErr
+		})
+		registry.Handle(models.CONTENT_TYPE_MOVE, Record("after"))
+		Expect(registry.Dispatch(NewMessage(models.CONTENT_TYPE_MOVE))).To(ConsistOf(handleErr))
+		Expect(calls).To(Equal([]string{"after"}))
+	})
+	It("reports content types without handlers", func() {
+		registry.Handle(models.CONTENT_TYPE_MOVE, IgnoreMessage)
+		Expect(registry.IsHandled(models.CONTENT_TYPE_MOVE)).To(BeTrue())
+		Expect(registry.IsHandled(models.CONTENT_TYPE_AUTH)).To(BeFalse())
+	})
+	It("counts a recovered panic as a failure", func() {
+		stats := NewMessageStats()
+		registry.Use(stats.Middleware)
+		registry.Use(RecoverPanics)
+		registry.Handle(models.CONTENT_TYPE_MOVE, func(msg *models.Message) error {
+			panic("bad move")
+		})
+		registry.Handle(models.CONTENT_TYPE_MOVE, IgnoreMessage)
+		Expect(registry.Dispatch(NewMessage(models.CONTENT_TYPE_MOVE))).To(HaveLen(1))
+		Expect(stats.Failed(models.CONTENT_TYPE_MOVE)).To(Equal(uint(1)))
+		Expect(stats.Handled(models.CONTENT_TYPE_MOVE)).To(Equal(uint(1)))
+	})
+})
@@ -0,0 +1,80 @@
+package arbitrator_client
+
+import (
+	"fmt"
+	"github.com/gorilla/websocket"
+	"strconv"
+	"time"
+)
+
+const (
+	DEFAULT_PING_INTERVAL = 10 * time.Second
+	DEFAULT_PONG_TIMEOUT  = 30 * time.Second
+	DEFAULT_WRITE_TIMEOUT = 10 * time.Second
+)
+
+// Heartbeat keeps the connection alive and detects when it has died. A connection that delivers
+// neither a message nor a pong within the pong timeout is dead, which catches half-open TCP
+// connections that would otherwise block reads indefinitely. A zero field takes its default.
+type Heartbeat struct {
+	PingInterval time.Duration
+	// PongTimeout is how long a connection may go silent. It is raised to three ping intervals if
+	// it would not outlast a ping.
+	PongTimeout time.Duration
+	// WriteTimeout bounds every write, including pings
+	WriteTimeout time.Duration
+}
+
+func (h *Heartbeat) withDefaults() *Heartbeat {
+	heartbeat := *h
+	if heartbeat.PingInterval == 0 {
+		heartbeat.PingInterval = DEFAULT_PING_INTERVAL
+	}
+	if heartbeat.PongTimeout == 0 {
+		heartbeat.PongTimeout = DEFAULT_PONG_TIMEOUT
+	}
+	if heartbeat.PongTimeout <= heartbeat.PingInterval {
+		heartbeat.PongTimeout = 3 * heartbeat.PingInterval
+	}
+	if heartbeat.WriteTimeout == 0 {
+		heartbeat.WriteTimeout = DEFAULT_WRITE_TIMEOUT
+	}
+	return &heartbeat
+}
+
+// Arm sets the connection's read deadline, which is extended on every pong. Each pong's round
+// trip is reported to onPong, which runs on the reading goroutine.
+func (h *Heartbeat) Arm(conn *websocket.Conn, onPong func(latency time.Duration)) error {
+	conn.SetPongHandler(func(appData string) error {
+		if sentNanos, parseErr := strconv.ParseInt(appData, 10, 64); parseErr == nil && onPong != nil {
+			onPong(time.Since(time.Unix(0, sentNanos)))
+		}
+		return h.Extend(conn)
+	})
+	return h.Extend(conn)
+}
+
+// Extend pushes back the read deadline after the connection shows signs of life
+func (h *Heartbeat) Extend(conn *websocket.Conn) error {
+	return conn.SetReadDeadline(time.Now().Add(h.PongTimeout))
+}
+
+// RunPinger pings the connection every interval until stop is closed. The ping carries its send
+// time, which the pong echoes back. If a ping cannot be written, the connection is closed, so
+// that the reader fails and the client reconnects.
+func (h *Heartbeat) RunPinger(conn *websocket.Conn, stop <-chan struct{}) error {
+	ticker := time.NewTicker(h.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case now := <-ticker.C:
+			payload := []byte(strconv.FormatInt(now.UnixNano(), 10))
+			if pingErr := conn.WriteControl(websocket.PingMessage, payload, now.Add(h.WriteTimeout)); pingErr != nil {
+				_ = conn.Close()
+				return fmt.Errorf("could not ping arbitrator: %s", pingErr)
+			}
+		}
+	}
+}
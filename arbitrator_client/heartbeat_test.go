@@ -0,0 +1,70 @@
+package arbitrator_client_test
+
+import (
+	. "github.com/CameronHonis/chess-bot-server/arbitrator_client"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"net"
+	"time"
+)
+
+var _ = Describe("Heartbeat", func() {
+	heartbeat := &Heartbeat{PingInterval: 20 * time.Millisecond, PongTimeout: 200 * time.Millisecond, WriteTimeout: time.Second}
+	var stop chan struct{}
+	BeforeEach(func() {
+		stop = make(chan struct{})
+		DeferCleanup(func() { close(stop) })
+	})
+	dial := func(onConn func(conn *websocket.Conn)) *websocket.Conn {
+		wsServer := NewWsServer(onConn)
+		wsServer.Start()
+		DeferCleanup(wsServer.Close)
+		conn, dialErr := (&DialOptions{Path: "/ws"}).Dial(wsServer.Host())
+		Expect(dialErr).ToNot(HaveOccurred())
+		DeferCleanup(conn.Close)
+		return conn
+	}
+	It("fills in the defaults", func() {
+		config := NewArbitratorClientConfig("", "", nil, 0, nil, nil, &Heartbeat{PingInterval: time.Minute}, nil)
+		Expect(config.Heartbeat().PongTimeout).To(Equal(3 * time.Minute))
+		Expect(config.Heartbeat().WriteTimeout).To(Equal(DEFAULT_WRITE_TIMEOUT))
+	})
+	When("the arbitrator answers pings", func() {
+		It("keeps the connection alive and measures the round trip", func() {
+			// reading lets the server answer pings
+			conn := dial(func(conn *websocket.Conn) {
+				for {
+					if _, _, readErr := conn.ReadMessage(); readErr != nil {
+						return
+					}
+				}
+			})
+			latencies := make(chan time.Duration, 100)
+			Expect(heartbeat.Arm(conn, func(latency time.Duration) { latencies <- latency })).To(Succeed())
+			go func() { _ = heartbeat.RunPinger(conn, stop) }()
+
+			readErrs := make(chan error, 1)
+			go func() {
+				_, _, readErr := conn.ReadMessage()
+				readErrs <- readErr
+			}()
+			Consistently(readErrs, 500*time.Millisecond).ShouldNot(Receive())
+			Expect(latencies).To(Receive(BeNumerically(">", 0)))
+		})
+	})
+	When("the arbitrator goes silent", func() {
+		It("declares the connection dead", func() {
+			silence := make(chan struct{})
+			DeferCleanup(func() { close(silence) })
+			conn := dial(func(conn *websocket.Conn) { <-silence })
+			Expect(heartbeat.Arm(conn, nil)).To(Succeed())
+			go func() { _ = heartbeat.RunPinger(conn, stop) }()
+
+			_, _, readErr := conn.ReadMessage()
+			netErr, ok := readErr.(net.Error)
+			Expect(ok).To(BeTrue())
+			Expect(netErr.Timeout()).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,81 @@
+package arbitrator_client
+
+import (
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	"sync"
+)
+
+// MatchUpdate is an update for a match worker to play
+type MatchUpdate struct {
+	Match *models.Match
+	// IsResumed marks the bot's last known state of the match, replayed after a reconnect or
+	// restart, which the bot has already seen
+	IsResumed bool
+}
+
+// MatchWorker plays one match off the listening goroutine, so that a long search holds up neither
+// the server's other matches nor its other messages. Updates wait in an inbox of one, where a
+// newer update replaces one the worker has yet to take.
+type MatchWorker struct {
+	MatchId string
+	mu      sync.Mutex
+	pending *MatchUpdate
+	// notify wakes the worker whenever an update is waiting
+	notify   chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewMatchWorker(matchId string) *MatchWorker {
+	return &MatchWorker{
+		MatchId: matchId,
+		notify:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Offer puts the update in the inbox, returning false if it was dropped for the update already
+// waiting. An update is dropped if it is older than the waiting update, or if it is resumed, since
+// a resumed update carries nothing a received update does not.
+func (w *MatchWorker) Offer(update *MatchUpdate) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pending != nil && (update.IsResumed || bot_manager.Ply(update.Match) < bot_manager.Ply(w.pending.Match)) {
+		return false
+	}
+	w.pending = update
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Run passes each update to play until play returns false or the worker is stopped
+func (w *MatchWorker) Run(play func(update *MatchUpdate) bool) {
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.notify:
+		}
+		w.mu.Lock()
+		update := w.pending
+		w.pending = nil
+		w.mu.Unlock()
+		if update == nil {
+			continue
+		}
+		if !play(update) {
+			return
+		}
+	}
+}
+
+// Stop ends the worker once the update it is playing, if any, returns
+func (w *MatchWorker) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
@@ -0,0 +1,58 @@
+package arbitrator_client_test
+
+import (
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	. "github.com/CameronHonis/chess-bot-server/arbitrator_client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func NewMatchAtMove(fullMoveCount uint16) *models.Match {
+	board := chess.GetInitBoard()
+	board.FullMoveCount = fullMoveCount
+	return &models.Match{Uuid: "match", Board: board, Result: models.MATCH_RESULT_IN_PROGRESS}
+}
+
+var _ = Describe("MatchWorker", func() {
+	var worker *MatchWorker
+	BeforeEach(func() {
+		worker = NewMatchWorker("match")
+	})
+	It("replaces a queued update with a newer one", func() {
+		Expect(worker.Offer(&MatchUpdate{Match: NewMatchAtMove(1)})).To(BeTrue())
+		Expect(worker.Offer(&MatchUpdate{Match: NewMatchAtMove(2)})).To(BeTrue())
+		played := make([]*MatchUpdate, 0)
+		worker.Run(func(update *MatchUpdate) bool {
+			played = append(played, update)
+			return false
+		})
+		Expect(played).To(HaveLen(1))
+		Expect(played[0].Match.Board.FullMoveCount).To(Equal(uint16(2)))
+	})
+	It("keeps a queued update over an older one", func() {
+		Expect(worker.Offer(&MatchUpdate{Match: NewMatchAtMove(2)})).To(BeTrue())
+		Expect(worker.Offer(&MatchUpdate{Match: NewMatchAtMove(1)})).To(BeFalse())
+	})
+	It("keeps a queued update over a resumed one", func() {
+		Expect(worker.Offer(&MatchUpdate{Match: NewMatchAtMove(1)})).To(BeTrue())
+		Expect(worker.Offer(&MatchUpdate{Match: NewMatchAtMove(1), IsResumed: true})).To(BeFalse())
+	})
+	It("plays updates until stopped", func() {
+		played := make(chan *MatchUpdate)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			worker.Run(func(update *MatchUpdate) bool {
+				played <- update
+				return true
+			})
+		}()
+		worker.Offer(&MatchUpdate{Match: NewMatchAtMove(1)})
+		Eventually(played).Should(Receive())
+		worker.Offer(&MatchUpdate{Match: NewMatchAtMove(2)})
+		Eventually(played).Should(Receive())
+		worker.Stop()
+		Eventually(done).Should(BeClosed())
+	})
+})
@@ -1,37 +1,64 @@
 package arbitrator_client
 
 import (
+	"context"
 	"fmt"
 	mainMods "github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	mods "github.com/CameronHonis/chess-bot-server/models"
 	"os"
+	"strings"
 )
 
-func (ac *ArbitratorClient) HandleMsg(msg *mainMods.Message) error {
-	switch msg.ContentType {
-	case mainMods.CONTENT_TYPE_AUTH:
-		return ac.HandleAuthMessage(msg)
-	case mainMods.CONTENT_TYPE_MATCH_UPDATED:
-		return ac.HandleMatchUpdateMessage(msg)
-	case mainMods.CONTENT_TYPE_UPGRADE_AUTH_DENIED:
-		return ac.HandleUpgradeAuthDeniedMessage(msg)
-	case mainMods.CONTENT_TYPE_UPGRADE_AUTH_GRANTED:
-		return ac.HandleUpgradeAuthGrantedMessage(msg)
-	case mainMods.CONTENT_TYPE_SUBSCRIBE_REQUEST_DENIED:
-		return ac.HandleSubscribeDeniedMessage(msg)
-	case mainMods.CONTENT_TYPE_CHALLENGE_UPDATED:
+// registerHandlers registers the client's own handlers. Panics are recovered inside the stats
+// middleware, so that they count as failures.
+func (ac *ArbitratorClient) registerHandlers() {
+	ac.handlers.Use(ac.stats.Middleware)
+	ac.handlers.Use(RecoverPanics)
+
+	ac.handlers.Handle(mainMods.CONTENT_TYPE_AUTH, ac.HandleAuthMessage)
+	ac.handlers.Handle(mainMods.CONTENT_TYPE_MATCH_UPDATED, ac.HandleMatchUpdateMessage)
+	ac.handlers.Handle(mainMods.CONTENT_TYPE_UPGRADE_AUTH_DENIED, ac.HandleUpgradeAuthDeniedMessage)
+	ac.handlers.Handle(mainMods.CONTENT_TYPE_UPGRADE_AUTH_GRANTED, ac.HandleUpgradeAuthGrantedMessage)
+	ac.handlers.Handle(mainMods.CONTENT_TYPE_SUBSCRIBE_REQUEST_DENIED, ac.HandleSubscribeDeniedMessage)
+	ac.handlers.Handle(mainMods.CONTENT_TYPE_CHALLENGE_UPDATED, func(msg *mainMods.Message) error {
 		return HandleChallengeUpdatedMessage(ac, msg)
-	case mainMods.CONTENT_TYPE_SUBSCRIBE_REQUEST_GRANTED:
-		return nil
-	case mainMods.CONTENT_TYPE_MOVE:
-		return nil
-	case mainMods.CONTENT_TYPE_ACCEPT_CHALLENGE:
-		return nil
-	case mainMods.CONTENT_TYPE_DECLINE_CHALLENGE:
-		return nil
-	case mainMods.CONTENT_TYPE_REVOKE_CHALLENGE:
-		return nil
-	default:
-		return fmt.Errorf("unhandled message with content type %s", msg.ContentType)
+	})
+	ac.handlers.Handle(mainMods.CONTENT_TYPE_REVOKE_CHALLENGE, ac.HandleRevokeChallengeMessage)
+	ac.handlers.Handle(mainMods.CONTENT_TYPE_CHALLENGE_REQUEST_FAILED, ac.HandleChallengeRequestFailedMessage)
+	ac.handlers.Handle(mods.CONTENT_TYPE_OFFER_DRAW, ac.HandleDrawOfferMessage)
+	ac.handlers.Handle(mods.CONTENT_TYPE_OFFER_REMATCH, ac.HandleRematchOfferMessage)
+	ac.handlers.Handle(mainMods.CONTENT_TYPE_SUBSCRIBE_REQUEST_GRANTED, ac.HandleSubscribeGrantedMessage)
+	// the echoes of the server's own messages, and of other players' messages on its topics
+	for _, contentType := range []mainMods.ContentType{
+		mainMods.CONTENT_TYPE_MOVE,
+		mainMods.CONTENT_TYPE_ACCEPT_CHALLENGE,
+		mainMods.CONTENT_TYPE_DECLINE_CHALLENGE,
+		mainMods.CONTENT_TYPE_RESIGN_MATCH,
+		mods.CONTENT_TYPE_ACCEPT_DRAW,
+		mods.CONTENT_TYPE_DECLINE_DRAW,
+		mods.CONTENT_TYPE_ABORT_MATCH,
+		mods.CONTENT_TYPE_ACCEPT_REMATCH,
+		mods.CONTENT_TYPE_DECLINE_REMATCH,
+	} {
+		ac.handlers.Handle(contentType, IgnoreMessage)
+	}
+}
+
+// HandleMsg passes the message to the handlers registered for its content type. Content types
+// without handlers are counted and logged, and failures are acted on by the failure policy.
+func (ac *ArbitratorClient) HandleMsg(msg *mainMods.Message) {
+	if !ac.handlers.IsHandled(msg.ContentType) {
+		count := ac.countUnknownMessage(msg.ContentType)
+		ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("ignored message with unknown content type %s (%d received)", msg.ContentType, count))
+		return
+	}
+	for _, handleErr := range ac.handlers.Dispatch(msg) {
+		if failureErr, ok := handleErr.(*FailureError); ok {
+			ac.OnFailure(failureErr)
+			continue
+		}
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not handle %s message: %s", msg.ContentType, handleErr))
 	}
 }
 
@@ -40,10 +67,17 @@ func (ac *ArbitratorClient) HandleAuthMessage(msg *mainMods.Message) error {
 	if !ok {
 		return fmt.Errorf("could not cast message to AuthMessageContent")
 	}
+	if ac.pubKey != "" && ac.pubKey != content.PublicKey {
+		// the arbitrator did not accept the previous creds, so matches under them cannot be played
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("creds for %s were not renewed, abandoning all bots", ac.pubKey))
+		ac.stopMatchWorkers()
+		ac.BotMngr.RemoveAllBots()
+	}
 	ac.SetPublicPrivateKey(content.PublicKey, content.PrivateKey)
+	ac.Journal.RecordCreds(content.PublicKey, content.PrivateKey)
 	botSecret, ok := os.LookupEnv("BOT_CLIENT_SECRET")
 	if !ok {
-		panic("could not determine bot client secret")
+		return &FailureError{Failure: FAILURE_MISSING_BOT_SECRET, Detail: "BOT_CLIENT_SECRET is not set"}
 	}
 	authUpgradeErr := RequestAuthUpgrade(ac.SendMessage, mainMods.BOT, botSecret)
 	if authUpgradeErr != nil {
@@ -53,13 +87,19 @@ func (ac *ArbitratorClient) HandleAuthMessage(msg *mainMods.Message) error {
 	return nil
 }
 
+// HandleUpgradeAuthGrantedMessage resumes the matches in progress and rejoins matchmaking
 func (ac *ArbitratorClient) HandleUpgradeAuthGrantedMessage(msg *mainMods.Message) error {
-	//return GetArbitratorClient().RequestSubscribe("findBotMatch")
+	ac.setAuthorized()
+	ac.ResumeSession()
+	if !ac.IsDraining() && !ac.IsReadOnly() {
+		ac.RejoinMatchmaking()
+	}
+	ac.closeIfDrained()
 	return nil
 }
 
 func (ac *ArbitratorClient) HandleUpgradeAuthDeniedMessage(msg *mainMods.Message) error {
-	panic("arbitrator denied bot client auth")
+	return &FailureError{Failure: FAILURE_AUTH_UPGRADE_DENIED, Detail: "arbitrator denied the upgrade to a bot client"}
 }
 
 func (ac *ArbitratorClient) HandleSubscribeDeniedMessage(msg *mainMods.Message) error {
@@ -68,9 +108,40 @@ func (ac *ArbitratorClient) HandleSubscribeDeniedMessage(msg *mainMods.Message)
 		return fmt.Errorf("could not cast message to SubscribeRequestDeniedMessageContent")
 	}
 
-	panic(fmt.Sprintf("arbitrator denied bot client subscription%s", content.Topic))
+	return &FailureError{
+		Failure: FAILURE_SUBSCRIBE_DENIED,
+		Detail:  fmt.Sprintf("arbitrator denied the subscription to %s", content.Topic),
+		Topic:   content.Topic,
+	}
+}
+
+// matchIdFromTopic is the id of the match the topic is for, if it is a match's topic
+func matchIdFromTopic(topic mainMods.MessageTopic) (string, bool) {
+	if !strings.HasPrefix(string(topic), "match-") {
+		return "", false
+	}
+	return strings.TrimPrefix(string(topic), "match-"), true
+}
+
+// HandleSubscribeGrantedMessage resumes the match once its topic is subscribed to again
+func (ac *ArbitratorClient) HandleSubscribeGrantedMessage(msg *mainMods.Message) error {
+	content, ok := msg.Content.(*mainMods.SubscribeRequestGrantedMessageContent)
+	if !ok {
+		return fmt.Errorf("could not cast message to SubscribeRequestGrantedMessageContent")
+	}
+	matchId, isMatchTopic := matchIdFromTopic(content.Topic)
+	if !isMatchTopic {
+		return nil
+	}
+	if botClient, _ := ac.BotMngr.ClientByMatchId(matchId); botClient != nil {
+		ac.resumeMatch(botClient)
+	}
+	return nil
 }
 
+// HandleMatchUpdateMessage passes the update to the worker for its match. An update that ends
+// the match cancels the bot's search straight away, rather than once the worker takes it, and an
+// update past the bot's last ply drops the moves still queued for the match, which are stale.
 func (ac *ArbitratorClient) HandleMatchUpdateMessage(msg *mainMods.Message) error {
 	content, ok := msg.Content.(*mainMods.MatchUpdateMessageContent)
 	if !ok {
@@ -82,25 +153,94 @@ func (ac *ArbitratorClient) HandleMatchUpdateMessage(msg *mainMods.Message) erro
 	if botClientErr != nil {
 		return botClientErr
 	}
+	if match.Result != mainMods.MATCH_RESULT_IN_PROGRESS {
+		botClient.CancelSearch()
+	}
+	if match.Result != mainMods.MATCH_RESULT_IN_PROGRESS || bot_manager.Ply(match) > botClient.Record().LastPly() {
+		ac.outbox.DropMoves(match.Uuid)
+	}
+	ac.dispatchMatchUpdate(botClient, &MatchUpdate{Match: match})
+	return nil
+}
 
+// PlayMatchUpdate passes the update to the bot and searches for its move if it is the bot's turn,
+// returning false once the match is over. It runs on the match's worker.
+func (ac *ArbitratorClient) PlayMatchUpdate(botClient *bot_manager.BotClient, update *MatchUpdate) bool {
+	match := update.Match
+	isBotWhite := match.WhiteClientKey == ac.PublicKey()
+	isFirstUpdate := botClient.Record().MatchId() != match.Uuid
+	if !update.IsResumed && !ac.BotMngr.UpdateMatch(botClient, match, isBotWhite) {
+		ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("ignored stale update for match %s at ply %d", match.Uuid, bot_manager.Ply(match)))
+		return true
+	}
+	challenge := botClient.Challenge()
+	if isFirstUpdate {
+		ac.Dispatch(NewMatchStartedEvent(challenge.BotName, challenge.Uuid, match))
+	}
 	if match.Result != mainMods.MATCH_RESULT_IN_PROGRESS {
-		removeErr := ac.BotMngr.RemoveBot(botClient.Key())
-		if removeErr != nil {
-			return removeErr
+		ac.Dispatch(NewMatchFinishedEvent(challenge.BotName, challenge.Uuid, match))
+		defer ac.closeIfDrained()
+		if removeErr := ac.BotMngr.RemoveBot(botClient.Key()); removeErr != nil {
+			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not remove bot for match %s: %s", match.Uuid, removeErr))
 		}
+		ac.recordFinishedMatch(challenge.BotName, match, isBotWhite)
+		return false
 	}
 
-	if match.Board.IsWhiteTurn && match.BlackClientKey == ac.PublicKey() {
+	if match.Board.IsWhiteTurn != isBotWhite {
+		return true
+	}
+
+	ac.BotMngr.UpdateClock(botClient.Key(), match)
+	ac.PlayMove(botClient.StartSearch(), botClient, match)
+	return true
+}
+
+// HandleRevokeChallengeMessage removes the bots for the sender's challenges to the server
+func (ac *ArbitratorClient) HandleRevokeChallengeMessage(msg *mainMods.Message) error {
+	content, ok := msg.Content.(*mainMods.RevokeChallengeMessageContent)
+	if !ok {
+		return fmt.Errorf("could not cast message to RevokeChallengeMessageContent")
+	}
+	if content.ChallengedClientKey != ac.PublicKey() {
 		return nil
-	} else if !match.Board.IsWhiteTurn && match.WhiteClientKey == ac.PublicKey() {
+	}
+	ac.BotMngr.RevokeChallenges(msg.SenderKey)
+	return nil
+}
+
+// HandleChallengeRequestFailedMessage removes the bot for the server's challenge, which the
+// arbitrator refused
+func (ac *ArbitratorClient) HandleChallengeRequestFailedMessage(msg *mainMods.Message) error {
+	content, ok := msg.Content.(*mainMods.ChallengeRequestFailedMessageContent)
+	if !ok {
+		return fmt.Errorf("could not cast message to ChallengeRequestFailedMessageContent")
+	}
+	if content.Challenge == nil {
 		return nil
 	}
+	ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("challenge to %s failed: %s", content.Challenge.ChallengedKey, content.Reason))
+	ac.BotMngr.FailSeekChallenge(content.Challenge.ChallengedKey)
+	return nil
+}
 
-	move, moveErr := botClient.Engine().GenerateMove(match)
+func (ac *ArbitratorClient) PlayMove(ctx context.Context, botClient *bot_manager.BotClient, match *mainMods.Match) {
+	defer botClient.EndSearch(ctx)
+	if ac.GamePolicy.ShouldResign(botClient) && ac.resign(match) {
+		return
+	}
+	move, moveErr := ac.BotMngr.GenerateMove(ctx, botClient, match)
 	if moveErr != nil {
-		return moveErr
+		if ctx.Err() != nil {
+			ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("search cancelled for match %s", match.Uuid))
+			return
+		}
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not generate move for match %s: %s", match.Uuid, moveErr))
+		return
+	}
+	if sendErr := SendMove(ac.SendMessage, match.Uuid, move); sendErr != nil {
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not send move for match %s: %s", match.Uuid, sendErr))
 	}
-	return SendMove(ac.SendMessage, match.Uuid, move)
 }
 
 var HandleChallengeUpdatedMessage = func(ac *ArbitratorClient, msg *mainMods.Message) error {
@@ -113,13 +253,50 @@ var HandleChallengeUpdatedMessage = func(ac *ArbitratorClient, msg *mainMods.Mes
 		return nil
 	}
 
-	// NOTE: assumes that only update to challenge is when challenge is created
+	// revoked, declined and accepted challenges are all reported as inactive, so the bot is only
+	// reaped if no match follows
 	challenge := content.Challenge
+	if challenge.ChallengerKey == ac.PublicKey() {
+		// the server's own challenge, which is bound to its bot when the match arrives
+		if !challenge.IsActive {
+			ac.BotMngr.CloseSeekChallenge(challenge.ChallengedKey)
+		}
+		return nil
+	}
+	if !challenge.IsActive {
+		ac.BotMngr.CloseChallenge(challenge.Uuid)
+		return nil
+	}
+	if botClient, _ := ac.BotMngr.ClientByChallengeId(challenge.Uuid); botClient != nil {
+		return nil
+	}
+	ac.Dispatch(NewChallengeReceivedEvent(challenge))
+	if ac.IsDraining() {
+		return ac.declineChallenge(msg.Topic, challenge, mods.DECLINE_REASON_DRAINING, "the bot server is shutting down")
+	}
+	if ac.IsReadOnly() {
+		return ac.declineChallenge(msg.Topic, challenge, mods.DECLINE_REASON_READ_ONLY, "the bot server is not taking on new games")
+	}
+	if decision := ac.Policy.Evaluate(challenge); !decision.IsAccepted() {
+		return ac.declineChallenge(msg.Topic, challenge, mods.DECLINE_REASON_POLICY, decision.String())
+	}
 	_, botInitErr := ac.BotMngr.InitBot(challenge)
 	if botInitErr != nil {
 		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, botInitErr)
-		return DeclineChallengeRequest(ac.SendMessage, msg.Topic, challenge.ChallengerKey)
+		reason := mods.DECLINE_REASON_BOT_UNAVAILABLE
+		if admitErr, ok := botInitErr.(*bot_manager.AdmissionError); ok {
+			reason = admitErr.Reason
+		}
+		return ac.declineChallenge(msg.Topic, challenge, reason, botInitErr.Error())
 	}
 
+	ac.Dispatch(NewChallengeAcceptedEvent(challenge))
 	return AcceptChallengeRequest(ac.SendMessage, msg.Topic, challenge.ChallengerKey)
 }
+
+// declineChallenge declines the challenge with the reason, reporting the decision
+func (ac *ArbitratorClient) declineChallenge(topic mainMods.MessageTopic, challenge *mainMods.Challenge,
+	reason mods.DeclineReason, detail string) error {
+	ac.Dispatch(NewChallengeDeclinedEvent(challenge, reason, detail))
+	return DeclineChallengeWithReason(ac.SendMessage, topic, challenge.ChallengerKey, reason, detail)
+}
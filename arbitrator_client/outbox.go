@@ -0,0 +1,290 @@
+package arbitrator_client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_OUTBOX_SIZE = 256
+	// DEFAULT_SEND_TIMEOUT bounds how long a sender waits for its message to be written
+	DEFAULT_SEND_TIMEOUT = 10 * time.Second
+)
+
+var (
+	ErrOutboxFull   = errors.New("outbound queue is full")
+	ErrNotConnected = errors.New("not connected to arbitrator")
+	ErrNotReady     = errors.New("not yet authorized by arbitrator")
+	// ErrSendDeferred is returned for messages kept across a reconnect, which are written once the
+	// server is ready again
+	ErrSendDeferred = errors.New("connection lost, message will be resent after reconnecting")
+	ErrConnLost     = errors.New("connection lost before message was sent")
+	// ErrSendTimeout is returned once a message that waited too long is withdrawn from the queue,
+	// so it is never sent
+	ErrSendTimeout = errors.New("timed out waiting for message to be sent")
+	// ErrMoveStale is reported for queued moves dropped because the match moved on without them
+	ErrMoveStale = errors.New("match was updated before move was sent")
+)
+
+type Priority int
+
+const (
+	PRIORITY_LOW Priority = iota
+	PRIORITY_NORMAL
+	// PRIORITY_HIGH messages are written first, since they spend the bots' clocks
+	PRIORITY_HIGH
+)
+
+func PriorityOf(contentType models.ContentType) Priority {
+	switch contentType {
+	case models.CONTENT_TYPE_MOVE:
+		return PRIORITY_HIGH
+	case models.CONTENT_TYPE_CHALLENGE_REQUEST, models.CONTENT_TYPE_JOIN_MATCHMAKING, models.CONTENT_TYPE_LEAVE_MATCHMAKING:
+		return PRIORITY_LOW
+	default:
+		return PRIORITY_NORMAL
+	}
+}
+
+// IsReplayable reports whether a message is kept across a reconnect. Auth and subscription
+// messages are not, since they are sent again on every connection.
+func IsReplayable(contentType models.ContentType) bool {
+	switch contentType {
+	case models.CONTENT_TYPE_MOVE, models.CONTENT_TYPE_ACCEPT_CHALLENGE, models.CONTENT_TYPE_DECLINE_CHALLENGE,
+		models.CONTENT_TYPE_CHALLENGE_REQUEST, models.CONTENT_TYPE_REVOKE_CHALLENGE, models.CONTENT_TYPE_RESIGN_MATCH,
+		mods.CONTENT_TYPE_ABORT_MATCH:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsHandshake reports whether a message may be written before the server is authorized
+func IsHandshake(contentType models.ContentType) bool {
+	return contentType == models.CONTENT_TYPE_REFRESH_AUTH || contentType == models.CONTENT_TYPE_UPGRADE_AUTH_REQUEST
+}
+
+// OutboxEntry is a queued message. Its result is reported once, when the message is written,
+// dropped or deferred to the next connection.
+type OutboxEntry struct {
+	Msg        *models.Message
+	Priority   Priority
+	result     chan error
+	isResolved bool
+}
+
+func (e *OutboxEntry) resolve(err error) {
+	if e.isResolved {
+		return
+	}
+	e.isResolved = true
+	e.result <- err
+}
+
+// Outbox queues outbound messages for the single goroutine that writes to the websocket. Until
+// the outbox is opened, only handshake messages are handed out.
+type Outbox struct {
+	mu          sync.Mutex
+	queues      [PRIORITY_HIGH + 1][]*OutboxEntry
+	size        int
+	maxSize     int
+	isConnected bool
+	isOpen      bool
+	// notify wakes a waiting Pop whenever an entry may have become available
+	notify chan struct{}
+}
+
+func NewOutbox(maxSize int) *Outbox {
+	if maxSize <= 0 {
+		maxSize = DEFAULT_OUTBOX_SIZE
+	}
+	return &Outbox{
+		maxSize: maxSize,
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Push queues the message, returning the channel its result is reported on. Until the outbox is
+// open, replayable messages are queued and deferred straight away, and other messages besides
+// handshakes are refused, so that no sender waits on a connection that is not ready.
+func (o *Outbox) Push(msg *models.Message) (<-chan error, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	isReplayable := IsReplayable(msg.ContentType)
+	if !o.isConnected && !isReplayable {
+		return nil, ErrNotConnected
+	}
+	isHeld := !o.isConnected || !o.isOpen && !IsHandshake(msg.ContentType)
+	if isHeld && !isReplayable {
+		return nil, ErrNotReady
+	}
+	if o.size >= o.maxSize {
+		return nil, ErrOutboxFull
+	}
+	entry := &OutboxEntry{
+		Msg:      msg,
+		Priority: PriorityOf(msg.ContentType),
+		result:   make(chan error, 1),
+	}
+	o.queues[entry.Priority] = append(o.queues[entry.Priority], entry)
+	o.size++
+	if isHeld {
+		entry.resolve(ErrSendDeferred)
+	}
+	o.wake()
+	return entry.result, nil
+}
+
+// DropMoves drops the queued moves for the match, once an update shows they are stale
+func (o *Outbox) DropMoves(matchId string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for priority := range o.queues {
+		keptEntries := make([]*OutboxEntry, 0, len(o.queues[priority]))
+		for _, entry := range o.queues[priority] {
+			content, isMove := entry.Msg.Content.(*models.MoveMessageContent)
+			if isMove && content.MatchId == matchId {
+				entry.resolve(ErrMoveStale)
+				o.size--
+				continue
+			}
+			keptEntries = append(keptEntries, entry)
+		}
+		o.queues[priority] = keptEntries
+	}
+}
+
+// Withdraw removes the queued message whose result is reported on the channel, reporting
+// ErrSendTimeout on it. It returns false if the message is no longer queued, in which case it is
+// being written or its result has already been reported.
+func (o *Outbox) Withdraw(result <-chan error) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for priority := range o.queues {
+		for idx, entry := range o.queues[priority] {
+			if entry.result != result || entry.isResolved {
+				continue
+			}
+			o.queues[priority] = append(o.queues[priority][:idx:idx], o.queues[priority][idx+1:]...)
+			o.size--
+			entry.resolve(ErrSendTimeout)
+			return true
+		}
+	}
+	return false
+}
+
+// Pop removes the next message that may be written, highest priority first, blocking until there
+// is one or stop is closed, in which case it returns nil
+func (o *Outbox) Pop(stop <-chan struct{}) *OutboxEntry {
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		if entry := o.tryPop(); entry != nil {
+			return entry
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-o.notify:
+		}
+	}
+}
+
+func (o *Outbox) tryPop() *OutboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.isConnected {
+		return nil
+	}
+	for priority := PRIORITY_HIGH; priority >= PRIORITY_LOW; priority-- {
+		for idx, entry := range o.queues[priority] {
+			if !o.isOpen && !IsHandshake(entry.Msg.ContentType) {
+				continue
+			}
+			o.queues[priority] = append(o.queues[priority][:idx:idx], o.queues[priority][idx+1:]...)
+			o.size--
+			return entry
+		}
+	}
+	return nil
+}
+
+// Written reports the result of writing the entry. A replayable entry that failed to write goes
+// back to the front of its queue for the next connection.
+func (o *Outbox) Written(entry *OutboxEntry, writeErr error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if writeErr == nil {
+		entry.resolve(nil)
+		return
+	}
+	if !IsReplayable(entry.Msg.ContentType) {
+		entry.resolve(fmt.Errorf("could not write message: %s", writeErr))
+		return
+	}
+	o.queues[entry.Priority] = append([]*OutboxEntry{entry}, o.queues[entry.Priority]...)
+	o.size++
+	entry.resolve(ErrSendDeferred)
+}
+
+// Fail drops the entry without writing it
+func (o *Outbox) Fail(entry *OutboxEntry, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entry.resolve(err)
+}
+
+// SetConnected marks the connection as up or down. When it goes down, the outbox closes, queued
+// replayable messages are deferred and the rest are dropped.
+func (o *Outbox) SetConnected(isConnected bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.isConnected = isConnected
+	if !isConnected {
+		o.isOpen = false
+		for priority := range o.queues {
+			keptEntries := make([]*OutboxEntry, 0, len(o.queues[priority]))
+			for _, entry := range o.queues[priority] {
+				if IsReplayable(entry.Msg.ContentType) {
+					entry.resolve(ErrSendDeferred)
+					keptEntries = append(keptEntries, entry)
+				} else {
+					entry.resolve(ErrConnLost)
+					o.size--
+				}
+			}
+			o.queues[priority] = keptEntries
+		}
+	}
+	o.wake()
+}
+
+// SetOpen lets every message out, not just handshakes, once the server is authorized
+func (o *Outbox) SetOpen(isOpen bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.isOpen = isOpen
+	o.wake()
+}
+
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.size
+}
+
+// wake signals a waiting Pop without blocking. The lock must be held.
+func (o *Outbox) wake() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,125 @@
+package arbitrator_client_test
+
+import (
+	"github.com/CameronHonis/chess-arbitrator/models"
+	. "github.com/CameronHonis/chess-bot-server/arbitrator_client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func NewMessage(contentType models.ContentType) *models.Message {
+	return &models.Message{ContentType: contentType}
+}
+
+var _ = Describe("Outbox", func() {
+	var outbox *Outbox
+	var stop chan struct{}
+	BeforeEach(func() {
+		outbox = NewOutbox(4)
+		stop = make(chan struct{})
+		close(stop)
+	})
+	When("the server is ready", func() {
+		BeforeEach(func() {
+			outbox.SetConnected(true)
+			outbox.SetOpen(true)
+		})
+		It("writes moves first, then messages in the order they were queued", func() {
+			_, _ = outbox.Push(NewMessage(models.CONTENT_TYPE_CHALLENGE_REQUEST))
+			_, _ = outbox.Push(NewMessage(models.CONTENT_TYPE_SUBSCRIBE_REQUEST))
+			_, _ = outbox.Push(NewMessage(models.CONTENT_TYPE_ACCEPT_CHALLENGE))
+			_, _ = outbox.Push(NewMessage(models.CONTENT_TYPE_MOVE))
+			Expect(outbox.Pop(nil).Msg.ContentType).To(Equal(models.CONTENT_TYPE_MOVE))
+			Expect(outbox.Pop(nil).Msg.ContentType).To(Equal(models.CONTENT_TYPE_SUBSCRIBE_REQUEST))
+			Expect(outbox.Pop(nil).Msg.ContentType).To(Equal(models.CONTENT_TYPE_ACCEPT_CHALLENGE))
+			Expect(outbox.Pop(nil).Msg.ContentType).To(Equal(models.CONTENT_TYPE_CHALLENGE_REQUEST))
+		})
+		It("refuses messages beyond its size", func() {
+			for i := 0; i < 4; i++ {
+				Expect(outbox.Push(NewMessage(models.CONTENT_TYPE_MOVE))).Error().ToNot(HaveOccurred())
+			}
+			Expect(outbox.Push(NewMessage(models.CONTENT_TYPE_MOVE))).Error().To(MatchError(ErrOutboxFull))
+		})
+		It("reports the result of the write", func() {
+			result, _ := outbox.Push(NewMessage(models.CONTENT_TYPE_SUBSCRIBE_REQUEST))
+			outbox.Written(outbox.Pop(nil), nil)
+			Expect(<-result).ToNot(HaveOccurred())
+		})
+		It("resends a move whose write failed on the next connection", func() {
+			result, _ := outbox.Push(NewMessage(models.CONTENT_TYPE_MOVE))
+			outbox.Written(outbox.Pop(nil), ErrConnLost)
+			Expect(<-result).To(MatchError(ErrSendDeferred))
+			outbox.SetConnected(false)
+			outbox.SetConnected(true)
+			outbox.SetOpen(true)
+			Expect(outbox.Pop(nil).Msg.ContentType).To(Equal(models.CONTENT_TYPE_MOVE))
+		})
+		It("withdraws a message that is still queued", func() {
+			result, _ := outbox.Push(NewMessage(models.CONTENT_TYPE_SUBSCRIBE_REQUEST))
+			Expect(outbox.Withdraw(result)).To(BeTrue())
+			Expect(<-result).To(MatchError(ErrSendTimeout))
+			Expect(outbox.Len()).To(Equal(0))
+			Expect(outbox.Pop(stop)).To(BeNil())
+		})
+		It("does not withdraw a message being written", func() {
+			result, _ := outbox.Push(NewMessage(models.CONTENT_TYPE_SUBSCRIBE_REQUEST))
+			entry := outbox.Pop(nil)
+			Expect(outbox.Withdraw(result)).To(BeFalse())
+			outbox.Written(entry, nil)
+			Expect(<-result).ToNot(HaveOccurred())
+		})
+		It("returns nothing once stopped", func() {
+			_, _ = outbox.Push(NewMessage(models.CONTENT_TYPE_MOVE))
+			Expect(outbox.Pop(stop)).To(BeNil())
+		})
+	})
+	When("the server is authenticating", func() {
+		BeforeEach(func() {
+			outbox.SetConnected(true)
+		})
+		It("only writes handshakes", func() {
+			moveResult, _ := outbox.Push(NewMessage(models.CONTENT_TYPE_MOVE))
+			_, _ = outbox.Push(NewMessage(models.CONTENT_TYPE_UPGRADE_AUTH_REQUEST))
+			Expect(outbox.Pop(nil).Msg.ContentType).To(Equal(models.CONTENT_TYPE_UPGRADE_AUTH_REQUEST))
+			Expect(outbox.Pop(stop)).To(BeNil())
+			Expect(<-moveResult).To(MatchError(ErrSendDeferred))
+			outbox.SetOpen(true)
+			Expect(outbox.Pop(nil).Msg.ContentType).To(Equal(models.CONTENT_TYPE_MOVE))
+		})
+		It("refuses messages that would wait for the server to be ready", func() {
+			Expect(outbox.Push(NewMessage(models.CONTENT_TYPE_SUBSCRIBE_REQUEST))).Error().To(MatchError(ErrNotReady))
+			Expect(outbox.Len()).To(Equal(0))
+		})
+	})
+	When("a match moves on", func() {
+		It("drops the moves queued for it", func() {
+			staleMove := &models.Message{ContentType: models.CONTENT_TYPE_MOVE, Content: &models.MoveMessageContent{MatchId: "match"}}
+			otherMove := &models.Message{ContentType: models.CONTENT_TYPE_MOVE, Content: &models.MoveMessageContent{MatchId: "other"}}
+			_, _ = outbox.Push(staleMove)
+			_, _ = outbox.Push(otherMove)
+			outbox.DropMoves("match")
+			Expect(outbox.Len()).To(Equal(1))
+			outbox.SetConnected(true)
+			outbox.SetOpen(true)
+			Expect(outbox.Pop(nil).Msg).To(Equal(otherMove))
+		})
+	})
+	When("the connection drops", func() {
+		It("keeps moves for the next connection and drops the rest", func() {
+			outbox.SetConnected(true)
+			outbox.SetOpen(true)
+			moveResult, _ := outbox.Push(NewMessage(models.CONTENT_TYPE_MOVE))
+			subResult, _ := outbox.Push(NewMessage(models.CONTENT_TYPE_SUBSCRIBE_REQUEST))
+			outbox.SetConnected(false)
+			Expect(<-moveResult).To(MatchError(ErrSendDeferred))
+			Expect(<-subResult).To(MatchError(ErrConnLost))
+			Expect(outbox.Len()).To(Equal(1))
+		})
+		It("queues moves sent while disconnected", func() {
+			result, pushErr := outbox.Push(NewMessage(models.CONTENT_TYPE_MOVE))
+			Expect(pushErr).ToNot(HaveOccurred())
+			Expect(<-result).To(MatchError(ErrSendDeferred))
+			Expect(outbox.Push(NewMessage(models.CONTENT_TYPE_SUBSCRIBE_REQUEST))).Error().To(MatchError(ErrNotConnected))
+		})
+	})
+})
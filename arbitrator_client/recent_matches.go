@@ -0,0 +1,90 @@
+package arbitrator_client
+
+import (
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+	"sync"
+)
+
+const DEFAULT_RECENT_MATCHES = 64
+
+// RecentMatch is a finished match, kept so that it can be replayed once its bot is gone
+type RecentMatch struct {
+	BotName    string
+	Match      *models.Match
+	IsBotWhite bool
+	// PriorGames counts the games the bot played the opponent in a row just before this one
+	PriorGames uint
+}
+
+func (m *RecentMatch) OppKey() mods.PlrClientKey {
+	if m.IsBotWhite {
+		return m.Match.BlackClientKey
+	}
+	return m.Match.WhiteClientKey
+}
+
+// Rematch is the seek for another game against the opponent, with the colors swapped
+func (m *RecentMatch) Rematch() *bot_manager.Seek {
+	return &bot_manager.Seek{
+		Origin:      bot_manager.ORIGIN_CHALLENGER,
+		BotName:     m.BotName,
+		OppKey:      m.OppKey(),
+		TimeControl: m.Match.TimeControl,
+		IsBotWhite:  !m.IsBotWhite,
+		IsBotBlack:  m.IsBotWhite,
+	}
+}
+
+// RecentMatches keeps the latest finished matches, forgetting the oldest beyond its capacity
+type RecentMatches struct {
+	capacity int
+	matches  []*RecentMatch
+	mu       sync.Mutex
+}
+
+func NewRecentMatches(capacity int) *RecentMatches {
+	return &RecentMatches{
+		capacity: capacity,
+		matches:  make([]*RecentMatch, 0, capacity),
+	}
+}
+
+// Add records the finished match. The games against the opponent are in a row for as long as the
+// same bot plays them.
+func (r *RecentMatches) Add(botName string, match *models.Match, isBotWhite bool) *RecentMatch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recent := &RecentMatch{
+		BotName:    botName,
+		Match:      match,
+		IsBotWhite: isBotWhite,
+	}
+	for idx := len(r.matches) - 1; idx >= 0; idx-- {
+		if r.matches[idx].OppKey() != recent.OppKey() {
+			continue
+		}
+		if r.matches[idx].BotName == botName {
+			recent.PriorGames = r.matches[idx].PriorGames + 1
+		}
+		break
+	}
+	if len(r.matches) == r.capacity {
+		r.matches = r.matches[1:]
+	}
+	r.matches = append(r.matches, recent)
+	return recent
+}
+
+// Get returns the finished match, or nil if it has been forgotten
+func (r *RecentMatches) Get(matchId string) *RecentMatch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, recent := range r.matches {
+		if recent.Match.Uuid == matchId {
+			return recent
+		}
+	}
+	return nil
+}
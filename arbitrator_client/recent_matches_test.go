@@ -0,0 +1,48 @@
+package arbitrator_client_test
+
+import (
+	"github.com/CameronHonis/chess-arbitrator/models"
+	. "github.com/CameronHonis/chess-bot-server/arbitrator_client"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func NewFinishedMatch(uuid string, whiteKey models.Key, blackKey models.Key) *models.Match {
+	return &models.Match{
+		Uuid:           uuid,
+		WhiteClientKey: whiteKey,
+		BlackClientKey: blackKey,
+		TimeControl:    &models.TimeControl{InitialTimeSec: 180, IncrementSec: 2},
+		Result:         models.MATCH_RESULT_WHITE_WINS_BY_RESIGNATION,
+	}
+}
+
+var _ = Describe("RecentMatches", func() {
+	var recentMatches *RecentMatches
+	BeforeEach(func() {
+		recentMatches = NewRecentMatches(2)
+	})
+	It("replays a match against the same opponent with the colors swapped", func() {
+		recent := recentMatches.Add("random", NewFinishedMatch("first", "server", "player"), true)
+		seek := recent.Rematch()
+		Expect(seek.Origin).To(Equal(bot_manager.ORIGIN_CHALLENGER))
+		Expect(seek.BotName).To(Equal("random"))
+		Expect(seek.OppKey).To(Equal(models.Key("player")))
+		Expect(seek.IsBotWhite).To(BeFalse())
+		Expect(seek.IsBotBlack).To(BeTrue())
+		Expect(seek.TimeControl.InitialTimeSec).To(Equal(int64(180)))
+	})
+	It("counts the games in a row the bot played the opponent", func() {
+		recentMatches.Add("random", NewFinishedMatch("first", "server", "player"), true)
+		Expect(recentMatches.Add("random", NewFinishedMatch("second", "player", "server"), false).PriorGames).To(Equal(uint(1)))
+		Expect(recentMatches.Add("stockfish", NewFinishedMatch("third", "server", "player"), true).PriorGames).To(Equal(uint(0)))
+	})
+	It("forgets the oldest match beyond its capacity", func() {
+		recentMatches.Add("random", NewFinishedMatch("first", "server", "player"), true)
+		recentMatches.Add("random", NewFinishedMatch("second", "server", "other"), true)
+		recentMatches.Add("random", NewFinishedMatch("third", "server", "another"), true)
+		Expect(recentMatches.Get("first")).To(BeNil())
+		Expect(recentMatches.Get("third").OppKey()).To(Equal(models.Key("another")))
+	})
+})
@@ -0,0 +1,157 @@
+package arbitrator_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	"github.com/CameronHonis/chess-bot-server/matchmaking"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+	"time"
+)
+
+// RunSeeker seeks the games asked for by the schedule every interval until the context is done.
+// Nothing is sought unless the server is ready and not read-only.
+func (ac *ArbitratorClient) RunSeeker(ctx context.Context) {
+	config := ac.Config().(*ArbitratorClientConfig)
+	if len(config.Schedule().Entries) == 0 {
+		return
+	}
+	ticker := time.NewTicker(config.SeekInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ac.ConnState() == CONN_STATE_READY && !ac.IsReadOnly() {
+				ac.Seek()
+			}
+		}
+	}
+}
+
+// Seek starts a bot for each game the schedule is short of, then challenges its opponent or joins
+// matchmaking. The bot is removed if the request cannot be sent.
+func (ac *ArbitratorClient) Seek() {
+	schedule := ac.Config().(*ArbitratorClientConfig).Schedule()
+	for _, plannedSeek := range schedule.Plan(ac.SeekStatus()) {
+		seek := &bot_manager.Seek{
+			Origin:        bot_manager.ORIGIN_CHALLENGER,
+			ScheduleEntry: plannedSeek.Entry.Name,
+			BotName:       plannedSeek.Entry.BotName,
+			OppKey:        plannedSeek.OppKey,
+			TimeControl:   plannedSeek.Entry.TimeControl,
+			IsBotWhite:    plannedSeek.Entry.IsBotWhite(),
+			IsBotBlack:    plannedSeek.Entry.IsBotBlack(),
+		}
+		if plannedSeek.OppKey == "" {
+			seek.Origin = bot_manager.ORIGIN_MATCHMAKING
+			seek.IsBotWhite = false
+			seek.IsBotBlack = false
+		}
+
+		botClient, botInitErr := ac.BotMngr.InitSeekBot(seek)
+		if botInitErr != nil {
+			ac.LogService.Log(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not seek game for schedule entry %s: %s", seek.ScheduleEntry, botInitErr))
+			continue
+		}
+		var sendErr error
+		if seek.Origin == bot_manager.ORIGIN_MATCHMAKING {
+			sendErr = JoinMatchmaking(ac.SendMessage, seek.TimeControl)
+		} else {
+			sendErr = RequestChallenge(ac.SendMessage, seek.WireChallenge(ac.PublicKey()))
+		}
+		if sendErr != nil {
+			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not seek game for schedule entry %s: %s", seek.ScheduleEntry, sendErr))
+			_ = ac.BotMngr.RemoveBot(botClient.Key())
+		}
+	}
+}
+
+// SeekStatus summarizes the bots as the schedule sees them. Every opponent of a bot counts as
+// busy, whichever side issued the challenge, since the arbitrator allows only one challenge
+// between two players.
+func (ac *ArbitratorClient) SeekStatus() *matchmaking.Status {
+	status := matchmaking.NewStatus()
+	for _, botClient := range ac.BotMngr.Clients() {
+		if entryName := botClient.ScheduleEntry(); entryName != "" {
+			status.GamesByEntry[entryName]++
+		}
+		if oppKey := botClient.OppKey(); oppKey != "" {
+			status.BusyOpps[oppKey] = true
+		}
+		if match := botClient.LastMatch(); match != nil && botClient.MatchId() != "" {
+			for _, plrKey := range []mods.PlrClientKey{match.WhiteClientKey, match.BlackClientKey} {
+				if plrKey != "" && plrKey != ac.PublicKey() {
+					status.BusyOpps[plrKey] = true
+				}
+			}
+		}
+		if botClient.Origin() == bot_manager.ORIGIN_MATCHMAKING && botClient.MatchId() == "" {
+			status.IsQueued = true
+		}
+	}
+	return status
+}
+
+// RejoinMatchmaking puts the server back in the matchmaking queue for each bot still waiting on
+// it, since the queue does not survive a reconnect
+func (ac *ArbitratorClient) RejoinMatchmaking() {
+	for _, botClient := range ac.BotMngr.Clients() {
+		if botClient.Origin() != bot_manager.ORIGIN_MATCHMAKING || botClient.MatchId() != "" {
+			continue
+		}
+		if joinErr := JoinMatchmaking(ac.SendMessage, botClient.Challenge().TimeControl); joinErr != nil {
+			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not rejoin matchmaking: %s", joinErr))
+		}
+	}
+}
+
+// OnBotReaped stops the worker for a reaped bot's match, and revokes the server's challenges that
+// never became matches, so that the opponent can be challenged again
+func (ac *ArbitratorClient) OnBotReaped(reapedBot *bot_manager.ReapedBot) {
+	defer ac.closeIfDrained()
+	if reapedBot.MatchId != "" {
+		ac.stopMatchWorker(reapedBot.MatchId)
+	}
+	if reapedBot.Origin != bot_manager.ORIGIN_CHALLENGER || reapedBot.MatchId != "" {
+		return
+	}
+	if reapedBot.Reason != bot_manager.REAP_REASON_CHALLENGE_EXPIRED {
+		return
+	}
+	if revokeErr := RevokeChallengeRequest(ac.SendMessage, reapedBot.OppKey); revokeErr != nil {
+		ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not revoke challenge to %s: %s", reapedBot.OppKey, revokeErr))
+	}
+}
+
+// AbandonSeeks leaves matchmaking and revokes the server's challenges, removing the bots that
+// were waiting on them. Unless the server is authorized, nothing can be sent, so the seeks are
+// only dropped locally.
+func (ac *ArbitratorClient) AbandonSeeks() {
+	isAuthorized := ac.isAuthorized()
+	isQueued := false
+	for _, botClient := range ac.BotMngr.Clients() {
+		if botClient.MatchId() != "" {
+			continue
+		}
+		switch botClient.Origin() {
+		case bot_manager.ORIGIN_MATCHMAKING:
+			isQueued = true
+		case bot_manager.ORIGIN_CHALLENGER:
+			if isAuthorized {
+				if revokeErr := RevokeChallengeRequest(ac.SendMessage, botClient.OppKey()); revokeErr != nil {
+					ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not revoke challenge to %s: %s", botClient.OppKey(), revokeErr))
+				}
+			}
+		default:
+			continue
+		}
+		_ = ac.BotMngr.RemoveBot(botClient.Key())
+	}
+	if isQueued && isAuthorized {
+		if leaveErr := LeaveMatchmaking(ac.SendMessage); leaveErr != nil {
+			ac.LogService.LogRed(ENV_ARBITRATOR_CLIENT, fmt.Sprintf("could not leave matchmaking: %s", leaveErr))
+		}
+	}
+}
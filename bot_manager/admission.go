@@ -0,0 +1,64 @@
+package bot_manager
+
+import (
+	"fmt"
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/engines"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+	"github.com/CameronHonis/chess-bot-server/resource_scheduler"
+)
+
+type AdmissionError struct {
+	Reason mods.DeclineReason
+	Detail string
+}
+
+func (e *AdmissionError) Error() string {
+	return fmt.Sprintf("challenge not admitted (%s): %s", e.Reason, e.Detail)
+}
+
+// Admit checks the challenge against the configured limits, returning an AdmissionError with the
+// reason if any limit would be exceeded by starting its bot
+func (bm *BotManager) Admit(challenge *arb_mods.Challenge) error {
+	limits := bm.config().Limits()
+	engineName, _, parseErr := engines.ParseBotName(challenge.BotName)
+	if parseErr != nil {
+		return &AdmissionError{mods.DECLINE_REASON_BOT_UNAVAILABLE, parseErr.Error()}
+	}
+
+	bm.mu.Lock()
+	botCount := uint(len(bm.clientByKey))
+	var engineBotCount, challengerBotCount uint
+	for _, botClient := range bm.clientByKey {
+		if botClient.EngineName() == engineName {
+			engineBotCount++
+		}
+		if botClient.OppKey() == challenge.ChallengerKey {
+			challengerBotCount++
+		}
+	}
+	bm.mu.Unlock()
+
+	if limits.MaxBots > 0 && botCount >= limits.MaxBots {
+		return &AdmissionError{mods.DECLINE_REASON_AT_CAPACITY,
+			fmt.Sprintf("%d of %d bots running", botCount, limits.MaxBots)}
+	}
+	if maxEngineBots, ok := limits.MaxBotsByEngine[engineName]; ok && engineBotCount >= maxEngineBots {
+		return &AdmissionError{mods.DECLINE_REASON_ENGINE_AT_CAPACITY,
+			fmt.Sprintf("%d of %d %s bots running", engineBotCount, maxEngineBots, engineName)}
+	}
+	if limits.MaxBotsPerChallenger > 0 && challenge.ChallengerKey != "" && challengerBotCount >= limits.MaxBotsPerChallenger {
+		return &AdmissionError{mods.DECLINE_REASON_CHALLENGER_AT_CAPACITY,
+			fmt.Sprintf("challenger has %d of %d bots", challengerBotCount, limits.MaxBotsPerChallenger)}
+	}
+	if limits.MaxHostLoad > 0 {
+		load, loadErr := resource_scheduler.HostLoad()
+		if loadErr != nil {
+			bm.LogService.LogRed(ENV_BOT_MANAGER, "could not read host load: ", loadErr)
+		} else if load >= limits.MaxHostLoad {
+			return &AdmissionError{mods.DECLINE_REASON_HOST_OVERLOADED,
+				fmt.Sprintf("host load %.2f exceeds %.2f", load, limits.MaxHostLoad)}
+		}
+	}
+	return nil
+}
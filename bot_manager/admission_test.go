@@ -0,0 +1,65 @@
+package bot_manager_test
+
+import (
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	"github.com/CameronHonis/chess-bot-server/models"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func ExpectDeclined(err error, reason models.DeclineReason) {
+	admitErr, ok := err.(*bot_manager.AdmissionError)
+	Expect(ok).To(BeTrue(), "expected an AdmissionError, got %v", err)
+	Expect(admitErr.Reason).To(Equal(reason))
+}
+
+var _ = Describe("::Admit", func() {
+	When("the bot limit is reached", func() {
+		It("declines the challenge", func() {
+			botManager := NewBotManager(&bot_manager.AdmissionLimits{MaxBots: 1})
+			Expect(botManager.InitBot(NewChallenge("player-a", true, 60))).Error().ToNot(HaveOccurred())
+			_, initErr := botManager.InitBot(NewChallenge("player-b", true, 60))
+			ExpectDeclined(initErr, models.DECLINE_REASON_AT_CAPACITY)
+		})
+	})
+	When("the engine limit is reached", func() {
+		var botManager *bot_manager.BotManager
+		BeforeEach(func() {
+			botManager = NewBotManager(&bot_manager.AdmissionLimits{
+				MaxBotsByEngine: map[string]uint{"random": 1},
+			})
+			Expect(botManager.InitBot(NewChallenge("player-a", true, 60))).Error().ToNot(HaveOccurred())
+		})
+		It("declines challenges to that engine", func() {
+			_, initErr := botManager.InitBot(NewChallenge("player-b", true, 60))
+			ExpectDeclined(initErr, models.DECLINE_REASON_ENGINE_AT_CAPACITY)
+		})
+		It("admits challenges to other engines", func() {
+			challenge := NewChallenge("player-b", true, 60)
+			challenge.BotName = "alphabeta?depth=1"
+			Expect(botManager.Admit(challenge)).To(Succeed())
+		})
+	})
+	When("the challenger limit is reached", func() {
+		var botManager *bot_manager.BotManager
+		BeforeEach(func() {
+			botManager = NewBotManager(&bot_manager.AdmissionLimits{MaxBotsPerChallenger: 1})
+			Expect(botManager.InitBot(NewChallenge("player-a", true, 60))).Error().ToNot(HaveOccurred())
+		})
+		It("declines further challenges from that challenger", func() {
+			_, initErr := botManager.InitBot(NewChallenge("player-a", false, 60))
+			ExpectDeclined(initErr, models.DECLINE_REASON_CHALLENGER_AT_CAPACITY)
+		})
+		It("admits challenges from other challengers", func() {
+			Expect(botManager.Admit(NewChallenge("player-b", true, 60))).To(Succeed())
+		})
+	})
+	When("a bot is removed", func() {
+		It("frees its slot", func() {
+			botManager := NewBotManager(&bot_manager.AdmissionLimits{MaxBots: 1})
+			botClient, _ := botManager.InitBot(NewChallenge("player-a", true, 60))
+			Expect(botManager.RemoveBot(botClient.Key())).To(Succeed())
+			Expect(botManager.Admit(NewChallenge("player-b", true, 60))).To(Succeed())
+		})
+	})
+})
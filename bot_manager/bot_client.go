@@ -1,16 +1,36 @@
 package bot_manager
 
 import (
+	"context"
+	"github.com/CameronHonis/chess"
 	"github.com/CameronHonis/chess-arbitrator/auth"
 	"github.com/CameronHonis/chess-arbitrator/models"
 	"github.com/CameronHonis/chess-bot-server/engines"
+	"sync"
+	"time"
 )
 
 const ENV_BOT_CLIENT = "BOT_CLIENT"
 
 type BotClient struct {
-	key    models.Key
-	engine engines.Engine
+	key       models.Key
+	engine    engines.EngineV2
+	challenge *models.Challenge
+	origin    BotOrigin
+	// scheduleEntry names the schedule entry the bot was started for, if any
+	scheduleEntry string
+	matchId       string
+	initTime      time.Time
+	// closeTime is when the bot's challenge was reported inactive, or zero while it is active
+	closeTime time.Time
+	// resumeTime is when the match was resumed with the opponent to move, or zero if it was not
+	resumeTime time.Time
+
+	record       *MatchRecord
+	lastMatch    *models.Match
+	searchCtx    context.Context
+	cancelSearch context.CancelFunc
+	mu           sync.Mutex
 }
 
 func NewLocalBotClient(engineName string) (*BotClient, error) {
@@ -18,19 +38,227 @@ func NewLocalBotClient(engineName string) (*BotClient, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewBotClientFromEngine(engine), nil
+}
+
+func NewBotClientFromEngine(engine engines.EngineV2) *BotClient {
 	pubKey, _ := auth.GenerateKeyset()
-	botClient := &BotClient{
-		key:    pubKey,
-		engine: engine,
+	return &BotClient{
+		key:      pubKey,
+		engine:   engine,
+		initTime: time.Now(),
+		record:   NewMatchRecord(),
 	}
-
-	return botClient, nil
 }
 
 func (c *BotClient) Key() models.Key {
 	return c.key
 }
 
-func (c *BotClient) Engine() engines.Engine {
+func (c *BotClient) Engine() engines.EngineV2 {
 	return c.engine
 }
+
+// Challenge is the challenge the bot was created for
+func (c *BotClient) Challenge() *models.Challenge {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.challenge == nil {
+		return &models.Challenge{}
+	}
+	return c.challenge
+}
+
+// Record is the record of the bot's match
+func (c *BotClient) Record() *MatchRecord {
+	return c.record
+}
+
+// isMatchFor reports whether the match could have been created for the bot
+func (c *BotClient) isMatchFor(match *models.Match) bool {
+	if c.Origin() == ORIGIN_CHALLENGED {
+		return IsMatchFromChallenge(match, c.Challenge())
+	}
+	return IsMatchFromSeek(match, c.Challenge())
+}
+
+// LastMatch is the latest update of the bot's match, or nil if the match has not been seen yet
+func (c *BotClient) LastMatch() *models.Match {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastMatch
+}
+
+// EngineName is the name of the bot's engine, without its parameters
+func (c *BotClient) EngineName() string {
+	engineName, _, _ := engines.ParseBotName(c.Challenge().BotName)
+	return engineName
+}
+
+// OppKey is the key of the bot's opponent, which is empty for bots waiting on matchmaking
+func (c *BotClient) OppKey() models.Key {
+	return c.Challenge().ChallengerKey
+}
+
+func (c *BotClient) Origin() BotOrigin {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.origin
+}
+
+func (c *BotClient) ScheduleEntry() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.scheduleEntry
+}
+
+// MatchId is the uuid of the match created from the bot's challenge, or empty if the match has
+// not been seen yet
+func (c *BotClient) MatchId() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.matchId
+}
+
+func (c *BotClient) CloseTime() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeTime
+}
+
+// ResumeTime is when the match was last resumed with the opponent to move, or zero if it was not
+func (c *BotClient) ResumeTime() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resumeTime
+}
+
+// AwaitResumedMatch marks the match as resumed with the opponent to move. If no update follows
+// within the stale resume timeout, the position the bot resumed from is taken to be stale and
+// the bot is reaped.
+func (c *BotClient) AwaitResumedMatch(resumeTime time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resumeTime = resumeTime
+}
+
+func (c *BotClient) setChallenge(challenge *models.Challenge, origin BotOrigin, scheduleEntry string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.challenge = challenge
+	c.origin = origin
+	c.scheduleEntry = scheduleEntry
+}
+
+func (c *BotClient) setMatchId(matchId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.matchId = matchId
+}
+
+func (c *BotClient) setCloseTime(closeTime time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closeTime.IsZero() {
+		c.closeTime = closeTime
+	}
+}
+
+// UpdateMatch notifies the engine of what changed since the last update: a move by the
+// opponent, a takeback, new clock times or the end of the match. Any search in progress is
+// cancelled once the match has ended or moves were taken back. Stale and duplicate updates are
+// ignored, returning false.
+func (c *BotClient) UpdateMatch(match *models.Match, isBotWhite bool) bool {
+	if !c.record.Update(match) {
+		return false
+	}
+	c.mu.Lock()
+	lastMatch := c.lastMatch
+	c.lastMatch = match
+	c.mu.Unlock()
+
+	if match.Result != models.MATCH_RESULT_IN_PROGRESS {
+		c.CancelSearch()
+		c.engine.OnMatchResult(match)
+		return true
+	}
+
+	c.engine.OnClockUpdate(match)
+	if lastMatch == nil {
+		return true
+	}
+	if Ply(match) < Ply(lastMatch) {
+		c.CancelSearch()
+		c.engine.OnTakeback(match)
+		return true
+	}
+	isOppMove := match.Board.IsWhiteTurn == isBotWhite
+	if Ply(match) > Ply(lastMatch) && isOppMove && match.LastMove != nil {
+		c.engine.OnOpponentMove(match, match.LastMove)
+	}
+	return true
+}
+
+// GenerateMove asks the engine for a move, recording its evaluation and how long it took
+func (c *BotClient) GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error) {
+	startTime := time.Now()
+	move, moveErr := c.engine.GenerateMove(ctx, match)
+	if moveErr != nil {
+		return nil, moveErr
+	}
+	var eval *int
+	if cp, ok := engines.LastEval(c.engine); ok {
+		eval = &cp
+	}
+	c.record.RecordBotMove(Ply(match), move, eval, time.Since(startTime))
+	return move, nil
+}
+
+// StartSearch returns the context for the next search, cancelling any search in progress
+func (c *BotClient) StartSearch() context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancelSearch != nil {
+		c.cancelSearch()
+	}
+	c.searchCtx, c.cancelSearch = context.WithCancel(context.Background())
+	return c.searchCtx
+}
+
+// EndSearch releases the search's context once it has finished, unless a newer search replaced it
+func (c *BotClient) EndSearch(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.searchCtx != ctx {
+		return
+	}
+	c.cancelSearch()
+	c.searchCtx = nil
+	c.cancelSearch = nil
+}
+
+// IsSearching reports whether a search started by StartSearch has yet to end
+func (c *BotClient) IsSearching() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.searchCtx != nil
+}
+
+func (c *BotClient) CancelSearch() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancelSearch != nil {
+		c.cancelSearch()
+		c.searchCtx = nil
+		c.cancelSearch = nil
+	}
+}
+
+// Ply is the number of half moves played in the match
+func Ply(match *models.Match) uint {
+	ply := uint(match.Board.FullMoveCount-1) * 2
+	if !match.Board.IsWhiteTurn {
+		ply++
+	}
+	return ply
+}
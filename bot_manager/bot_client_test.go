@@ -0,0 +1,38 @@
+package bot_manager_test
+
+import (
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	"github.com/CameronHonis/chess-bot-server/engines"
+	"github.com/CameronHonis/chess-bot-server/engines/alphabeta"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TakebackEngine counts the takebacks it is told about
+type TakebackEngine struct {
+	engines.EngineV2
+	takebacks int
+}
+
+func (e *TakebackEngine) OnTakeback(match *models.Match) {
+	e.takebacks++
+}
+
+var _ = Describe("BotClient", func() {
+	Describe("::UpdateMatch", func() {
+		It("tells the engine about takebacks", func() {
+			engine := &TakebackEngine{EngineV2: alphabeta.NewEngine(1)}
+			botClient := bot_manager.NewBotClientFromEngine(engine)
+			match := NewMatchFromChallenge(NewChallenge("player", false, 60), "match")
+			afterE4 := PlayMove(match, "e2e4")
+			Expect(botClient.UpdateMatch(match, true)).To(BeTrue())
+			Expect(botClient.UpdateMatch(afterE4, true)).To(BeTrue())
+
+			takenBack := *match
+			takenBack.WhiteTimeRemainingSec--
+			Expect(botClient.UpdateMatch(&takenBack, true)).To(BeTrue())
+			Expect(engine.takebacks).To(Equal(1))
+		})
+	})
+})
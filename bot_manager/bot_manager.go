@@ -1,39 +1,56 @@
 package bot_manager
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/CameronHonis/chess"
 	"github.com/CameronHonis/chess-arbitrator/builders"
 	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/engines"
+	"github.com/CameronHonis/chess-bot-server/journal"
 	mods "github.com/CameronHonis/chess-bot-server/models"
+	"github.com/CameronHonis/chess-bot-server/resource_scheduler"
 	"github.com/CameronHonis/log"
 	. "github.com/CameronHonis/marker"
 	"github.com/CameronHonis/service"
+	"github.com/CameronHonis/set"
 	"sync"
+	"time"
 )
 
 const ENV_BOT_MANAGER = "BOT_MANAGER"
 
-// BotManager A key is generated and assigned to a new bot. This key is mapped to the player
-// key that is opposing the bot in the match. This allows a unique bot to be assigned
-// for the duration of a match.
+const ENGINE_INIT_TIMEOUT = 10 * time.Second
+
+// BotManager A key is generated and assigned to a new bot for each challenge. A bot is indexed by
+// its challenge until the match created from the challenge is first seen, after which it is
+// indexed by the match. Bots are also indexed by their opponent's key, but since one player may
+// play several bots at once, that index only narrows down the candidates.
 type BotManager struct {
 	service.Service
-	__dependencies__ Marker
-	LogService       log.LoggerServiceI
+	__dependencies__  Marker
+	LogService        log.LoggerServiceI
+	// ResourceScheduler is optional. Without it, engines keep their own thread and hash settings.
+	ResourceScheduler *resource_scheduler.ResourceScheduler
+	Journal           *journal.Journal
 
-	__state__         Marker
-	clientKeyByOppKey map[mods.PlrClientKey]mods.BotClientKey
-	oppKeyByClientKey map[mods.BotClientKey]mods.PlrClientKey
-	clientByKey       map[mods.BotClientKey]*BotClient
-	mu                sync.Mutex
+	__state__              Marker
+	clientByKey            map[mods.BotClientKey]*BotClient
+	clientKeyByChallengeId map[string]mods.BotClientKey
+	clientKeyByMatchId     map[string]mods.BotClientKey
+	clientKeysByOppKey     map[mods.PlrClientKey]*set.Set[mods.BotClientKey]
+	fallbackCount          uint
+	mu                     sync.Mutex
 }
 
 func NewBotManager(config *BotManagerConfig) *BotManager {
 	m := &BotManager{
-		clientKeyByOppKey: make(map[mods.PlrClientKey]mods.BotClientKey),
-		oppKeyByClientKey: make(map[mods.BotClientKey]mods.PlrClientKey),
-		clientByKey:       make(map[mods.BotClientKey]*BotClient),
-		mu:                sync.Mutex{},
+		clientByKey:            make(map[mods.BotClientKey]*BotClient),
+		clientKeyByChallengeId: make(map[string]mods.BotClientKey),
+		clientKeyByMatchId:     make(map[string]mods.BotClientKey),
+		clientKeysByOppKey:     make(map[mods.PlrClientKey]*set.Set[mods.BotClientKey]),
+		mu:                     sync.Mutex{},
 	}
 	m.Service = *service.NewService(m, config)
 	return m
@@ -48,92 +65,373 @@ func (bm *BotManager) Client(key mods.BotClientKey) (*BotClient, error) {
 	return nil, fmt.Errorf("no client found with key %s", key)
 }
 
-func (bm *BotManager) ClientByOppKey(oppKey mods.PlrClientKey) (*BotClient, error) {
-	clientKey, clientKeyErr := bm.ClientKeyByOppKey(oppKey)
-	if clientKeyErr != nil {
-		return nil, clientKeyErr
+// Clients returns every bot, in no particular order
+func (bm *BotManager) Clients() []*BotClient {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	clients := make([]*BotClient, 0, len(bm.clientByKey))
+	for _, botClient := range bm.clientByKey {
+		clients = append(clients, botClient)
 	}
+	return clients
+}
 
+func (bm *BotManager) ClientByChallengeId(challengeId string) (*BotClient, error) {
 	bm.mu.Lock()
 	defer bm.mu.Unlock()
-	if botClient, ok := bm.clientByKey[clientKey]; ok {
-		return botClient, nil
+	if clientKey, ok := bm.clientKeyByChallengeId[challengeId]; ok {
+		return bm.clientByKey[clientKey], nil
+	}
+	for _, botClient := range bm.clientByKey {
+		if botClient.Challenge().Uuid == challengeId {
+			return botClient, nil
+		}
+	}
+	return nil, fmt.Errorf("no client found for challenge %s", challengeId)
+}
+
+// ClientsByOppKey returns every bot playing or challenged by the player
+func (bm *BotManager) ClientsByOppKey(oppKey mods.PlrClientKey) []*BotClient {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	clients := make([]*BotClient, 0)
+	if clientKeys, ok := bm.clientKeysByOppKey[oppKey]; ok {
+		for _, clientKey := range clientKeys.Flatten() {
+			clients = append(clients, bm.clientByKey[clientKey])
+		}
+	}
+	return clients
+}
+
+// ClientByMatchId returns the bot bound to the match, without binding one
+func (bm *BotManager) ClientByMatchId(matchId string) (*BotClient, error) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	if clientKey, ok := bm.clientKeyByMatchId[matchId]; ok {
+		return bm.clientByKey[clientKey], nil
 	}
-	return nil, fmt.Errorf("no bot client found by oppKey %s", oppKey)
+	return nil, fmt.Errorf("no client found for match %s", matchId)
 }
 
+// ClientByMatch resolves the bot playing the match, binding the match to a bot the first time it
+// is seen
 func (bm *BotManager) ClientByMatch(match *arb_mods.Match) (*BotClient, error) {
-	var client *BotClient
-	if client, _ = bm.ClientByOppKey(match.WhiteClientKey); client != nil {
-		return client, nil
+	bm.mu.Lock()
+	if clientKey, ok := bm.clientKeyByMatchId[match.Uuid]; ok {
+		botClient := bm.clientByKey[clientKey]
+		bm.mu.Unlock()
+		return botClient, nil
 	}
-	if client, _ = bm.ClientByOppKey(match.BlackClientKey); client != nil {
-		return client, nil
+	boundClient := bm.bindMatch(match)
+	bm.mu.Unlock()
+	if boundClient == nil {
+		return nil, fmt.Errorf("no client could be resolved from match %s", match.Uuid)
 	}
-	return nil, fmt.Errorf("no client could be resolved from match")
+	bm.Journal.RecordMatchBound(boundClient.Challenge().Uuid, match.Uuid)
+	return boundClient, nil
 }
 
+// bindMatch binds the match to the oldest unbound bot whose challenge the match could have been
+// created from, returning nil if there is no such bot. Bots challenging or challenged by one of
+// the match's players are preferred over bots waiting on matchmaking, which would match any
+// opponent on the same time control. The lock must be held.
+func (bm *BotManager) bindMatch(match *arb_mods.Match) *BotClient {
+	boundClient := bm.oldestUnboundBot(match, match.WhiteClientKey, match.BlackClientKey)
+	if boundClient == nil {
+		// bots waiting on matchmaking have no opponent yet, so they are indexed under an empty key
+		boundClient = bm.oldestUnboundBot(match, "")
+	}
+	if boundClient != nil {
+		bm.setMatchId(boundClient, match.Uuid)
+	}
+	return boundClient
+}
+
+// oldestUnboundBot is the oldest bot indexed under one of the opponent keys that has no match yet
+// and could have been matched into this one. The lock must be held.
+func (bm *BotManager) oldestUnboundBot(match *arb_mods.Match, oppKeys ...mods.PlrClientKey) *BotClient {
+	var oldestClient *BotClient
+	for _, oppKey := range oppKeys {
+		clientKeys, ok := bm.clientKeysByOppKey[oppKey]
+		if !ok {
+			continue
+		}
+		for _, clientKey := range clientKeys.Flatten() {
+			botClient := bm.clientByKey[clientKey]
+			if botClient.MatchId() != "" || !botClient.isMatchFor(match) {
+				continue
+			}
+			if oldestClient == nil || botClient.initTime.Before(oldestClient.initTime) {
+				oldestClient = botClient
+			}
+		}
+	}
+	return oldestClient
+}
+
+// setMatchId indexes the bot by its match instead of its challenge. The lock must be held.
+func (bm *BotManager) setMatchId(botClient *BotClient, matchId string) {
+	botClient.setMatchId(matchId)
+	delete(bm.clientKeyByChallengeId, botClient.Challenge().Uuid)
+	bm.clientKeyByMatchId[matchId] = botClient.Key()
+}
+
+// IsMatchFromChallenge reports whether the match could have been created by accepting the
+// challenge
+func IsMatchFromChallenge(match *arb_mods.Match, challenge *arb_mods.Challenge) bool {
+	if match.BotName != challenge.BotName {
+		return false
+	}
+	isChallengerWhite := match.WhiteClientKey == challenge.ChallengerKey
+	isChallengerBlack := match.BlackClientKey == challenge.ChallengerKey
+	if !isChallengerWhite && !isChallengerBlack {
+		return false
+	}
+	if challenge.IsChallengerWhite && !isChallengerWhite || challenge.IsChallengerBlack && !isChallengerBlack {
+		return false
+	}
+	if match.TimeControl == nil || challenge.TimeControl == nil {
+		return match.TimeControl == challenge.TimeControl
+	}
+	return match.TimeControl.Equals(challenge.TimeControl)
+}
+
+// InitBot creates and initializes a bot for the challenge. A challenge that already has a bot
+// returns the existing bot, and a challenge beyond the admission limits returns an
+// AdmissionError.
 func (bm *BotManager) InitBot(challenge *arb_mods.Challenge) (*BotClient, error) {
-	botClient, botClientErr := NewLocalBotClient(challenge.BotName)
-	if botClientErr != nil {
-		return nil, fmt.Errorf("could not create bot: %s", botClientErr)
+	if botClient, _ := bm.ClientByChallengeId(challenge.Uuid); botClient != nil {
+		return botClient, nil
+	}
+	if admitErr := bm.Admit(challenge); admitErr != nil {
+		return nil, admitErr
 	}
 
 	match := builders.NewMatchBuilder().FromChallenge(challenge).Build()
-	initErr := botClient.Engine().Initialize(match)
-	if initErr != nil {
-		return nil, fmt.Errorf("could not init bot: %s", initErr)
+	botClient, startErr := bm.startBot(challenge, match, ORIGIN_CHALLENGED, "")
+	if startErr != nil {
+		return nil, startErr
+	}
+	bm.Journal.RecordBotStarted(challenge, string(ORIGIN_CHALLENGED), "")
+	return botClient, nil
+}
+
+// InitSeekBot creates and initializes a bot for a game the server looks for itself, subject to
+// the same admission limits as challenges
+func (bm *BotManager) InitSeekBot(seek *Seek) (*BotClient, error) {
+	challenge := seek.Challenge()
+	if admitErr := bm.Admit(challenge); admitErr != nil {
+		return nil, admitErr
 	}
 
-	botKey := botClient.Key()
-	bm.addBot(challenge.ChallengerKey, botKey, botClient)
+	match := builders.NewMatchBuilder().FromChallenge(challenge).Build()
+	botClient, startErr := bm.startBot(challenge, match, seek.Origin, seek.ScheduleEntry)
+	if startErr != nil {
+		return nil, startErr
+	}
+	bm.Journal.RecordBotStarted(challenge, string(seek.Origin), seek.ScheduleEntry)
 	return botClient, nil
 }
 
-func (bm *BotManager) RemoveBot(key mods.BotClientKey) error {
-	client, clientErr := bm.Client(key)
-	if clientErr != nil {
-		return clientErr
+// RestoreBots restarts the bots from a journal snapshot, bypassing the admission limits since
+// they were admitted before the restart. Each bot's match is restored to its latest update.
+func (bm *BotManager) RestoreBots(serverKey mods.PlrClientKey, bots []*journal.BotSnapshot) []*BotClient {
+	botClients := make([]*BotClient, 0, len(bots))
+	for _, bot := range bots {
+		match := bot.LastMatch
+		if match == nil {
+			match = builders.NewMatchBuilder().FromChallenge(bot.Challenge).Build()
+		}
+		origin := BotOrigin(bot.Origin)
+		if origin == "" {
+			origin = ORIGIN_CHALLENGED
+		}
+		botClient, startErr := bm.startBot(bot.Challenge, match, origin, bot.ScheduleEntry)
+		if startErr != nil {
+			bm.LogService.LogRed(ENV_BOT_MANAGER, fmt.Sprintf("could not restore bot for challenge %s: %s", bot.Challenge.Uuid, startErr))
+			bm.Journal.RecordBotRemoved(bot.Challenge.Uuid)
+			continue
+		}
+		if bot.MatchId != "" {
+			bm.mu.Lock()
+			bm.setMatchId(botClient, bot.MatchId)
+			bm.mu.Unlock()
+		}
+		if bot.LastMatch != nil {
+			botClient.UpdateMatch(bot.LastMatch, bot.LastMatch.WhiteClientKey == serverKey)
+		}
+		bm.LogService.Log(ENV_BOT_MANAGER, fmt.Sprintf("restored bot %s for challenge %s", bot.Challenge.BotName, bot.Challenge.Uuid))
+		botClients = append(botClients, botClient)
 	}
+	return botClients
+}
 
-	oppKey, oppKeyErr := bm.OppKeyByClientKey(key)
-	if oppKeyErr != nil {
-		return oppKeyErr
+// UpdateMatch passes the match update to the bot and journals it, returning false if the update
+// was stale or a duplicate
+func (bm *BotManager) UpdateMatch(botClient *BotClient, match *arb_mods.Match, isBotWhite bool) bool {
+	if !botClient.UpdateMatch(match, isBotWhite) {
+		return false
 	}
+	bm.Journal.RecordMatchUpdated(botClient.Challenge().Uuid, match)
+	return true
+}
 
-	bm.mu.Lock()
-	defer bm.mu.Unlock()
+// GenerateMove asks the bot for its move in the match, reporting the move once it is generated
+func (bm *BotManager) GenerateMove(ctx context.Context, botClient *BotClient, match *arb_mods.Match) (*chess.Move, error) {
+	move, moveErr := botClient.GenerateMove(ctx, match)
+	if moveErr != nil {
+		return nil, moveErr
+	}
+	if moveRecord := botClient.Record().PendingBotMove(Ply(match)); moveRecord != nil {
+		bm.Dispatch(NewMoveGeneratedEvent(botClient, moveRecord))
+	}
+	return move, nil
+}
+
+// startBot creates a bot for the challenge and initializes its engine from the match
+func (bm *BotManager) startBot(challenge *arb_mods.Challenge, match *arb_mods.Match, origin BotOrigin,
+	scheduleEntry string) (*BotClient, error) {
+	// the engine reports its fallbacks as the bot, which exists before the engine is initialized
+	var botClient *BotClient
+	engine, engineErr := bm.engineWithFallback(challenge.BotName, func(reason string, cause error) {
+		bm.onFallback(botClient, reason, cause)
+	})
+	if engineErr != nil {
+		return nil, fmt.Errorf("could not create bot: %s", engineErr)
+	}
+	botClient = NewBotClientFromEngine(engines.NewFastPathEngine(engine, bm.config().IsEasyMoves(), func(reason string) {
+		bm.LogService.Log(ENV_BOT_MANAGER, fmt.Sprintf("bot %s skipped search: %s", challenge.BotName, reason))
+	}))
+	botClient.setChallenge(challenge, origin, scheduleEntry)
+
+	initCtx, cancelInitCtx := context.WithTimeout(context.Background(), ENGINE_INIT_TIMEOUT)
+	defer cancelInitCtx()
+	initErr := botClient.Engine().Initialize(initCtx, match)
+	if initErr != nil {
+		return nil, fmt.Errorf("could not init bot: %s", initErr)
+	}
 
+	botKey := botClient.Key()
+	bm.addBot(botClient)
+	if bm.ResourceScheduler == nil {
+		return botClient, nil
+	}
+	if consumer, ok := engines.Unwrap(engine.Primary()).(resource_scheduler.ResourceConsumer); ok {
+		bm.ResourceScheduler.Register(botKey, consumer)
+	}
+	return botClient, nil
+}
+
+func (bm *BotManager) RemoveBot(key mods.BotClientKey) error {
+	bm.mu.Lock()
+	client, ok := bm.clientByKey[key]
+	if !ok {
+		bm.mu.Unlock()
+		return fmt.Errorf("no client found with key %s", key)
+	}
 	delete(bm.clientByKey, key)
-	delete(bm.oppKeyByClientKey, key)
-	delete(bm.clientKeyByOppKey, oppKey)
+	delete(bm.clientKeyByChallengeId, client.Challenge().Uuid)
+	delete(bm.clientKeyByMatchId, client.MatchId())
+	if clientKeys, ok := bm.clientKeysByOppKey[client.OppKey()]; ok {
+		clientKeys.Remove(key)
+		if clientKeys.Size() == 0 {
+			delete(bm.clientKeysByOppKey, client.OppKey())
+		}
+	}
+	bm.mu.Unlock()
 
+	bm.Journal.RecordBotRemoved(client.Challenge().Uuid)
+	if bm.ResourceScheduler != nil {
+		bm.ResourceScheduler.Unregister(key)
+	}
+	client.CancelSearch()
 	client.Engine().Terminate()
 	return nil
 }
 
-func (bm *BotManager) ClientKeyByOppKey(oppKey mods.PlrClientKey) (mods.BotClientKey, error) {
-	bm.mu.Lock()
-	defer bm.mu.Unlock()
-	if clientKey, ok := bm.clientKeyByOppKey[oppKey]; ok {
-		return clientKey, nil
+// RemoveAllBots removes every bot, for when the server's matches can no longer be played
+func (bm *BotManager) RemoveAllBots() {
+	for _, botClient := range bm.Clients() {
+		_ = bm.RemoveBot(botClient.Key())
+	}
+}
+
+// UpdateClock reports the time remaining for the bot's side of the match, so the resource
+// scheduler can favor engines under time pressure
+func (bm *BotManager) UpdateClock(key mods.BotClientKey, match *arb_mods.Match) {
+	if bm.ResourceScheduler == nil {
+		return
+	}
+	secsRemaining := match.BlackTimeRemainingSec
+	if match.Board.IsWhiteTurn {
+		secsRemaining = match.WhiteTimeRemainingSec
 	}
-	return "", fmt.Errorf("no client key exists for opp key %s", oppKey)
+	bm.ResourceScheduler.UpdateClock(key, secsRemaining)
 }
 
-func (bm *BotManager) OppKeyByClientKey(key mods.BotClientKey) (mods.PlrClientKey, error) {
+// FallbackCount is the number of times any bot's engine failed to produce a move
+func (bm *BotManager) FallbackCount() uint {
 	bm.mu.Lock()
 	defer bm.mu.Unlock()
-	if oppKey, ok := bm.oppKeyByClientKey[key]; ok {
-		return oppKey, nil
-	}
-	return "", fmt.Errorf("no opp key exists for client key %s", key)
+	return bm.fallbackCount
 }
 
-func (bm *BotManager) addBot(playerKey mods.PlrClientKey, botKey mods.BotClientKey, botClient *BotClient) {
+func (bm *BotManager) addBot(botClient *BotClient) {
 	bm.mu.Lock()
 	defer bm.mu.Unlock()
+	botKey := botClient.Key()
 	bm.clientByKey[botKey] = botClient
-	bm.clientKeyByOppKey[playerKey] = botKey
-	bm.oppKeyByClientKey[botKey] = playerKey
+	bm.clientKeyByChallengeId[botClient.Challenge().Uuid] = botKey
+	if _, ok := bm.clientKeysByOppKey[botClient.OppKey()]; !ok {
+		bm.clientKeysByOppKey[botClient.OppKey()] = set.EmptySet[mods.BotClientKey]()
+	}
+	bm.clientKeysByOppKey[botClient.OppKey()].Add(botKey)
+}
+
+// engineWithFallback builds the engine for the bot, backed by the configured fallback bot and
+// finally a random legal move. The fallback bot's engine is only built if the bot's engine fails.
+// Both engines have their moves checked for legality.
+func (bm *BotManager) engineWithFallback(botName string, onFallback func(reason string, cause error)) (*engines.FallbackEngine, error) {
+	primary, primaryErr := engines.EngineFromName(botName)
+	if primaryErr != nil {
+		return nil, primaryErr
+	}
+	primary = engines.NewLegalityGuard(primary, engines.DEFAULT_MOVE_RETRIES, bm.logBadMove)
+
+	var newFallback func() (engines.EngineV2, error)
+	if fallbackBotName := bm.config().FallbackBotName(); fallbackBotName != "" && fallbackBotName != botName {
+		newFallback = func() (engines.EngineV2, error) {
+			fallback, fallbackErr := engines.EngineFromName(fallbackBotName)
+			if fallbackErr != nil {
+				return nil, fmt.Errorf("could not create fallback bot %s: %s", fallbackBotName, fallbackErr)
+			}
+			return engines.NewLegalityGuard(fallback, engines.DEFAULT_MOVE_RETRIES, bm.logBadMove), nil
+		}
+	}
+
+	return engines.NewFallbackEngine(primary, newFallback, bm.config().PrimaryTimeFraction(), onFallback), nil
+}
+
+// onFallback counts and reports each time the bot's engine failed to produce a move, and reports
+// separately the failures that were crashes
+func (bm *BotManager) onFallback(botClient *BotClient, reason string, cause error) {
+	bm.mu.Lock()
+	bm.fallbackCount++
+	fallbackCount := bm.fallbackCount
+	bm.mu.Unlock()
+	botName := botClient.Challenge().BotName
+	bm.LogService.LogRed(ENV_BOT_MANAGER, fmt.Sprintf("bot %s fell back (%d total): %s", botName, fallbackCount, reason))
+	bm.Dispatch(NewEngineFallbackEvent(botClient, reason))
+	var crashErr *engines.CrashError
+	if errors.As(cause, &crashErr) {
+		bm.Dispatch(NewEngineCrashedEvent(botClient, cause))
+	}
+}
+
+func (bm *BotManager) logBadMove(diagnostic *engines.IllegalMoveError) {
+	bm.LogService.LogRed(ENV_BOT_MANAGER, diagnostic.Details())
+}
+
+func (bm *BotManager) config() *BotManagerConfig {
+	return bm.Config().(*BotManagerConfig)
 }
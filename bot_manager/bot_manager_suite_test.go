@@ -0,0 +1,13 @@
+package bot_manager_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBotManager(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "BotManager Suite")
+}
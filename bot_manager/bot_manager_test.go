@@ -0,0 +1,253 @@
+package bot_manager_test
+
+import (
+	"context"
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	"github.com/CameronHonis/chess-bot-server/journal"
+	"github.com/CameronHonis/chess-bot-server/resource_scheduler"
+	"github.com/CameronHonis/log"
+	"github.com/CameronHonis/service"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"path/filepath"
+)
+
+const BOT_SERVER_KEY = "bot-server"
+
+func NewBotManager(limits *bot_manager.AdmissionLimits) *bot_manager.BotManager {
+	return NewBotManagerWithJournal(limits, "")
+}
+
+func NewBotManagerWithJournal(limits *bot_manager.AdmissionLimits, journalPath string) *bot_manager.BotManager {
+	logService := log.NewLoggerService(log.NewConfigBuilder().WithIsMuted(true).Build())
+	scheduler := resource_scheduler.NewResourceScheduler(resource_scheduler.NewResourceSchedulerConfig(1, 16, false))
+	scheduler.AddDependency(logService)
+	botJournal := journal.NewJournal(journal.NewJournalConfig(journalPath, 0))
+	botJournal.AddDependency(logService)
+
+	botManager := bot_manager.NewBotManager(bot_manager.NewBotManagerConfig("", 0, false, limits, nil))
+	botManager.AddDependency(logService)
+	botManager.AddDependency(scheduler)
+	botManager.AddDependency(botJournal)
+	return botManager
+}
+
+func NewChallenge(challengerKey models.Key, isChallengerWhite bool, initialTimeSec int64) *models.Challenge {
+	return builders.NewChallengeBuilder().
+		WithRandomUuid().
+		WithChallengerKey(challengerKey).
+		WithChallengedKey(BOT_SERVER_KEY).
+		WithIsChallengerWhite(isChallengerWhite).
+		WithIsChallengerBlack(!isChallengerWhite).
+		WithTimeControl(&models.TimeControl{InitialTimeSec: initialTimeSec}).
+		WithBotName("random").
+		WithIsActive(true).
+		Build()
+}
+
+func NewMatchFromChallenge(challenge *models.Challenge, matchId string) *models.Match {
+	match := builders.NewMatchBuilder().FromChallenge(challenge).Build()
+	match.Uuid = matchId
+	if challenge.IsChallengerWhite {
+		match.WhiteClientKey, match.BlackClientKey = challenge.ChallengerKey, BOT_SERVER_KEY
+	} else {
+		match.WhiteClientKey, match.BlackClientKey = BOT_SERVER_KEY, challenge.ChallengerKey
+	}
+	return match
+}
+
+var _ = Describe("BotManager", func() {
+	var botManager *bot_manager.BotManager
+	BeforeEach(func() {
+		botManager = NewBotManager(nil)
+	})
+	Describe("::InitBot", func() {
+		It("indexes the bot by its challenge", func() {
+			challenge := NewChallenge("player", true, 60)
+			botClient, initErr := botManager.InitBot(challenge)
+			Expect(initErr).ToNot(HaveOccurred())
+			Expect(botManager.ClientByChallengeId(challenge.Uuid)).To(BeIdenticalTo(botClient))
+		})
+		When("the challenge already has a bot", func() {
+			It("returns the existing bot", func() {
+				challenge := NewChallenge("player", true, 60)
+				botClient, _ := botManager.InitBot(challenge)
+				Expect(botManager.InitBot(challenge)).To(BeIdenticalTo(botClient))
+				Expect(botManager.ClientsByOppKey("player")).To(HaveLen(1))
+			})
+		})
+		When("there is no resource scheduler", func() {
+			It("runs and removes bots whose engines take resources", func() {
+				logService := log.NewLoggerService(log.NewConfigBuilder().WithIsMuted(true).Build())
+				botJournal := journal.NewJournal(journal.NewJournalConfig("", 0))
+				botJournal.AddDependency(logService)
+				botManager = bot_manager.NewBotManager(bot_manager.NewBotManagerConfig("", 0, false, nil, nil))
+				botManager.AddDependency(logService)
+				botManager.AddDependency(botJournal)
+
+				challenge := NewChallenge("player", true, 60)
+				challenge.BotName = "alphabeta"
+				botClient, initErr := botManager.InitBot(challenge)
+				Expect(initErr).ToNot(HaveOccurred())
+				botManager.UpdateClock(botClient.Key(), NewMatchFromChallenge(challenge, "match"))
+				Expect(botManager.RemoveBot(botClient.Key())).To(Succeed())
+			})
+		})
+	})
+	Describe("::ClientByMatch", func() {
+		When("a player has two simultaneous challenges", func() {
+			var botA, botB *bot_manager.BotClient
+			var challengeA, challengeB *models.Challenge
+			BeforeEach(func() {
+				challengeA = NewChallenge("player", true, 60)
+				challengeB = NewChallenge("player", false, 180)
+				botA, _ = botManager.InitBot(challengeA)
+				botB, _ = botManager.InitBot(challengeB)
+			})
+			It("keeps both bots", func() {
+				Expect(botManager.ClientsByOppKey("player")).To(ConsistOf(botA, botB))
+			})
+			It("resolves each match to the bot of its challenge", func() {
+				Expect(botManager.ClientByMatch(NewMatchFromChallenge(challengeB, "match-b"))).To(BeIdenticalTo(botB))
+				Expect(botManager.ClientByMatch(NewMatchFromChallenge(challengeA, "match-a"))).To(BeIdenticalTo(botA))
+			})
+			It("keeps resolving a match to the same bot", func() {
+				match := NewMatchFromChallenge(challengeA, "match-a")
+				Expect(botManager.ClientByMatch(match)).To(BeIdenticalTo(botA))
+				Expect(botManager.ClientByMatch(match)).To(BeIdenticalTo(botA))
+				Expect(botA.MatchId()).To(Equal("match-a"))
+			})
+		})
+		When("no challenge matches the match", func() {
+			It("returns an error", func() {
+				_, _ = botManager.InitBot(NewChallenge("player", true, 60))
+				otherMatch := NewMatchFromChallenge(NewChallenge("other-player", true, 60), "match")
+				Expect(botManager.ClientByMatch(otherMatch)).Error().To(HaveOccurred())
+			})
+		})
+	})
+	Describe("::InitSeekBot", func() {
+		timeControl := &models.TimeControl{InitialTimeSec: 60}
+		seekMatch := func(whiteKey, blackKey models.Key, matchId string) *models.Match {
+			match := builders.NewMatchBuilder().WithTimeControl(timeControl).Build()
+			match.Uuid = matchId
+			match.WhiteClientKey, match.BlackClientKey = whiteKey, blackKey
+			return match
+		}
+		When("the server challenged a player", func() {
+			var botClient *bot_manager.BotClient
+			BeforeEach(func() {
+				botClient, _ = botManager.InitSeekBot(&bot_manager.Seek{
+					Origin:        bot_manager.ORIGIN_CHALLENGER,
+					ScheduleEntry: "sparring",
+					BotName:       "random",
+					OppKey:        "player",
+					TimeControl:   timeControl,
+					IsBotWhite:    true,
+				})
+			})
+			It("indexes the bot by the challenged player", func() {
+				Expect(botManager.ClientsByOppKey("player")).To(ConsistOf(botClient))
+				Expect(botClient.Origin()).To(Equal(bot_manager.ORIGIN_CHALLENGER))
+				Expect(botClient.ScheduleEntry()).To(Equal("sparring"))
+			})
+			It("binds the match against the player to the bot", func() {
+				Expect(botManager.ClientByMatch(seekMatch(BOT_SERVER_KEY, "player", "match"))).To(BeIdenticalTo(botClient))
+			})
+			It("does not bind a match with the wrong colors", func() {
+				Expect(botManager.ClientByMatch(seekMatch("player", BOT_SERVER_KEY, "match"))).Error().To(HaveOccurred())
+			})
+			It("removes the bot when the challenge fails", func() {
+				Expect(botManager.FailSeekChallenge("player")).To(HaveLen(1))
+				Expect(botManager.Clients()).To(BeEmpty())
+			})
+		})
+		When("the server joined matchmaking", func() {
+			It("binds the next match with the same time control to the bot", func() {
+				botClient, _ := botManager.InitSeekBot(&bot_manager.Seek{
+					Origin:      bot_manager.ORIGIN_MATCHMAKING,
+					BotName:     "random",
+					TimeControl: timeControl,
+				})
+				Expect(botManager.ClientByMatch(seekMatch("stranger", BOT_SERVER_KEY, "match"))).To(BeIdenticalTo(botClient))
+			})
+			It("prefers the bot that challenged the player over an older queued bot", func() {
+				queuedBot, _ := botManager.InitSeekBot(&bot_manager.Seek{
+					Origin:      bot_manager.ORIGIN_MATCHMAKING,
+					BotName:     "random",
+					TimeControl: timeControl,
+				})
+				challengerBot, _ := botManager.InitSeekBot(&bot_manager.Seek{
+					Origin:      bot_manager.ORIGIN_CHALLENGER,
+					BotName:     "random",
+					OppKey:      "player",
+					TimeControl: timeControl,
+					IsBotWhite:  true,
+				})
+				Expect(botManager.ClientByMatch(seekMatch(BOT_SERVER_KEY, "player", "match"))).To(BeIdenticalTo(challengerBot))
+				Expect(queuedBot.MatchId()).To(BeEmpty())
+			})
+		})
+	})
+	Describe("::RemoveBot", func() {
+		It("removes the bot from every index", func() {
+			challenge := NewChallenge("player", true, 60)
+			botClient, _ := botManager.InitBot(challenge)
+			match := NewMatchFromChallenge(challenge, "match")
+			_, _ = botManager.ClientByMatch(match)
+
+			Expect(botManager.RemoveBot(botClient.Key())).To(Succeed())
+			Expect(botManager.Client(botClient.Key())).Error().To(HaveOccurred())
+			Expect(botManager.ClientByChallengeId(challenge.Uuid)).Error().To(HaveOccurred())
+			Expect(botManager.ClientByMatch(match)).Error().To(HaveOccurred())
+			Expect(botManager.ClientsByOppKey("player")).To(BeEmpty())
+		})
+	})
+	Describe("::GenerateMove", func() {
+		It("reports the move once it is generated", func() {
+			challenge := NewChallenge("player", false, 60)
+			botClient, _ := botManager.InitBot(challenge)
+			match := NewMatchFromChallenge(challenge, "match")
+			_, _ = botManager.ClientByMatch(match)
+			var payload *bot_manager.MoveGeneratedPayload
+			botManager.AddEventListener(bot_manager.MOVE_GENERATED, func(self service.ServiceI, event service.EventI) bool {
+				payload = event.Payload().(*bot_manager.MoveGeneratedPayload)
+				return true
+			})
+
+			move, moveErr := botManager.GenerateMove(context.Background(), botClient, match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(payload).ToNot(BeNil())
+			Expect(payload.MatchId).To(Equal("match"))
+			Expect(payload.Ply).To(BeZero())
+			Expect(payload.Move).To(Equal(move.ToLongAlgebraic()))
+		})
+	})
+	Describe("::RestoreBots", func() {
+		It("restores bots and their matches from the journal", func() {
+			journalPath := filepath.Join(GinkgoT().TempDir(), "journal.jsonl")
+			botManager = NewBotManagerWithJournal(nil, journalPath)
+			_, loadErr := botManager.Journal.Load()
+			Expect(loadErr).ToNot(HaveOccurred())
+			challenge := NewChallenge("player", true, 60)
+			botClient, _ := botManager.InitBot(challenge)
+			match := NewMatchFromChallenge(challenge, "match")
+			Expect(botManager.ClientByMatch(match)).To(BeIdenticalTo(botClient))
+			Expect(botManager.UpdateMatch(botClient, match, false)).To(BeTrue())
+			Expect(botManager.Journal.Close()).To(Succeed())
+
+			restartedBotManager := NewBotManagerWithJournal(nil, journalPath)
+			snapshot, loadErr := restartedBotManager.Journal.Load()
+			Expect(loadErr).ToNot(HaveOccurred())
+			restoredClients := restartedBotManager.RestoreBots(BOT_SERVER_KEY, snapshot.Bots)
+			Expect(restoredClients).To(HaveLen(1))
+			restoredClient := restoredClients[0]
+			Expect(restoredClient.MatchId()).To(Equal("match"))
+			Expect(restoredClient.LastMatch().Uuid).To(Equal("match"))
+			Expect(restartedBotManager.ClientByMatch(match)).To(BeIdenticalTo(restoredClient))
+			Expect(restartedBotManager.UpdateMatch(restoredClient, match, false)).To(BeFalse())
+		})
+	})
+})
@@ -1,11 +1,118 @@
 package bot_manager
 
-import "github.com/CameronHonis/service"
+import (
+	"github.com/CameronHonis/service"
+	"time"
+)
+
+const (
+	DEFAULT_PENDING_CHALLENGE_TIMEOUT = 2 * time.Minute
+	DEFAULT_CLOSED_CHALLENGE_GRACE    = 10 * time.Second
+	DEFAULT_STALLED_MATCH_TIMEOUT     = 5 * time.Minute
+	DEFAULT_STALLED_UNTIMED_TIMEOUT   = 24 * time.Hour
+	DEFAULT_STALE_RESUME_TIMEOUT      = time.Minute
+	DEFAULT_REAP_INTERVAL             = 10 * time.Second
+)
 
 type BotManagerConfig struct {
 	service.ConfigI
+	fallbackBotName     string
+	primaryTimeFraction float64
+	isEasyMoves         bool
+	limits              *AdmissionLimits
+	timeouts            *ReaperTimeouts
+}
+
+// AdmissionLimits caps the bots that may run at once. A zero limit is no limit.
+type AdmissionLimits struct {
+	MaxBots              uint
+	MaxBotsByEngine      map[string]uint
+	MaxBotsPerChallenger uint
+	// MaxHostLoad is the highest load average per host thread at which challenges are accepted
+	MaxHostLoad float64
+}
+
+// ReaperTimeouts decide when the reaper removes a bot. A zero timeout takes its default.
+type ReaperTimeouts struct {
+	// PendingChallenge is how long a bot waits for its challenge to become a match
+	PendingChallenge time.Duration
+	// ClosedChallengeGrace is how long a bot waits for its match once its challenge is no longer
+	// active, since accepted challenges are reported as inactive just like revoked ones
+	ClosedChallengeGrace time.Duration
+	// StalledMatch is how long a match may go without an update beyond the time left on the
+	// clock of the side to move
+	StalledMatch time.Duration
+	// StalledUntimedMatch is how long a match without a time control may go without an update,
+	// since its players may take as long as they like to move
+	StalledUntimedMatch time.Duration
+	// StaleResume is how long a match resumed with the opponent to move may go without an
+	// update. The arbitrator has no way to request a match's state, so a move played while the
+	// server was disconnected is never seen, and the bot would otherwise wait out StalledMatch.
+	StaleResume time.Duration
+	Interval    time.Duration
+}
+
+func (t *ReaperTimeouts) withDefaults() *ReaperTimeouts {
+	timeouts := *t
+	if timeouts.PendingChallenge == 0 {
+		timeouts.PendingChallenge = DEFAULT_PENDING_CHALLENGE_TIMEOUT
+	}
+	if timeouts.ClosedChallengeGrace == 0 {
+		timeouts.ClosedChallengeGrace = DEFAULT_CLOSED_CHALLENGE_GRACE
+	}
+	if timeouts.StalledMatch == 0 {
+		timeouts.StalledMatch = DEFAULT_STALLED_MATCH_TIMEOUT
+	}
+	if timeouts.StalledUntimedMatch == 0 {
+		timeouts.StalledUntimedMatch = DEFAULT_STALLED_UNTIMED_TIMEOUT
+	}
+	if timeouts.StaleResume == 0 {
+		timeouts.StaleResume = DEFAULT_STALE_RESUME_TIMEOUT
+	}
+	if timeouts.Interval == 0 {
+		timeouts.Interval = DEFAULT_REAP_INTERVAL
+	}
+	return &timeouts
+}
+
+// NewBotManagerConfig takes the bot that is consulted when a bot's engine fails to move, and the
+// fraction of the remaining clock each engine is given before falling back. An empty
+// fallbackBotName falls back straight to a random legal move. isEasyMoves lets bots play
+// obvious recaptures from their last search without searching again. Challenges beyond the
+// limits are declined, and nil limits admit every challenge. Nil timeouts take the defaults.
+func NewBotManagerConfig(fallbackBotName string, primaryTimeFraction float64, isEasyMoves bool,
+	limits *AdmissionLimits, timeouts *ReaperTimeouts) *BotManagerConfig {
+	if limits == nil {
+		limits = &AdmissionLimits{}
+	}
+	if timeouts == nil {
+		timeouts = &ReaperTimeouts{}
+	}
+	return &BotManagerConfig{
+		fallbackBotName:     fallbackBotName,
+		primaryTimeFraction: primaryTimeFraction,
+		isEasyMoves:         isEasyMoves,
+		limits:              limits,
+		timeouts:            timeouts.withDefaults(),
+	}
+}
+
+func (c *BotManagerConfig) FallbackBotName() string {
+	return c.fallbackBotName
+}
+
+func (c *BotManagerConfig) PrimaryTimeFraction() float64 {
+	return c.primaryTimeFraction
+}
+
+func (c *BotManagerConfig) IsEasyMoves() bool {
+	return c.isEasyMoves
+}
+
+func (c *BotManagerConfig) Limits() *AdmissionLimits {
+	return c.limits
 }
 
-func NewBotManagerConfig() *BotManagerConfig {
-	return &BotManagerConfig{}
+func (c *BotManagerConfig) Timeouts() *ReaperTimeouts {
+	return c.timeouts
 }
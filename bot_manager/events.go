@@ -0,0 +1,79 @@
+package bot_manager
+
+import (
+	"github.com/CameronHonis/service"
+	"time"
+)
+
+const (
+	MOVE_GENERATED  service.EventVariant = "MOVE_GENERATED"
+	ENGINE_FALLBACK service.EventVariant = "ENGINE_FALLBACK"
+	ENGINE_CRASHED  service.EventVariant = "ENGINE_CRASHED"
+)
+
+type MoveGeneratedPayload struct {
+	BotName     string `json:"botName"`
+	ChallengeId string `json:"challengeId"`
+	MatchId     string `json:"matchId"`
+	Ply         uint   `json:"ply"`
+	Move        string `json:"move"`
+	// Eval is in centipawns from the bot's side, or nil if the engine does not report it
+	Eval      *int          `json:"eval,omitempty"`
+	ThinkTime time.Duration `json:"thinkTimeNs"`
+}
+
+type MoveGeneratedEvent struct{ service.Event }
+
+func NewMoveGeneratedEvent(botClient *BotClient, moveRecord *MoveRecord) *MoveGeneratedEvent {
+	return &MoveGeneratedEvent{
+		Event: *service.NewEvent(MOVE_GENERATED, &MoveGeneratedPayload{
+			BotName:     botClient.Challenge().BotName,
+			ChallengeId: botClient.Challenge().Uuid,
+			MatchId:     botClient.MatchId(),
+			Ply:         moveRecord.Ply,
+			Move:        moveRecord.Move,
+			Eval:        moveRecord.Eval,
+			ThinkTime:   moveRecord.ThinkTime,
+		}),
+	}
+}
+
+type EngineFallbackPayload struct {
+	BotName     string `json:"botName"`
+	ChallengeId string `json:"challengeId"`
+	MatchId     string `json:"matchId"`
+	Reason      string `json:"reason"`
+}
+
+type EngineFallbackEvent struct{ service.Event }
+
+func NewEngineFallbackEvent(botClient *BotClient, reason string) *EngineFallbackEvent {
+	return &EngineFallbackEvent{
+		Event: *service.NewEvent(ENGINE_FALLBACK, &EngineFallbackPayload{
+			BotName:     botClient.Challenge().BotName,
+			ChallengeId: botClient.Challenge().Uuid,
+			MatchId:     botClient.MatchId(),
+			Reason:      reason,
+		}),
+	}
+}
+
+type EngineCrashedPayload struct {
+	BotName     string `json:"botName"`
+	ChallengeId string `json:"challengeId"`
+	MatchId     string `json:"matchId"`
+	Err         string `json:"err"`
+}
+
+type EngineCrashedEvent struct{ service.Event }
+
+func NewEngineCrashedEvent(botClient *BotClient, err error) *EngineCrashedEvent {
+	return &EngineCrashedEvent{
+		Event: *service.NewEvent(ENGINE_CRASHED, &EngineCrashedPayload{
+			BotName:     botClient.Challenge().BotName,
+			ChallengeId: botClient.Challenge().Uuid,
+			MatchId:     botClient.MatchId(),
+			Err:         err.Error(),
+		}),
+	}
+}
@@ -0,0 +1,288 @@
+package bot_manager
+
+import (
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MAX_RECONSTRUCTED_PLIES bounds how many moves missed between two updates are searched for
+const MAX_RECONSTRUCTED_PLIES = 3
+
+type MoveRecord struct {
+	// Ply is the number of half moves played before the move
+	Ply uint
+	// Move is the move in long algebraic notation, or empty if no update reported it and it could
+	// not be worked out from the positions before and after it
+	Move      string
+	IsBotMove bool
+	// Eval is the bot's evaluation in centipawns, and is nil for the opponent's moves and for
+	// moves the bot's engine did not evaluate
+	Eval *int
+	// ThinkTime is how long the bot took to produce the move
+	ThinkTime time.Duration
+}
+
+// ClockRecord is the time remaining on each clock as of an update
+type ClockRecord struct {
+	Ply                   uint
+	WhiteTimeRemainingSec float64
+	BlackTimeRemainingSec float64
+	Time                  time.Time
+}
+
+// MatchRecord tracks a bot's match across updates. An update at the last processed ply with the
+// same position, clocks and result is a duplicate. An update behind the last processed ply is
+// stale if its clocks were already recorded at that ply, and is otherwise a takeback.
+type MatchRecord struct {
+	matchId      string
+	moves        []*MoveRecord
+	clocks       []*ClockRecord
+	lastPly      uint
+	lastBoard    *chess.Board
+	hasUpdate    bool
+	result       models.MatchResult
+	pendingMoves map[uint]*MoveRecord
+	mu           sync.Mutex
+}
+
+func NewMatchRecord() *MatchRecord {
+	return &MatchRecord{
+		moves:        make([]*MoveRecord, 0),
+		clocks:       make([]*ClockRecord, 0),
+		result:       models.MATCH_RESULT_IN_PROGRESS,
+		pendingMoves: make(map[uint]*MoveRecord),
+	}
+}
+
+// Update records the match, returning false without recording it if the update is stale or a
+// duplicate. A takeback removes the moves it took back.
+func (r *MatchRecord) Update(match *models.Match) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ply := Ply(match)
+	if r.hasUpdate {
+		if ply < r.lastPly && r.hasClock(ply, match) {
+			return false
+		}
+		if ply == r.lastPly && match.Board.ToFEN() == r.lastBoard.ToFEN() && match.Result == r.result &&
+			r.isLastClock(match) {
+			return false
+		}
+	}
+
+	if ply < r.lastPly {
+		r.takeBack(ply)
+	} else if ply > r.lastPly && match.LastMove != nil {
+		r.addMoves(match, ply)
+	}
+	for pendingPly := range r.pendingMoves {
+		if pendingPly < ply {
+			delete(r.pendingMoves, pendingPly)
+		}
+	}
+
+	r.matchId = match.Uuid
+	r.lastPly = ply
+	r.lastBoard = match.Board
+	r.hasUpdate = true
+	r.result = match.Result
+	r.clocks = append(r.clocks, &ClockRecord{
+		Ply:                   ply,
+		WhiteTimeRemainingSec: match.WhiteTimeRemainingSec,
+		BlackTimeRemainingSec: match.BlackTimeRemainingSec,
+		Time:                  time.Now(),
+	})
+	return true
+}
+
+// addMoves records the moves from the last processed ply up to the match's ply. Moves that no
+// update reported are worked out from the last position where possible. The lock must be held.
+func (r *MatchRecord) addMoves(match *models.Match, ply uint) {
+	var missedMoves []*chess.Move
+	if r.hasUpdate && ply-r.lastPly > 1 && ply-r.lastPly <= MAX_RECONSTRUCTED_PLIES {
+		missedMoves = missedMovesBetween(r.lastBoard, match.Board, match.LastMove, int(ply-r.lastPly-1))
+	}
+	firstPly := r.lastPly
+	for movePly := firstPly; movePly < ply; movePly++ {
+		var move string
+		if movePly == ply-1 {
+			move = match.LastMove.ToLongAlgebraic()
+		} else if missedMoves != nil {
+			move = missedMoves[movePly-firstPly].ToLongAlgebraic()
+		}
+		moveRecord, ok := r.pendingMoves[movePly]
+		if !ok || move == "" || moveRecord.Move != move {
+			moveRecord = &MoveRecord{
+				Ply:  movePly,
+				Move: move,
+			}
+		}
+		r.moves = append(r.moves, moveRecord)
+	}
+}
+
+// takeBack removes the moves played from the ply onwards. The lock must be held.
+func (r *MatchRecord) takeBack(ply uint) {
+	keptMoves := make([]*MoveRecord, 0, len(r.moves))
+	for _, moveRecord := range r.moves {
+		if moveRecord.Ply < ply {
+			keptMoves = append(keptMoves, moveRecord)
+		}
+	}
+	r.moves = keptMoves
+	for pendingPly := range r.pendingMoves {
+		if pendingPly >= ply {
+			delete(r.pendingMoves, pendingPly)
+		}
+	}
+}
+
+// hasClock reports whether the match's clocks were recorded at the ply. The lock must be held.
+func (r *MatchRecord) hasClock(ply uint, match *models.Match) bool {
+	for _, clock := range r.clocks {
+		if clock.Ply == ply && clock.WhiteTimeRemainingSec == match.WhiteTimeRemainingSec &&
+			clock.BlackTimeRemainingSec == match.BlackTimeRemainingSec {
+			return true
+		}
+	}
+	return false
+}
+
+// isLastClock reports whether the match's clocks are the last ones recorded. The lock must be held.
+func (r *MatchRecord) isLastClock(match *models.Match) bool {
+	if len(r.clocks) == 0 {
+		return false
+	}
+	lastClock := r.clocks[len(r.clocks)-1]
+	return lastClock.WhiteTimeRemainingSec == match.WhiteTimeRemainingSec &&
+		lastClock.BlackTimeRemainingSec == match.BlackTimeRemainingSec
+}
+
+// missedMovesBetween finds the only sequence of missedPlies moves that leads from the board to
+// the one before lastMove was played to reach toBoard. It returns nil if there is no such sequence
+// or more than one.
+func missedMovesBetween(fromBoard *chess.Board, toBoard *chess.Board, lastMove *chess.Move, missedPlies int) []*chess.Move {
+	toFEN := toBoard.ToFEN()
+	var found []*chess.Move
+	var foundCount int
+	var search func(board *chess.Board, moves []*chess.Move)
+	search = func(board *chess.Board, moves []*chess.Move) {
+		if foundCount > 1 {
+			return
+		}
+		if len(moves) == missedPlies {
+			if chess.IsLegalMove(board, lastMove) && chess.GetBoardFromMove(board, lastMove).ToFEN() == toFEN {
+				found = append([]*chess.Move{}, moves...)
+				foundCount++
+			}
+			return
+		}
+		legalMoves, movesErr := chess.GetLegalMoves(board)
+		if movesErr != nil {
+			return
+		}
+		for _, move := range legalMoves {
+			search(chess.GetBoardFromMove(board, move), append(moves, move))
+		}
+	}
+	search(fromBoard, make([]*chess.Move, 0, missedPlies))
+	if foundCount != 1 {
+		return nil
+	}
+	return found
+}
+
+// RecordBotMove stores the evaluation and timing of the bot's move, which is added to the move
+// list once an update confirms it was played
+func (r *MatchRecord) RecordBotMove(ply uint, move *chess.Move, eval *int, thinkTime time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pendingMoves[ply] = &MoveRecord{
+		Ply:       ply,
+		Move:      move.ToLongAlgebraic(),
+		IsBotMove: true,
+		Eval:      eval,
+		ThinkTime: thinkTime,
+	}
+}
+
+// HasBotMove reports whether the bot has generated its move for the ply, which may not have
+// reached the arbitrator yet
+func (r *MatchRecord) HasBotMove(ply uint) bool {
+	return r.PendingBotMove(ply) != nil
+}
+
+// PendingBotMove is the bot's move for the ply, from when it was generated until an update
+// confirms it was played, or nil if there is none
+func (r *MatchRecord) PendingBotMove(ply uint) *MoveRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pendingMoves[ply]
+}
+
+// BotEvals are the evaluations of the bot's moves in order, ending with the moves it generated
+// that no update has confirmed yet. An eval is nil if the engine did not report it.
+func (r *MatchRecord) BotEvals() []*int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	evals := make([]*int, 0)
+	for _, moveRecord := range r.moves {
+		if moveRecord.IsBotMove {
+			evals = append(evals, moveRecord.Eval)
+		}
+	}
+	pendingPlies := make([]uint, 0, len(r.pendingMoves))
+	for ply := range r.pendingMoves {
+		pendingPlies = append(pendingPlies, ply)
+	}
+	sort.Slice(pendingPlies, func(i, j int) bool { return pendingPlies[i] < pendingPlies[j] })
+	for _, ply := range pendingPlies {
+		evals = append(evals, r.pendingMoves[ply].Eval)
+	}
+	return evals
+}
+
+func (r *MatchRecord) MatchId() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.matchId
+}
+
+// Moves returns the moves played, in order, one for each ply
+func (r *MatchRecord) Moves() []*MoveRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*MoveRecord{}, r.moves...)
+}
+
+func (r *MatchRecord) Clocks() []*ClockRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*ClockRecord{}, r.clocks...)
+}
+
+// LastPly is the ply of the last processed update
+func (r *MatchRecord) LastPly() uint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastPly
+}
+
+// LastUpdateTime is when the last update was processed, or zero if there has been none
+func (r *MatchRecord) LastUpdateTime() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.clocks) == 0 {
+		return time.Time{}
+	}
+	return r.clocks[len(r.clocks)-1].Time
+}
+
+func (r *MatchRecord) Result() models.MatchResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.result
+}
@@ -0,0 +1,126 @@
+package bot_manager_test
+
+import (
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+func PlayMove(match *models.Match, moveLAlg string) *models.Match {
+	move, moveErr := chess.MoveFromLongAlgebraic(moveLAlg, match.Board)
+	Expect(moveErr).ToNot(HaveOccurred())
+	nextMatch := *match
+	nextMatch.Board = chess.GetBoardFromMove(match.Board, move)
+	nextMatch.LastMove = move
+	return &nextMatch
+}
+
+var _ = Describe("MatchRecord", func() {
+	var record *bot_manager.MatchRecord
+	var match *models.Match
+	BeforeEach(func() {
+		record = bot_manager.NewMatchRecord()
+		match = NewMatchFromChallenge(NewChallenge("player", false, 60), "match")
+		Expect(record.Update(match)).To(BeTrue())
+	})
+	It("records each move and clock", func() {
+		afterE4 := PlayMove(match, "e2e4")
+		Expect(record.Update(afterE4)).To(BeTrue())
+		Expect(record.Update(PlayMove(afterE4, "e7e5"))).To(BeTrue())
+		Expect(record.LastPly()).To(Equal(uint(2)))
+		Expect(record.Moves()).To(HaveLen(2))
+		Expect(record.Moves()[1].Move).To(Equal("e7e5"))
+		Expect(record.Clocks()).To(HaveLen(3))
+	})
+	It("ignores duplicate updates", func() {
+		afterE4 := PlayMove(match, "e2e4")
+		Expect(record.Update(afterE4)).To(BeTrue())
+		Expect(record.Update(afterE4)).To(BeFalse())
+		Expect(record.Moves()).To(HaveLen(1))
+	})
+	It("ignores stale updates", func() {
+		afterE4 := PlayMove(match, "e2e4")
+		Expect(record.Update(afterE4)).To(BeTrue())
+		Expect(record.Update(match)).To(BeFalse())
+		Expect(record.LastPly()).To(Equal(uint(1)))
+	})
+	It("records updates that only change the clocks", func() {
+		lowClock := *match
+		lowClock.WhiteTimeRemainingSec--
+		Expect(record.Update(&lowClock)).To(BeTrue())
+		Expect(record.Clocks()).To(HaveLen(2))
+		Expect(record.Moves()).To(BeEmpty())
+	})
+	It("treats an earlier position with new clocks as a takeback", func() {
+		afterE4 := PlayMove(match, "e2e4")
+		Expect(record.Update(afterE4)).To(BeTrue())
+		Expect(record.Update(PlayMove(afterE4, "e7e5"))).To(BeTrue())
+
+		takenBack := *afterE4
+		takenBack.WhiteTimeRemainingSec--
+		Expect(record.Update(&takenBack)).To(BeTrue())
+		Expect(record.LastPly()).To(Equal(uint(1)))
+		Expect(record.Moves()).To(HaveLen(1))
+		Expect(record.Moves()[0].Move).To(Equal("e2e4"))
+	})
+	It("works out a move that no update reported", func() {
+		afterE4 := PlayMove(match, "e2e4")
+		Expect(record.Update(afterE4)).To(BeTrue())
+		Expect(record.Update(PlayMove(PlayMove(afterE4, "e7e5"), "g1f3"))).To(BeTrue())
+		moves := record.Moves()
+		Expect(moves).To(HaveLen(3))
+		Expect(moves[1].Ply).To(Equal(uint(1)))
+		Expect(moves[1].Move).To(Equal("e7e5"))
+		Expect(moves[2].Move).To(Equal("g1f3"))
+	})
+	It("keeps a place for each move it cannot work out", func() {
+		afterNf3 := PlayMove(PlayMove(PlayMove(PlayMove(match, "e2e4"), "e7e5"), "g1f3"), "b8c6")
+		Expect(record.Update(PlayMove(afterNf3, "f1b5"))).To(BeTrue())
+		moves := record.Moves()
+		Expect(moves).To(HaveLen(5))
+		Expect(moves[0].Move).To(BeEmpty())
+		Expect(moves[4].Ply).To(Equal(uint(4)))
+		Expect(moves[4].Move).To(Equal("f1b5"))
+	})
+	It("accepts the match result at the last ply", func() {
+		endedMatch := *match
+		endedMatch.Result = models.MATCH_RESULT_BLACK_WINS_BY_RESIGNATION
+		Expect(record.Update(&endedMatch)).To(BeTrue())
+		Expect(record.Result()).To(Equal(models.MATCH_RESULT_BLACK_WINS_BY_RESIGNATION))
+	})
+	It("attaches the eval and timing of the bot's moves once they are played", func() {
+		move, _ := chess.MoveFromLongAlgebraic("e2e4", match.Board)
+		eval := 30
+		record.RecordBotMove(0, move, &eval, time.Second)
+		Expect(record.Moves()).To(BeEmpty())
+		Expect(record.HasBotMove(0)).To(BeTrue())
+
+		Expect(record.Update(PlayMove(match, "e2e4"))).To(BeTrue())
+		Expect(record.HasBotMove(0)).To(BeFalse())
+		moves := record.Moves()
+		Expect(moves).To(HaveLen(1))
+		Expect(moves[0].IsBotMove).To(BeTrue())
+		Expect(*moves[0].Eval).To(Equal(30))
+		Expect(moves[0].ThinkTime).To(Equal(time.Second))
+	})
+	It("lists the bot's evals, ending with the move awaiting confirmation", func() {
+		e4, _ := chess.MoveFromLongAlgebraic("e2e4", match.Board)
+		firstEval := 30
+		record.RecordBotMove(0, e4, &firstEval, time.Second)
+		afterE4 := PlayMove(match, "e2e4")
+		Expect(record.Update(afterE4)).To(BeTrue())
+		afterE5 := PlayMove(afterE4, "e7e5")
+		Expect(record.Update(afterE5)).To(BeTrue())
+
+		nf3, _ := chess.MoveFromLongAlgebraic("g1f3", afterE5.Board)
+		secondEval := -250
+		record.RecordBotMove(2, nf3, &secondEval, time.Second)
+		evals := record.BotEvals()
+		Expect(evals).To(HaveLen(2))
+		Expect(*evals[0]).To(Equal(30))
+		Expect(*evals[1]).To(Equal(-250))
+	})
+})
@@ -0,0 +1,173 @@
+package bot_manager
+
+import (
+	"context"
+	"fmt"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+	"time"
+)
+
+type ReapReason string
+
+const (
+	REAP_REASON_CHALLENGE_REVOKED ReapReason = "challenge revoked"
+	REAP_REASON_CHALLENGE_CLOSED  ReapReason = "challenge closed without a match"
+	REAP_REASON_CHALLENGE_EXPIRED ReapReason = "challenge never became a match"
+	REAP_REASON_CHALLENGE_FAILED  ReapReason = "challenge request failed"
+	REAP_REASON_MATCH_STALLED     ReapReason = "match stalled"
+	REAP_REASON_STALE_RESUME      ReapReason = "no update since resuming the match"
+)
+
+// ReapedBot reports a bot removed by the reaper
+type ReapedBot struct {
+	Key         mods.BotClientKey
+	BotName     string
+	ChallengeId string
+	MatchId     string
+	Origin      BotOrigin
+	OppKey      mods.PlrClientKey
+	Reason      ReapReason
+	Age         time.Duration
+}
+
+func (r *ReapedBot) String() string {
+	return fmt.Sprintf("reaped bot %s for challenge %s, match %q, after %s: %s",
+		r.BotName, r.ChallengeId, r.MatchId, r.Age.Round(time.Second), r.Reason)
+}
+
+// RunReaper reaps bots every interval until the context is done, passing each reaped bot to
+// onReaped if it is not nil
+func (bm *BotManager) RunReaper(ctx context.Context, onReaped func(reapedBot *ReapedBot)) {
+	ticker := time.NewTicker(bm.config().Timeouts().Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, reapedBot := range bm.Reap(now) {
+				if onReaped != nil {
+					onReaped(reapedBot)
+				}
+			}
+		}
+	}
+}
+
+// Reap removes every bot whose challenge never became a match in time, and every bot whose match
+// has stopped receiving updates, whether stalled or resumed from a stale position
+func (bm *BotManager) Reap(now time.Time) []*ReapedBot {
+	timeouts := bm.config().Timeouts()
+	reaped := make([]*ReapedBot, 0)
+	for _, botClient := range bm.Clients() {
+		if reason, ok := reapReason(botClient, now, timeouts); ok {
+			if reapedBot := bm.reapBot(botClient, reason, now); reapedBot != nil {
+				reaped = append(reaped, reapedBot)
+			}
+		}
+	}
+	return reaped
+}
+
+// CloseChallenge marks the challenge as no longer active. If its bot has no match by the end of
+// the grace period, the bot is reaped.
+func (bm *BotManager) CloseChallenge(challengeId string) {
+	if botClient, _ := bm.ClientByChallengeId(challengeId); botClient != nil && botClient.MatchId() == "" {
+		botClient.setCloseTime(time.Now())
+	}
+}
+
+// RevokeChallenges removes the bots for the player's challenges that have not become matches
+func (bm *BotManager) RevokeChallenges(challengerKey mods.PlrClientKey) []*ReapedBot {
+	return bm.reapUnbound(challengerKey, ORIGIN_CHALLENGED, REAP_REASON_CHALLENGE_REVOKED)
+}
+
+// CloseSeekChallenge marks the server's challenge to the player as no longer active. As with
+// challenges to the server, the bot is reaped if no match follows within the grace period.
+func (bm *BotManager) CloseSeekChallenge(challengedKey mods.PlrClientKey) {
+	for _, botClient := range bm.ClientsByOppKey(challengedKey) {
+		if botClient.Origin() == ORIGIN_CHALLENGER && botClient.MatchId() == "" {
+			botClient.setCloseTime(time.Now())
+		}
+	}
+}
+
+// FailSeekChallenge removes the bot for the server's challenge to the player, which the
+// arbitrator refused
+func (bm *BotManager) FailSeekChallenge(challengedKey mods.PlrClientKey) []*ReapedBot {
+	return bm.reapUnbound(challengedKey, ORIGIN_CHALLENGER, REAP_REASON_CHALLENGE_FAILED)
+}
+
+func (bm *BotManager) reapUnbound(oppKey mods.PlrClientKey, origin BotOrigin, reason ReapReason) []*ReapedBot {
+	now := time.Now()
+	reaped := make([]*ReapedBot, 0)
+	for _, botClient := range bm.ClientsByOppKey(oppKey) {
+		if botClient.MatchId() != "" || botClient.Origin() != origin {
+			continue
+		}
+		if reapedBot := bm.reapBot(botClient, reason, now); reapedBot != nil {
+			reaped = append(reaped, reapedBot)
+		}
+	}
+	return reaped
+}
+
+func (bm *BotManager) reapBot(botClient *BotClient, reason ReapReason, now time.Time) *ReapedBot {
+	if removeErr := bm.RemoveBot(botClient.Key()); removeErr != nil {
+		// the bot was removed since it was looked up
+		return nil
+	}
+	reapedBot := &ReapedBot{
+		Key:         botClient.Key(),
+		BotName:     botClient.Challenge().BotName,
+		ChallengeId: botClient.Challenge().Uuid,
+		MatchId:     botClient.MatchId(),
+		Origin:      botClient.Origin(),
+		OppKey:      botClient.OppKey(),
+		Reason:      reason,
+		Age:         now.Sub(botClient.initTime),
+	}
+	bm.LogService.Log(ENV_BOT_MANAGER, reapedBot.String())
+	return reapedBot
+}
+
+func reapReason(botClient *BotClient, now time.Time, timeouts *ReaperTimeouts) (ReapReason, bool) {
+	if botClient.MatchId() == "" {
+		if closeTime := botClient.CloseTime(); !closeTime.IsZero() && now.Sub(closeTime) > timeouts.ClosedChallengeGrace {
+			return REAP_REASON_CHALLENGE_CLOSED, true
+		}
+		// the arbitrator has no way to leave matchmaking for a single bot, so queued bots wait
+		if botClient.Origin() != ORIGIN_MATCHMAKING && now.Sub(botClient.initTime) > timeouts.PendingChallenge {
+			return REAP_REASON_CHALLENGE_EXPIRED, true
+		}
+		return "", false
+	}
+
+	lastUpdateTime := botClient.Record().LastUpdateTime()
+	if resumeTime := botClient.ResumeTime(); !resumeTime.IsZero() && lastUpdateTime.Before(resumeTime) &&
+		now.Sub(resumeTime) > timeouts.StaleResume {
+		return REAP_REASON_STALE_RESUME, true
+	}
+	match := botClient.LastMatch()
+	if lastUpdateTime.IsZero() || match == nil {
+		lastUpdateTime = botClient.initTime
+	}
+	var deadline time.Time
+	if match != nil && match.TimeControl == nil {
+		// no clock bounds how long the side to move may think
+		deadline = lastUpdateTime.Add(timeouts.StalledUntimedMatch)
+	} else {
+		var secsRemaining float64
+		if match != nil {
+			secsRemaining = match.BlackTimeRemainingSec
+			if match.Board.IsWhiteTurn {
+				secsRemaining = match.WhiteTimeRemainingSec
+			}
+		}
+		deadline = lastUpdateTime.Add(time.Duration(secsRemaining*float64(time.Second)) + timeouts.StalledMatch)
+	}
+	if now.After(deadline) {
+		return REAP_REASON_MATCH_STALLED, true
+	}
+	return "", false
+}
@@ -0,0 +1,117 @@
+package bot_manager_test
+
+import (
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+var _ = Describe("Reaper", func() {
+	var botManager *bot_manager.BotManager
+	BeforeEach(func() {
+		botManager = NewBotManager(nil)
+	})
+	When("a challenge never becomes a match", func() {
+		It("reaps the bot after the pending timeout", func() {
+			botClient, _ := botManager.InitBot(NewChallenge("player", true, 60))
+			Expect(botManager.Reap(time.Now())).To(BeEmpty())
+
+			reaped := botManager.Reap(time.Now().Add(bot_manager.DEFAULT_PENDING_CHALLENGE_TIMEOUT + time.Second))
+			Expect(reaped).To(HaveLen(1))
+			Expect(reaped[0].Key).To(Equal(botClient.Key()))
+			Expect(reaped[0].Reason).To(Equal(bot_manager.REAP_REASON_CHALLENGE_EXPIRED))
+			Expect(botManager.Clients()).To(BeEmpty())
+		})
+	})
+	When("a challenge is closed", func() {
+		var challengeId string
+		BeforeEach(func() {
+			challenge := NewChallenge("player", true, 60)
+			challengeId = challenge.Uuid
+			_, _ = botManager.InitBot(challenge)
+			botManager.CloseChallenge(challengeId)
+		})
+		It("waits out the grace period for the match", func() {
+			Expect(botManager.Reap(time.Now())).To(BeEmpty())
+		})
+		It("reaps the bot if no match follows", func() {
+			reaped := botManager.Reap(time.Now().Add(bot_manager.DEFAULT_CLOSED_CHALLENGE_GRACE + time.Second))
+			Expect(reaped).To(HaveLen(1))
+			Expect(reaped[0].ChallengeId).To(Equal(challengeId))
+			Expect(reaped[0].Reason).To(Equal(bot_manager.REAP_REASON_CHALLENGE_CLOSED))
+		})
+	})
+	When("a challenge is revoked", func() {
+		It("removes only the challenger's unmatched bots", func() {
+			matchedChallenge := NewChallenge("player", true, 60)
+			matchedBot, _ := botManager.InitBot(matchedChallenge)
+			_, _ = botManager.ClientByMatch(NewMatchFromChallenge(matchedChallenge, "match"))
+			_, _ = botManager.InitBot(NewChallenge("player", true, 180))
+			otherBot, _ := botManager.InitBot(NewChallenge("other", true, 60))
+
+			reaped := botManager.RevokeChallenges("player")
+			Expect(reaped).To(HaveLen(1))
+			Expect(reaped[0].Reason).To(Equal(bot_manager.REAP_REASON_CHALLENGE_REVOKED))
+			Expect(botManager.Clients()).To(ConsistOf(matchedBot, otherBot))
+		})
+	})
+	When("a match stops receiving updates", func() {
+		It("reaps the bot once the clock and stall timeout have passed", func() {
+			challenge := NewChallenge("player", true, 60)
+			botClient, _ := botManager.InitBot(challenge)
+			match := NewMatchFromChallenge(challenge, "match")
+			_, _ = botManager.ClientByMatch(match)
+			botManager.UpdateMatch(botClient, match, false)
+
+			Expect(botManager.Reap(time.Now().Add(bot_manager.DEFAULT_STALLED_MATCH_TIMEOUT))).To(BeEmpty())
+			reaped := botManager.Reap(time.Now().Add(bot_manager.DEFAULT_STALLED_MATCH_TIMEOUT + 61*time.Second))
+			Expect(reaped).To(HaveLen(1))
+			Expect(reaped[0].MatchId).To(Equal("match"))
+			Expect(reaped[0].Reason).To(Equal(bot_manager.REAP_REASON_MATCH_STALLED))
+		})
+		When("the match was resumed with the opponent to move", func() {
+			It("reaps the bot once the stale resume timeout passes without an update", func() {
+				challenge := NewChallenge("player", true, 60)
+				botClient, _ := botManager.InitBot(challenge)
+				match := NewMatchFromChallenge(challenge, "match")
+				_, _ = botManager.ClientByMatch(match)
+				botManager.UpdateMatch(botClient, match, false)
+				botClient.AwaitResumedMatch(time.Now())
+
+				Expect(botManager.Reap(time.Now())).To(BeEmpty())
+				reaped := botManager.Reap(time.Now().Add(bot_manager.DEFAULT_STALE_RESUME_TIMEOUT + time.Second))
+				Expect(reaped).To(HaveLen(1))
+				Expect(reaped[0].Reason).To(Equal(bot_manager.REAP_REASON_STALE_RESUME))
+			})
+			It("keeps the bot once an update follows", func() {
+				challenge := NewChallenge("player", true, 60)
+				botClient, _ := botManager.InitBot(challenge)
+				match := NewMatchFromChallenge(challenge, "match")
+				_, _ = botManager.ClientByMatch(match)
+				botManager.UpdateMatch(botClient, match, false)
+				botClient.AwaitResumedMatch(time.Now().Add(-time.Second))
+				clockUpdate := *match
+				clockUpdate.WhiteTimeRemainingSec--
+				botManager.UpdateMatch(botClient, &clockUpdate, false)
+
+				Expect(botManager.Reap(time.Now().Add(bot_manager.DEFAULT_STALE_RESUME_TIMEOUT + time.Second))).To(BeEmpty())
+			})
+		})
+		When("the match has no time control", func() {
+			It("waits out the untimed stall timeout instead", func() {
+				challenge := NewChallenge("player", true, 60)
+				botClient, _ := botManager.InitBot(challenge)
+				match := NewMatchFromChallenge(challenge, "match")
+				_, _ = botManager.ClientByMatch(match)
+				match.TimeControl = nil
+				botManager.UpdateMatch(botClient, match, false)
+
+				Expect(botManager.Reap(time.Now().Add(bot_manager.DEFAULT_STALLED_MATCH_TIMEOUT + time.Minute))).To(BeEmpty())
+				reaped := botManager.Reap(time.Now().Add(bot_manager.DEFAULT_STALLED_UNTIMED_TIMEOUT + time.Minute))
+				Expect(reaped).To(HaveLen(1))
+				Expect(reaped[0].Reason).To(Equal(bot_manager.REAP_REASON_MATCH_STALLED))
+			})
+		})
+	})
+})
@@ -0,0 +1,81 @@
+package bot_manager
+
+import (
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+)
+
+// BotOrigin is how the game a bot was created for came about
+type BotOrigin string
+
+const (
+	// ORIGIN_CHALLENGED bots were challenged by a player
+	ORIGIN_CHALLENGED BotOrigin = "challenged"
+	// ORIGIN_CHALLENGER bots challenged a player
+	ORIGIN_CHALLENGER BotOrigin = "challenger"
+	// ORIGIN_MATCHMAKING bots joined the arbitrator's matchmaking queue
+	ORIGIN_MATCHMAKING BotOrigin = "matchmaking"
+)
+
+// Seek is a game the server looks for itself, by challenging a player or by joining matchmaking.
+// The arbitrator creates these matches without a bot name, so the seek's bot is chosen locally.
+type Seek struct {
+	Origin BotOrigin
+	// ScheduleEntry names the schedule entry the seek was made for, if any
+	ScheduleEntry string
+	BotName       string
+	// OppKey is the challenged player, and is empty for matchmaking
+	OppKey      mods.PlrClientKey
+	TimeControl *arb_mods.TimeControl
+	IsBotWhite  bool
+	IsBotBlack  bool
+}
+
+// Challenge is the seek seen from the bot's side, as if the opponent had challenged the bot. This
+// is the challenge the bot is indexed and journaled by.
+func (s *Seek) Challenge() *arb_mods.Challenge {
+	return builders.NewChallengeBuilder().
+		WithRandomUuid().
+		WithChallengerKey(s.OppKey).
+		WithIsChallengerWhite(s.IsBotBlack).
+		WithIsChallengerBlack(s.IsBotWhite).
+		WithTimeControl(s.TimeControl).
+		WithBotName(s.BotName).
+		WithIsActive(true).
+		Build()
+}
+
+// WireChallenge is the challenge sent to the arbitrator to challenge the seek's opponent
+func (s *Seek) WireChallenge(serverKey mods.PlrClientKey) *arb_mods.Challenge {
+	return builders.NewChallengeBuilder().
+		WithChallengerKey(serverKey).
+		WithChallengedKey(s.OppKey).
+		WithIsChallengerWhite(s.IsBotWhite).
+		WithIsChallengerBlack(s.IsBotBlack).
+		WithTimeControl(s.TimeControl).
+		WithIsActive(true).
+		Build()
+}
+
+// IsMatchFromSeek reports whether the match could have been created for a seek, given the
+// seek's bot-side challenge
+func IsMatchFromSeek(match *arb_mods.Match, challenge *arb_mods.Challenge) bool {
+	if match.BotName != "" {
+		return false
+	}
+	if challenge.ChallengerKey != "" {
+		isOppWhite := match.WhiteClientKey == challenge.ChallengerKey
+		isOppBlack := match.BlackClientKey == challenge.ChallengerKey
+		if !isOppWhite && !isOppBlack {
+			return false
+		}
+		if challenge.IsChallengerWhite && !isOppWhite || challenge.IsChallengerBlack && !isOppBlack {
+			return false
+		}
+	}
+	if match.TimeControl == nil || challenge.TimeControl == nil {
+		return match.TimeControl == challenge.TimeControl
+	}
+	return match.TimeControl.Equals(challenge.TimeControl)
+}
@@ -0,0 +1,34 @@
+package challenge_policy
+
+import (
+	"fmt"
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/log"
+	. "github.com/CameronHonis/marker"
+	"github.com/CameronHonis/service"
+)
+
+const ENV_CHALLENGE_POLICY = "CHALLENGE_POLICY"
+
+// ChallengePolicy decides which challenges the bots accept, logging every decision along with
+// the rule that made it
+type ChallengePolicy struct {
+	service.Service
+	__dependencies__ Marker
+	LogService       log.LoggerServiceI
+
+	__state__ Marker
+}
+
+func NewChallengePolicy(config *ChallengePolicyConfig) *ChallengePolicy {
+	s := &ChallengePolicy{}
+	s.Service = *service.NewService(s, config)
+	return s
+}
+
+func (cp *ChallengePolicy) Evaluate(challenge *arb_mods.Challenge) *Decision {
+	decision := cp.Config().(*ChallengePolicyConfig).Policy().Evaluate(AttributesFromChallenge(challenge))
+	cp.LogService.Log(ENV_CHALLENGE_POLICY, fmt.Sprintf("challenge %s from %s for %s: %s",
+		challenge.Uuid, challenge.ChallengerKey, challenge.BotName, decision))
+	return decision
+}
@@ -0,0 +1,21 @@
+package challenge_policy
+
+import "github.com/CameronHonis/service"
+
+type ChallengePolicyConfig struct {
+	service.ConfigI
+	policy *Policy
+}
+
+func NewChallengePolicyConfig(policy *Policy) *ChallengePolicyConfig {
+	if policy == nil {
+		policy = AcceptAllPolicy()
+	}
+	return &ChallengePolicyConfig{
+		policy: policy,
+	}
+}
+
+func (c *ChallengePolicyConfig) Policy() *Policy {
+	return c.policy
+}
@@ -0,0 +1,13 @@
+package challenge_policy_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestChallengePolicy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ChallengePolicy Suite")
+}
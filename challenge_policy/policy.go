@@ -0,0 +1,255 @@
+package challenge_policy
+
+import (
+	"encoding/json"
+	"fmt"
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/engines"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+	"os"
+	"strings"
+)
+
+const DEFAULT_VARIANT = "standard"
+
+type Action string
+
+const (
+	ACTION_ACCEPT  Action = "accept"
+	ACTION_DECLINE Action = "decline"
+)
+
+// TimeControlRange bounds the initial time and increment of a challenge. A zero maximum is
+// unbounded.
+type TimeControlRange struct {
+	MinInitialTimeSec int64 `json:"minInitialTimeSec"`
+	MaxInitialTimeSec int64 `json:"maxInitialTimeSec"`
+	MinIncrementSec   int64 `json:"minIncrementSec"`
+	MaxIncrementSec   int64 `json:"maxIncrementSec"`
+}
+
+func (r *TimeControlRange) Contains(timeControl *arb_mods.TimeControl) bool {
+	if timeControl == nil {
+		return false
+	}
+	if timeControl.InitialTimeSec < r.MinInitialTimeSec || r.MaxInitialTimeSec > 0 && timeControl.InitialTimeSec > r.MaxInitialTimeSec {
+		return false
+	}
+	if timeControl.IncrementSec < r.MinIncrementSec || r.MaxIncrementSec > 0 && timeControl.IncrementSec > r.MaxIncrementSec {
+		return false
+	}
+	return true
+}
+
+// Rule matches a challenge when every condition it sets holds. Unset conditions match any
+// challenge, so a rule with only PlayerKeys set acts as an allow or deny list entry. The rated,
+// rating and variant conditions are refused when a policy is loaded, since the arbitrator does not
+// report them yet (see AttributesFromChallenge).
+type Rule struct {
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	// Bots are the bot names or engine names the rule applies to
+	Bots         []string            `json:"bots"`
+	TimeControls []*TimeControlRange `json:"timeControls"`
+	IsUntimed    *bool               `json:"isUntimed"`
+	IsRated      *bool               `json:"isRated"`
+	MinRating    *int                `json:"minRating"`
+	MaxRating    *int                `json:"maxRating"`
+	Variants     []string            `json:"variants"`
+	PlayerKeys   []mods.PlrClientKey `json:"playerKeys"`
+}
+
+// Attributes are the properties of a challenge that rules are evaluated against
+type Attributes struct {
+	BotName       string
+	EngineName    string
+	ChallengerKey mods.PlrClientKey
+	TimeControl   *arb_mods.TimeControl
+	IsRated       bool
+	// Rating is nil when the challenger's rating is unknown
+	Rating  *int
+	Variant string
+}
+
+// AttributesFromChallenge reads the attributes of the challenge. The arbitrator does not report
+// whether a challenge is rated, the challenger's rating or the variant, so every challenge is
+// treated as a casual, standard game against an unrated challenger until it does.
+func AttributesFromChallenge(challenge *arb_mods.Challenge) *Attributes {
+	engineName, _, _ := engines.ParseBotName(challenge.BotName)
+	return &Attributes{
+		BotName:       challenge.BotName,
+		EngineName:    engineName,
+		ChallengerKey: challenge.ChallengerKey,
+		TimeControl:   challenge.TimeControl,
+		IsRated:       false,
+		Rating:        nil,
+		Variant:       DEFAULT_VARIANT,
+	}
+}
+
+// Matches reports whether the rule applies to the challenge. Rating bounds never match a
+// challenger whose rating is unknown.
+func (r *Rule) Matches(attrs *Attributes) bool {
+	if len(r.Bots) > 0 && !containsStr(r.Bots, attrs.BotName) && !containsStr(r.Bots, attrs.EngineName) {
+		return false
+	}
+	if r.IsUntimed != nil && *r.IsUntimed != (attrs.TimeControl == nil) {
+		return false
+	}
+	if len(r.TimeControls) > 0 {
+		isInRange := false
+		for _, timeControlRange := range r.TimeControls {
+			if timeControlRange.Contains(attrs.TimeControl) {
+				isInRange = true
+				break
+			}
+		}
+		if !isInRange {
+			return false
+		}
+	}
+	if r.IsRated != nil && *r.IsRated != attrs.IsRated {
+		return false
+	}
+	if r.MinRating != nil && (attrs.Rating == nil || *attrs.Rating < *r.MinRating) {
+		return false
+	}
+	if r.MaxRating != nil && (attrs.Rating == nil || *attrs.Rating > *r.MaxRating) {
+		return false
+	}
+	if len(r.Variants) > 0 && !containsStr(r.Variants, attrs.Variant) {
+		return false
+	}
+	if len(r.PlayerKeys) > 0 {
+		isListed := false
+		for _, playerKey := range r.PlayerKeys {
+			if playerKey == attrs.ChallengerKey {
+				isListed = true
+				break
+			}
+		}
+		if !isListed {
+			return false
+		}
+	}
+	return true
+}
+
+// Policy evaluates its rules in order, and the first rule matching a challenge decides it. A
+// challenge matching no rule gets the default action.
+type Policy struct {
+	DefaultAction Action  `json:"defaultAction"`
+	Rules         []*Rule `json:"rules"`
+}
+
+// AcceptAllPolicy is used when no policy is configured
+func AcceptAllPolicy() *Policy {
+	return &Policy{
+		DefaultAction: ACTION_ACCEPT,
+		Rules:         make([]*Rule, 0),
+	}
+}
+
+func PolicyFromJSON(policyJson []byte) (*Policy, error) {
+	policy := AcceptAllPolicy()
+	if unmarshalErr := json.Unmarshal(policyJson, policy); unmarshalErr != nil {
+		return nil, fmt.Errorf("could not parse policy: %s", unmarshalErr)
+	}
+	if vetErr := policy.Vet(); vetErr != nil {
+		return nil, vetErr
+	}
+	return policy, nil
+}
+
+func LoadPolicy(path string) (*Policy, error) {
+	policyJson, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, fmt.Errorf("could not read policy file %s: %s", path, readErr)
+	}
+	return PolicyFromJSON(policyJson)
+}
+
+func (p *Policy) Vet() error {
+	if !isValidAction(p.DefaultAction) {
+		return fmt.Errorf("invalid default action %s", p.DefaultAction)
+	}
+	for idx, rule := range p.Rules {
+		if !isValidAction(rule.Action) {
+			return fmt.Errorf("invalid action %s in rule %s", rule.Action, rule.Label(idx))
+		}
+		if rule.MinRating != nil && rule.MaxRating != nil && *rule.MinRating > *rule.MaxRating {
+			return fmt.Errorf("min rating exceeds max rating in rule %s", rule.Label(idx))
+		}
+		if unreportedConds := rule.unreportedConditions(); len(unreportedConds) > 0 {
+			return fmt.Errorf("rule %s sets %s, which the arbitrator does not report", rule.Label(idx),
+				strings.Join(unreportedConds, ", "))
+		}
+	}
+	return nil
+}
+
+// unreportedConditions are the conditions the rule sets on attributes that AttributesFromChallenge
+// cannot read from a challenge, so that they would match every challenge or none
+func (r *Rule) unreportedConditions() []string {
+	conds := make([]string, 0)
+	if r.IsRated != nil {
+		conds = append(conds, "isRated")
+	}
+	if r.MinRating != nil {
+		conds = append(conds, "minRating")
+	}
+	if r.MaxRating != nil {
+		conds = append(conds, "maxRating")
+	}
+	if len(r.Variants) > 0 {
+		conds = append(conds, "variants")
+	}
+	return conds
+}
+
+// Label names the rule in logs, falling back to its position in the policy
+func (r *Rule) Label(idx int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("#%d", idx+1)
+}
+
+type Decision struct {
+	Action Action
+	// Rule is the label of the matching rule, or empty if the default action was taken
+	Rule string
+}
+
+func (d *Decision) IsAccepted() bool {
+	return d.Action == ACTION_ACCEPT
+}
+
+func (d *Decision) String() string {
+	if d.Rule == "" {
+		return fmt.Sprintf("%s by default", d.Action)
+	}
+	return fmt.Sprintf("%s by rule %s", d.Action, d.Rule)
+}
+
+func (p *Policy) Evaluate(attrs *Attributes) *Decision {
+	for idx, rule := range p.Rules {
+		if rule.Matches(attrs) {
+			return &Decision{rule.Action, rule.Label(idx)}
+		}
+	}
+	return &Decision{p.DefaultAction, ""}
+}
+
+func isValidAction(action Action) bool {
+	return action == ACTION_ACCEPT || action == ACTION_DECLINE
+}
+
+func containsStr(strs []string, target string) bool {
+	for _, str := range strs {
+		if strings.EqualFold(str, target) {
+			return true
+		}
+	}
+	return false
+}
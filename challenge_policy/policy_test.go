@@ -0,0 +1,106 @@
+package challenge_policy_test
+
+import (
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	. "github.com/CameronHonis/chess-bot-server/challenge_policy"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func NewChallenge(challengerKey arb_mods.Key, botName string, timeControl *arb_mods.TimeControl) *arb_mods.Challenge {
+	return builders.NewChallengeBuilder().
+		WithRandomUuid().
+		WithChallengerKey(challengerKey).
+		WithBotName(botName).
+		WithTimeControl(timeControl).
+		WithIsActive(true).
+		Build()
+}
+
+var _ = Describe("Policy", func() {
+	blitz := &arb_mods.TimeControl{InitialTimeSec: 300, IncrementSec: 2}
+	bullet := &arb_mods.TimeControl{InitialTimeSec: 60}
+	When("the policy is loaded from json", func() {
+		It("parses the rules", func() {
+			policy, loadErr := PolicyFromJSON([]byte(`{
+				"defaultAction": "decline",
+				"rules": [{"name": "blitz", "action": "accept", "timeControls": [{"minInitialTimeSec": 180}]}]
+			}`))
+			Expect(loadErr).ToNot(HaveOccurred())
+			Expect(policy.DefaultAction).To(Equal(ACTION_DECLINE))
+			Expect(policy.Rules).To(HaveLen(1))
+			Expect(policy.Rules[0].TimeControls[0].MinInitialTimeSec).To(Equal(int64(180)))
+		})
+		It("defaults to accepting", func() {
+			policy, loadErr := PolicyFromJSON([]byte(`{"rules": []}`))
+			Expect(loadErr).ToNot(HaveOccurred())
+			Expect(policy.DefaultAction).To(Equal(ACTION_ACCEPT))
+		})
+		It("rejects unknown actions", func() {
+			_, loadErr := PolicyFromJSON([]byte(`{"rules": [{"action": "maybe"}]}`))
+			Expect(loadErr).To(HaveOccurred())
+		})
+		It("rejects conditions the arbitrator does not report", func() {
+			for _, rule := range []string{`{"action": "decline", "isRated": true}`, `{"action": "decline", "minRating": 1000}`,
+				`{"action": "decline", "maxRating": 2000}`, `{"action": "decline", "variants": ["chess960"]}`} {
+				_, loadErr := PolicyFromJSON([]byte(`{"rules": [` + rule + `]}`))
+				Expect(loadErr).To(HaveOccurred(), rule)
+			}
+		})
+	})
+	When("evaluating challenges", func() {
+		var policy *Policy
+		BeforeEach(func() {
+			policy, _ = PolicyFromJSON([]byte(`{
+				"defaultAction": "accept",
+				"rules": [
+					{"name": "banned", "action": "decline", "playerKeys": ["troll"]},
+					{"name": "friends", "action": "accept", "playerKeys": ["friend"]},
+					{"name": "no stockfish bullet", "action": "decline", "bots": ["stockfish"],
+						"timeControls": [{"maxInitialTimeSec": 120}]}
+				]
+			}`))
+			// loading refuses rating and variant conditions, but they are still evaluated
+			maxRating := 1500
+			policy.Rules = append(policy.Rules,
+				&Rule{Name: "stockfish strong only", Action: ACTION_DECLINE, Bots: []string{"stockfish"}, MaxRating: &maxRating},
+				&Rule{Name: "standard only", Action: ACTION_DECLINE, Variants: []string{"chess960"}})
+		})
+		It("declines players on the deny list", func() {
+			decision := policy.Evaluate(AttributesFromChallenge(NewChallenge("troll", "random", blitz)))
+			Expect(decision.IsAccepted()).To(BeFalse())
+			Expect(decision.Rule).To(Equal("banned"))
+		})
+		It("accepts players on the allow list before later rules", func() {
+			decision := policy.Evaluate(AttributesFromChallenge(NewChallenge("friend", "stockfish", bullet)))
+			Expect(decision.IsAccepted()).To(BeTrue())
+			Expect(decision.Rule).To(Equal("friends"))
+		})
+		It("matches rules by engine name, ignoring bot params", func() {
+			decision := policy.Evaluate(AttributesFromChallenge(NewChallenge("someone", "stockfish?elo=1800", bullet)))
+			Expect(decision.IsAccepted()).To(BeFalse())
+			Expect(decision.Rule).To(Equal("no stockfish bullet"))
+		})
+		It("does not match rating bounds when the rating is unknown", func() {
+			decision := policy.Evaluate(AttributesFromChallenge(NewChallenge("someone", "stockfish", blitz)))
+			Expect(decision.IsAccepted()).To(BeTrue())
+			Expect(decision.Rule).To(BeEmpty())
+		})
+		It("matches rating bounds when the rating is known", func() {
+			attrs := AttributesFromChallenge(NewChallenge("someone", "stockfish", blitz))
+			rating := 1200
+			attrs.Rating = &rating
+			Expect(policy.Evaluate(attrs).Rule).To(Equal("stockfish strong only"))
+		})
+		It("treats challenges as the standard variant", func() {
+			decision := policy.Evaluate(AttributesFromChallenge(NewChallenge("someone", "random", blitz)))
+			Expect(decision.IsAccepted()).To(BeTrue())
+		})
+		It("does not match time control ranges for untimed challenges", func() {
+			policy.Rules[2].TimeControls = []*TimeControlRange{{MinInitialTimeSec: 0}}
+			decision := policy.Evaluate(AttributesFromChallenge(NewChallenge("someone", "stockfish", nil)))
+			Expect(decision.IsAccepted()).To(BeTrue())
+		})
+	})
+})
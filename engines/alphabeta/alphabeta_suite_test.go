@@ -0,0 +1,13 @@
+package alphabeta_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAlphabeta(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Alphabeta Suite")
+}
@@ -0,0 +1,162 @@
+package alphabeta
+
+import (
+	"context"
+	"fmt"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_MAX_DEPTH = 5
+	// DEFAULT_TT_MB sizes the transposition table until the resource scheduler allots a size
+	DEFAULT_TT_MB       = 16
+	MIN_SEARCH_TIME     = 20 * time.Millisecond
+	UNTIMED_SEARCH_TIME = time.Hour
+	MOVES_TO_GO_GUESS   = 30
+	CLOCK_SAFETY_RATIO  = 0.1
+)
+
+// Engine is a pure Go alpha-beta searcher built on the chess package's move generation. It
+// needs no external binary, which makes it a good default for development and CI. It implements
+// engines.EngineV2 natively, so a search stops as soon as its context is cancelled.
+type Engine struct {
+	maxDepth int
+	// tt is allocated for the first search, and freed once the match is over
+	tt         *TranspositionTable
+	lastResult *SearchResult
+	// hashMb is the transposition table size allotted by the resource scheduler, applied
+	// before the next search
+	hashMb uint
+	mu     sync.Mutex
+}
+
+func NewEngine(maxDepth uint) *Engine {
+	if maxDepth == 0 {
+		maxDepth = DEFAULT_MAX_DEPTH
+	}
+	return &Engine{
+		maxDepth: int(maxDepth),
+		hashMb:   DEFAULT_TT_MB,
+	}
+}
+
+func (e *Engine) Initialize(ctx context.Context, match *models.Match) error {
+	e.freeTable()
+	return nil
+}
+
+func (e *Engine) GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error) {
+	searchCtx, cancelSearchCtx := context.WithTimeout(ctx, SearchTime(match))
+	defer cancelSearchCtx()
+	result := Search(searchCtx, match.Board, e.table(), e.maxDepth)
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("search cancelled: %s", ctx.Err())
+	}
+	if result == nil || result.Move == nil {
+		return nil, fmt.Errorf("no legal moves")
+	}
+	e.lastResult = result
+	return result.Move, nil
+}
+
+func (e *Engine) Terminate() {
+	e.freeTable()
+}
+
+// SetResources stages the transposition table size, which is applied before the next search.
+// The search is single threaded, so threads are ignored.
+func (e *Engine) SetResources(threads uint, hashMb uint) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if hashMb != 0 {
+		e.hashMb = hashMb
+	}
+}
+
+// table returns the transposition table, allocating it at the allotted size if there is none
+// or the allotment has changed
+func (e *Engine) table() *TranspositionTable {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.tt == nil || e.tt.SizeMb() != e.hashMb {
+		e.tt = NewTranspositionTable(e.hashMb)
+	}
+	return e.tt
+}
+
+// TableSizeMb is the size of the transposition table, or 0 if it is not allocated
+func (e *Engine) TableSizeMb() uint {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.tt == nil {
+		return 0
+	}
+	return e.tt.SizeMb()
+}
+
+func (e *Engine) freeTable() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tt = nil
+}
+
+func (e *Engine) OnOpponentMove(match *models.Match, move *chess.Move) {}
+
+// OnMatchResult frees the transposition table as soon as the game is over
+func (e *Engine) OnMatchResult(match *models.Match) {
+	e.freeTable()
+}
+
+func (e *Engine) OnTakeback(match *models.Match) {}
+
+func (e *Engine) OnClockUpdate(match *models.Match) {}
+
+func (e *Engine) LastResult() *SearchResult {
+	return e.lastResult
+}
+
+// LastPV is the principal variation of the last search, starting with the move played
+func (e *Engine) LastPV() []string {
+	if e.lastResult == nil {
+		return nil
+	}
+	return e.lastResult.PV
+}
+
+func (e *Engine) LastEval() (int, bool) {
+	if e.lastResult == nil {
+		return 0, false
+	}
+	return e.lastResult.Score, true
+}
+
+// SearchTime budgets a slice of the remaining clock for the next move, assuming the game
+// lasts another MOVES_TO_GO_GUESS moves and adding most of the increment
+func SearchTime(match *models.Match) time.Duration {
+	secsRemaining := match.BlackTimeRemainingSec
+	if match.Board.IsWhiteTurn {
+		secsRemaining = match.WhiteTimeRemainingSec
+	}
+	var incrSecs float64
+	if match.TimeControl != nil {
+		incrSecs = float64(match.TimeControl.IncrementSec)
+	}
+	if secsRemaining <= 0 {
+		// untimed match, so only the depth limits the search
+		return UNTIMED_SEARCH_TIME
+	}
+
+	budgetSecs := secsRemaining/MOVES_TO_GO_GUESS + incrSecs*0.8
+	maxSecs := secsRemaining * (1 - CLOCK_SAFETY_RATIO) / 3
+	if budgetSecs > maxSecs {
+		budgetSecs = maxSecs
+	}
+	budget := time.Duration(budgetSecs * float64(time.Second))
+	if budget < MIN_SEARCH_TIME {
+		return MIN_SEARCH_TIME
+	}
+	return budget
+}
@@ -0,0 +1,100 @@
+package alphabeta_test
+
+import (
+	"context"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/engines/alphabeta"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+func MatchFromFEN(fen string, secsRemaining float64) *models.Match {
+	board, boardErr := chess.BoardFromFEN(fen)
+	Expect(boardErr).ToNot(HaveOccurred())
+	return builders.NewMatchBuilder().
+		WithBoard(board).
+		WithTimeControl(&models.TimeControl{InitialTimeSec: int64(secsRemaining)}).
+		WithTimeRemainingSec(secsRemaining).
+		Build()
+}
+
+var _ = Describe("Engine", func() {
+	var engine *alphabeta.Engine
+	BeforeEach(func() {
+		engine = alphabeta.NewEngine(3)
+	})
+	Describe("::GenerateMove", func() {
+		When("there is a mate in one", func() {
+			It("plays the mating move", func() {
+				match := MatchFromFEN("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", 60)
+				Expect(engine.Initialize(context.Background(), match)).To(Succeed())
+				move, moveErr := engine.GenerateMove(context.Background(), match)
+				Expect(moveErr).ToNot(HaveOccurred())
+				Expect(move.ToLongAlgebraic()).To(Equal("a1a8"))
+			})
+		})
+		When("a queen is hanging", func() {
+			It("captures the queen", func() {
+				match := MatchFromFEN("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", 60)
+				Expect(engine.Initialize(context.Background(), match)).To(Succeed())
+				move, moveErr := engine.GenerateMove(context.Background(), match)
+				Expect(moveErr).ToNot(HaveOccurred())
+				Expect(move.ToLongAlgebraic()).To(Equal("d2d5"))
+			})
+		})
+		When("the clock is low", func() {
+			It("returns a legal move within the budget", func() {
+				engine = alphabeta.NewEngine(20)
+				match := MatchFromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 1)
+				Expect(engine.Initialize(context.Background(), match)).To(Succeed())
+				start := time.Now()
+				move, moveErr := engine.GenerateMove(context.Background(), match)
+				Expect(moveErr).ToNot(HaveOccurred())
+				Expect(chess.IsLegalMove(match.Board, move)).To(BeTrue())
+				Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+			})
+		})
+		When("the context is cancelled mid-search", func() {
+			It("stops searching", func() {
+				engine = alphabeta.NewEngine(20)
+				match := MatchFromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0)
+				ctx, cancelCtx := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancelCtx()
+				start := time.Now()
+				Expect(engine.GenerateMove(ctx, match)).Error().To(HaveOccurred())
+				Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+			})
+		})
+		When("there are no legal moves", func() {
+			It("returns an error", func() {
+				match := MatchFromFEN("7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", 60)
+				Expect(engine.GenerateMove(context.Background(), match)).Error().To(HaveOccurred())
+			})
+		})
+	})
+	Describe("::SearchTime", func() {
+		It("never budgets more than a third of the remaining clock", func() {
+			match := MatchFromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 3)
+			match.TimeControl.IncrementSec = 10
+			Expect(alphabeta.SearchTime(match)).To(BeNumerically("<=", time.Second))
+		})
+	})
+	Describe("::SetResources", func() {
+		It("sizes the transposition table for the next search", func() {
+			match := MatchFromFEN("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", 60)
+			engine.SetResources(1, 2)
+			Expect(engine.GenerateMove(context.Background(), match)).ToNot(BeNil())
+			Expect(engine.TableSizeMb()).To(Equal(uint(2)))
+		})
+	})
+})
+
+var _ = Describe("TranspositionTable", func() {
+	It("holds as many entries as fit in its size", func() {
+		Expect(alphabeta.NewTranspositionTable(1).EntryCount()).To(Equal(1 << 20 / alphabeta.TT_ENTRY_BYTES))
+		Expect(alphabeta.NewTranspositionTable(3).EntryCount()).To(Equal(2 << 20 / alphabeta.TT_ENTRY_BYTES))
+	})
+})
@@ -0,0 +1,180 @@
+package alphabeta
+
+import "github.com/CameronHonis/chess"
+
+const (
+	PAWN_VALUE   = 100
+	KNIGHT_VALUE = 320
+	BISHOP_VALUE = 330
+	ROOK_VALUE   = 500
+	QUEEN_VALUE  = 900
+	KING_VALUE   = 20000
+)
+
+// Piece-square tables are laid out from white's perspective, with the 8th rank first.
+// Values are taken from the "simplified evaluation function".
+var pawnTable = [64]int{
+	0, 0, 0, 0, 0, 0, 0, 0,
+	50, 50, 50, 50, 50, 50, 50, 50,
+	10, 10, 20, 30, 30, 20, 10, 10,
+	5, 5, 10, 25, 25, 10, 5, 5,
+	0, 0, 0, 20, 20, 0, 0, 0,
+	5, -5, -10, 0, 0, -10, -5, 5,
+	5, 10, 10, -20, -20, 10, 10, 5,
+	0, 0, 0, 0, 0, 0, 0, 0,
+}
+
+var knightTable = [64]int{
+	-50, -40, -30, -30, -30, -30, -40, -50,
+	-40, -20, 0, 0, 0, 0, -20, -40,
+	-30, 0, 10, 15, 15, 10, 0, -30,
+	-30, 5, 15, 20, 20, 15, 5, -30,
+	-30, 0, 15, 20, 20, 15, 0, -30,
+	-30, 5, 10, 15, 15, 10, 5, -30,
+	-40, -20, 0, 5, 5, 0, -20, -40,
+	-50, -40, -30, -30, -30, -30, -40, -50,
+}
+
+var bishopTable = [64]int{
+	-20, -10, -10, -10, -10, -10, -10, -20,
+	-10, 0, 0, 0, 0, 0, 0, -10,
+	-10, 0, 5, 10, 10, 5, 0, -10,
+	-10, 5, 5, 10, 10, 5, 5, -10,
+	-10, 0, 10, 10, 10, 10, 0, -10,
+	-10, 10, 10, 10, 10, 10, 10, -10,
+	-10, 5, 0, 0, 0, 0, 5, -10,
+	-20, -10, -10, -10, -10, -10, -10, -20,
+}
+
+var rookTable = [64]int{
+	0, 0, 0, 0, 0, 0, 0, 0,
+	5, 10, 10, 10, 10, 10, 10, 5,
+	-5, 0, 0, 0, 0, 0, 0, -5,
+	-5, 0, 0, 0, 0, 0, 0, -5,
+	-5, 0, 0, 0, 0, 0, 0, -5,
+	-5, 0, 0, 0, 0, 0, 0, -5,
+	-5, 0, 0, 0, 0, 0, 0, -5,
+	0, 0, 0, 5, 5, 0, 0, 0,
+}
+
+var queenTable = [64]int{
+	-20, -10, -10, -5, -5, -10, -10, -20,
+	-10, 0, 0, 0, 0, 0, 0, -10,
+	-10, 0, 5, 5, 5, 5, 0, -10,
+	-5, 0, 5, 5, 5, 5, 0, -5,
+	0, 0, 5, 5, 5, 5, 0, -5,
+	-10, 5, 5, 5, 5, 5, 0, -10,
+	-10, 0, 5, 0, 0, 0, 0, -10,
+	-20, -10, -10, -5, -5, -10, -10, -20,
+}
+
+var kingMiddlegameTable = [64]int{
+	-30, -40, -40, -50, -50, -40, -40, -30,
+	-30, -40, -40, -50, -50, -40, -40, -30,
+	-30, -40, -40, -50, -50, -40, -40, -30,
+	-30, -40, -40, -50, -50, -40, -40, -30,
+	-20, -30, -30, -40, -40, -30, -30, -20,
+	-10, -20, -20, -20, -20, -20, -20, -10,
+	20, 20, 0, 0, 0, 0, 20, 20,
+	20, 30, 10, 0, 0, 10, 30, 20,
+}
+
+var kingEndgameTable = [64]int{
+	-50, -40, -30, -20, -20, -30, -40, -50,
+	-30, -20, -10, 0, 0, -10, -20, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -30, 0, 0, 0, 0, -30, -30,
+	-50, -30, -30, -30, -30, -30, -30, -50,
+}
+
+func PieceValue(piece chess.Piece) int {
+	switch {
+	case piece.IsPawn():
+		return PAWN_VALUE
+	case piece.IsKnight():
+		return KNIGHT_VALUE
+	case piece.IsBishop():
+		return BISHOP_VALUE
+	case piece.IsRook():
+		return ROOK_VALUE
+	case piece.IsQueen():
+		return QUEEN_VALUE
+	case piece.IsKing():
+		return KING_VALUE
+	}
+	return 0
+}
+
+// Evaluate scores the board in centipawns from the perspective of the side to move
+func Evaluate(board *chess.Board) int {
+	isEndgame := isEndgame(board)
+	score := 0
+	for rankIdx := 0; rankIdx < 8; rankIdx++ {
+		for fileIdx := 0; fileIdx < 8; fileIdx++ {
+			piece := board.Pieces[rankIdx][fileIdx]
+			if piece == chess.EMPTY {
+				continue
+			}
+			var tableIdx int
+			if piece.IsWhite() {
+				tableIdx = (7-rankIdx)*8 + fileIdx
+			} else {
+				tableIdx = rankIdx*8 + fileIdx
+			}
+			pieceScore := PieceValue(piece) + pieceSquareValue(piece, tableIdx, isEndgame)
+			if piece.IsWhite() {
+				score += pieceScore
+			} else {
+				score -= pieceScore
+			}
+		}
+	}
+	if !board.IsWhiteTurn {
+		return -score
+	}
+	return score
+}
+
+func pieceSquareValue(piece chess.Piece, tableIdx int, isEndgame bool) int {
+	switch {
+	case piece.IsPawn():
+		return pawnTable[tableIdx]
+	case piece.IsKnight():
+		return knightTable[tableIdx]
+	case piece.IsBishop():
+		return bishopTable[tableIdx]
+	case piece.IsRook():
+		return rookTable[tableIdx]
+	case piece.IsQueen():
+		return queenTable[tableIdx]
+	case piece.IsKing():
+		if isEndgame {
+			return kingEndgameTable[tableIdx]
+		}
+		return kingMiddlegameTable[tableIdx]
+	}
+	return 0
+}
+
+// isEndgame considers the game to be in the endgame once neither side has more than a
+// queen's worth of minor and major pieces
+func isEndgame(board *chess.Board) bool {
+	var whiteMaterial, blackMaterial int
+	for rankIdx := 0; rankIdx < 8; rankIdx++ {
+		for fileIdx := 0; fileIdx < 8; fileIdx++ {
+			piece := board.Pieces[rankIdx][fileIdx]
+			if piece == chess.EMPTY || piece.IsPawn() || piece.IsKing() {
+				continue
+			}
+			if piece.IsWhite() {
+				whiteMaterial += PieceValue(piece)
+			} else {
+				blackMaterial += PieceValue(piece)
+			}
+		}
+	}
+	return whiteMaterial <= QUEEN_VALUE+KNIGHT_VALUE && blackMaterial <= QUEEN_VALUE+KNIGHT_VALUE
+}
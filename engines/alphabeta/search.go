@@ -0,0 +1,306 @@
+package alphabeta
+
+import (
+	"context"
+	"github.com/CameronHonis/chess"
+	"sort"
+)
+
+const (
+	INF_SCORE       = 1_000_000
+	MATE_SCORE      = 100_000
+	MAX_QUIET_DEPTH = 6
+)
+
+type SearchResult struct {
+	Move  *chess.Move
+	Score int // centipawns from the perspective of the side to move
+	Depth int
+	Nodes uint64
+	PV    []string // principal variation in long algebraic notation
+}
+
+type searcher struct {
+	ctx     context.Context
+	tt      *TranspositionTable
+	nodes   uint64
+	aborted bool
+}
+
+// Search runs an iterative deepening alpha-beta search until maxDepth is reached or the
+// context is done. The result of the deepest fully searched iteration is returned.
+func Search(ctx context.Context, board *chess.Board, tt *TranspositionTable, maxDepth int) *SearchResult {
+	s := &searcher{
+		ctx: ctx,
+		tt:  tt,
+	}
+	var result *SearchResult
+	for depth := 1; depth <= maxDepth; depth++ {
+		move, score := s.searchRoot(board, depth)
+		if s.aborted && result != nil {
+			break
+		}
+		result = &SearchResult{
+			Move:  move,
+			Score: score,
+			Depth: depth,
+			Nodes: s.nodes,
+		}
+		if s.aborted || score >= MATE_SCORE-maxDepth || score <= -MATE_SCORE+maxDepth {
+			break
+		}
+	}
+	if result != nil && result.Move != nil {
+		result.PV = s.principalVariation(board, result.Depth)
+	}
+	return result
+}
+
+func (s *searcher) searchRoot(board *chess.Board, depth int) (*chess.Move, int) {
+	moves, movesErr := chess.GetLegalMoves(board)
+	if movesErr != nil || len(moves) == 0 {
+		return nil, s.terminalScore(board, 0)
+	}
+	hash := ZobristHash(board)
+	s.orderMoves(moves, s.ttMove(hash))
+
+	alpha, beta := -INF_SCORE, INF_SCORE
+	var bestMove = moves[0]
+	var bestScore = -INF_SCORE
+	for _, move := range moves {
+		child := chess.GetBoardFromMove(board, move)
+		score := -s.negamax(child, depth-1, 1, -beta, -alpha)
+		if s.aborted {
+			break
+		}
+		if score > bestScore {
+			bestScore = score
+			bestMove = move
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	if !s.aborted {
+		s.tt.Store(hash, depth, bestScore, TT_EXACT, bestMove)
+	}
+	return bestMove, bestScore
+}
+
+func (s *searcher) negamax(board *chess.Board, depth, ply, alpha, beta int) int {
+	if s.shouldAbort() {
+		return 0
+	}
+	s.nodes++
+	if board.Result != chess.BOARD_RESULT_IN_PROGRESS {
+		return s.terminalScore(board, ply)
+	}
+
+	hash := ZobristHash(board)
+	alphaOrig := alpha
+	if entry, ok := s.tt.Probe(hash); ok && int(entry.depth) >= depth {
+		score := scoreFromTT(int(entry.score), ply)
+		switch entry.flag {
+		case TT_EXACT:
+			return score
+		case TT_LOWER_BOUND:
+			if score > alpha {
+				alpha = score
+			}
+		case TT_UPPER_BOUND:
+			if score < beta {
+				beta = score
+			}
+		}
+		if alpha >= beta {
+			return score
+		}
+	}
+
+	if depth <= 0 {
+		return s.quiesce(board, ply, alpha, beta, 0)
+	}
+
+	moves, movesErr := chess.GetLegalMoves(board)
+	if movesErr != nil || len(moves) == 0 {
+		return s.terminalScore(board, ply)
+	}
+	s.orderMoves(moves, s.ttMove(hash))
+
+	bestScore := -INF_SCORE
+	var bestMove *chess.Move
+	for _, move := range moves {
+		child := chess.GetBoardFromMove(board, move)
+		score := -s.negamax(child, depth-1, ply+1, -beta, -alpha)
+		if s.aborted {
+			return 0
+		}
+		if score > bestScore {
+			bestScore = score
+			bestMove = move
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+
+	flag := TT_EXACT
+	if bestScore <= alphaOrig {
+		flag = TT_UPPER_BOUND
+	} else if bestScore >= beta {
+		flag = TT_LOWER_BOUND
+	}
+	s.tt.Store(hash, depth, scoreToTT(bestScore, ply), flag, bestMove)
+	return bestScore
+}
+
+// quiesce only searches captures and promotions, so the static evaluation is never taken
+// in the middle of an exchange
+func (s *searcher) quiesce(board *chess.Board, ply, alpha, beta, quietDepth int) int {
+	if s.shouldAbort() {
+		return 0
+	}
+	s.nodes++
+	if board.Result != chess.BOARD_RESULT_IN_PROGRESS {
+		return s.terminalScore(board, ply)
+	}
+
+	standPat := Evaluate(board)
+	if standPat >= beta {
+		return standPat
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+	if quietDepth >= MAX_QUIET_DEPTH {
+		return alpha
+	}
+
+	moves, movesErr := chess.GetLegalMoves(board)
+	if movesErr != nil {
+		return s.terminalScore(board, ply)
+	}
+	if len(moves) == 0 {
+		return s.terminalScore(board, ply)
+	}
+	tacticalMoves := make([]*chess.Move, 0, len(moves))
+	for _, move := range moves {
+		if move.CapturedPiece != chess.EMPTY || move.PawnUpgradedTo != chess.EMPTY {
+			tacticalMoves = append(tacticalMoves, move)
+		}
+	}
+	s.orderMoves(tacticalMoves, 0)
+
+	for _, move := range tacticalMoves {
+		child := chess.GetBoardFromMove(board, move)
+		score := -s.quiesce(child, ply+1, -beta, -alpha, quietDepth+1)
+		if s.aborted {
+			return 0
+		}
+		if score >= beta {
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+// terminalScore scores a board on which the side to move has no legal moves, or the game has
+// otherwise ended
+func (s *searcher) terminalScore(board *chess.Board, ply int) int {
+	switch board.Result {
+	case chess.BOARD_RESULT_WHITE_WINS_BY_CHECKMATE, chess.BOARD_RESULT_BLACK_WINS_BY_CHECKMATE:
+		return -MATE_SCORE + ply
+	case chess.BOARD_RESULT_IN_PROGRESS:
+		if len(chess.GetCheckingSquares(board, board.IsWhiteTurn)) > 0 {
+			return -MATE_SCORE + ply
+		}
+	}
+	return 0
+}
+
+func (s *searcher) shouldAbort() bool {
+	if s.aborted {
+		return true
+	}
+	if s.nodes&63 == 0 {
+		if s.ctx.Err() != nil {
+			s.aborted = true
+		}
+	}
+	return s.aborted
+}
+
+func (s *searcher) ttMove(hash uint64) packedMove {
+	if entry, ok := s.tt.Probe(hash); ok {
+		return entry.bestMove
+	}
+	return 0
+}
+
+// orderMoves sorts the hash move first, then captures by most valuable victim/least valuable
+// attacker, then promotions, then quiet moves
+func (s *searcher) orderMoves(moves []*chess.Move, ttMove packedMove) {
+	scores := make(map[*chess.Move]int, len(moves))
+	for _, move := range moves {
+		score := 0
+		if ttMove != 0 && packMove(move) == ttMove {
+			score += 1_000_000
+		}
+		if move.CapturedPiece != chess.EMPTY {
+			score += 10_000 + 10*PieceValue(move.CapturedPiece) - PieceValue(move.Piece)/10
+		}
+		if move.PawnUpgradedTo != chess.EMPTY {
+			score += 5_000 + PieceValue(move.PawnUpgradedTo)
+		}
+		scores[move] = score
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return scores[moves[i]] > scores[moves[j]]
+	})
+}
+
+func (s *searcher) principalVariation(board *chess.Board, maxLen int) []string {
+	pv := make([]string, 0, maxLen)
+	for len(pv) < maxLen {
+		entry, ok := s.tt.Probe(ZobristHash(board))
+		if !ok {
+			break
+		}
+		move := entry.bestMove.unpack(board)
+		if move == nil {
+			break
+		}
+		pv = append(pv, move.ToLongAlgebraic())
+		board = chess.GetBoardFromMove(board, move)
+		if board.Result != chess.BOARD_RESULT_IN_PROGRESS {
+			break
+		}
+	}
+	return pv
+}
+
+// mate scores are stored relative to the node rather than the root, so they stay correct
+// when the position is reached at a different ply
+func scoreToTT(score, ply int) int {
+	if score >= MATE_SCORE-1000 {
+		return score + ply
+	} else if score <= -MATE_SCORE+1000 {
+		return score - ply
+	}
+	return score
+}
+
+func scoreFromTT(score, ply int) int {
+	if score >= MATE_SCORE-1000 {
+		return score - ply
+	} else if score <= -MATE_SCORE+1000 {
+		return score + ply
+	}
+	return score
+}
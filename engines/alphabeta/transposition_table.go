@@ -0,0 +1,169 @@
+package alphabeta
+
+import (
+	"github.com/CameronHonis/chess"
+	"math/rand"
+	"unsafe"
+)
+
+type ttFlag uint8
+
+const (
+	TT_EXACT ttFlag = iota
+	TT_LOWER_BOUND
+	TT_UPPER_BOUND
+)
+
+// TT_ENTRY_BYTES is the size of a ttEntry, which the table is sized by
+const TT_ENTRY_BYTES = 16
+
+// fails to compile if a ttEntry is not TT_ENTRY_BYTES
+var _ [TT_ENTRY_BYTES]byte = [unsafe.Sizeof(ttEntry{})]byte{}
+
+// packedMove holds a move's start square, end square and promotion in 16 bits. The zero value
+// is no move, since no move starts and ends on a1.
+type packedMove uint16
+
+func packMove(move *chess.Move) packedMove {
+	if move == nil {
+		return 0
+	}
+	startIdx := uint16(move.StartSquare.Rank-1)*8 + uint16(move.StartSquare.File-1)
+	endIdx := uint16(move.EndSquare.Rank-1)*8 + uint16(move.EndSquare.File-1)
+	return packedMove(startIdx | endIdx<<6 | uint16(move.PawnUpgradedTo)<<12)
+}
+
+// unpack finds the legal move on the board that was packed, or nil if there is none
+func (m packedMove) unpack(board *chess.Board) *chess.Move {
+	if m == 0 {
+		return nil
+	}
+	moves, movesErr := chess.GetLegalMoves(board)
+	if movesErr != nil {
+		return nil
+	}
+	for _, move := range moves {
+		if packMove(move) == m {
+			return move
+		}
+	}
+	return nil
+}
+
+// ttEntry is stored by value, so that the table is a single allocation of fixed size
+type ttEntry struct {
+	key      uint64
+	score    int32
+	bestMove packedMove
+	depth    int8
+	flag     ttFlag
+}
+
+// TranspositionTable caches search results by zobrist hash in a fixed number of slots. A result
+// replaces the slot's entry unless that entry is for the same position and searched deeper.
+type TranspositionTable struct {
+	entries []ttEntry
+	sizeMb  uint
+}
+
+// NewTranspositionTable allocates a table of up to sizeMb megabytes, holding a power of two
+// entries
+func NewTranspositionTable(sizeMb uint) *TranspositionTable {
+	if sizeMb == 0 {
+		sizeMb = 1
+	}
+	entryCount := uint64(1)
+	for entryCount*2*TT_ENTRY_BYTES <= uint64(sizeMb)<<20 {
+		entryCount *= 2
+	}
+	return &TranspositionTable{
+		entries: make([]ttEntry, entryCount),
+		sizeMb:  sizeMb,
+	}
+}
+
+func (tt *TranspositionTable) SizeMb() uint {
+	return tt.sizeMb
+}
+
+func (tt *TranspositionTable) EntryCount() int {
+	return len(tt.entries)
+}
+
+func (tt *TranspositionTable) Probe(hash uint64) (ttEntry, bool) {
+	entry := tt.entries[hash&uint64(len(tt.entries)-1)]
+	return entry, entry.key == hash
+}
+
+func (tt *TranspositionTable) Store(hash uint64, depth, score int, flag ttFlag, bestMove *chess.Move) {
+	slot := &tt.entries[hash&uint64(len(tt.entries)-1)]
+	if slot.key == hash && int(slot.depth) > depth {
+		return
+	}
+	*slot = ttEntry{
+		key:      hash,
+		score:    int32(score),
+		bestMove: packMove(bestMove),
+		depth:    int8(depth),
+		flag:     flag,
+	}
+}
+
+func (tt *TranspositionTable) Clear() {
+	for i := range tt.entries {
+		tt.entries[i] = ttEntry{}
+	}
+}
+
+var zobristPieces [13][64]uint64
+var zobristBlackTurn uint64
+var zobristCastleRights [4]uint64
+var zobristEnPassantFile [8]uint64
+
+func init() {
+	rng := rand.New(rand.NewSource(0x5eed))
+	for piece := range zobristPieces {
+		for sqrIdx := range zobristPieces[piece] {
+			zobristPieces[piece][sqrIdx] = rng.Uint64()
+		}
+	}
+	zobristBlackTurn = rng.Uint64()
+	for i := range zobristCastleRights {
+		zobristCastleRights[i] = rng.Uint64()
+	}
+	for i := range zobristEnPassantFile {
+		zobristEnPassantFile[i] = rng.Uint64()
+	}
+}
+
+func ZobristHash(board *chess.Board) uint64 {
+	var hash uint64
+	for rankIdx := 0; rankIdx < 8; rankIdx++ {
+		for fileIdx := 0; fileIdx < 8; fileIdx++ {
+			piece := board.Pieces[rankIdx][fileIdx]
+			if piece == chess.EMPTY {
+				continue
+			}
+			hash ^= zobristPieces[piece][rankIdx*8+fileIdx]
+		}
+	}
+	if !board.IsWhiteTurn {
+		hash ^= zobristBlackTurn
+	}
+	if board.CanWhiteCastleKingside {
+		hash ^= zobristCastleRights[0]
+	}
+	if board.CanWhiteCastleQueenside {
+		hash ^= zobristCastleRights[1]
+	}
+	if board.CanBlackCastleKingside {
+		hash ^= zobristCastleRights[2]
+	}
+	if board.CanBlackCastleQueenside {
+		hash ^= zobristCastleRights[3]
+	}
+	if board.OptEnPassantSquare != nil {
+		hash ^= zobristEnPassantFile[board.OptEnPassantSquare.File-1]
+	}
+	return hash
+}
@@ -0,0 +1,41 @@
+package engines
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ParseBotName splits a bot name into the engine name and its parameters. Parameters are
+// formatted like a url query, for example "alphabeta?depth=5".
+func ParseBotName(botName string) (string, url.Values, error) {
+	name, rawParams, _ := strings.Cut(botName, "?")
+	params, parseErr := url.ParseQuery(rawParams)
+	if parseErr != nil {
+		return "", nil, fmt.Errorf("could not parse params of bot %s: %s", botName, parseErr)
+	}
+	return name, params, nil
+}
+
+func uintParam(params url.Values, name string, defaultVal uint) (uint, error) {
+	if !params.Has(name) {
+		return defaultVal, nil
+	}
+	val, parseErr := strconv.ParseUint(params.Get(name), 10, 32)
+	if parseErr != nil {
+		return 0, fmt.Errorf("invalid value %s for param %s: %s", params.Get(name), name, parseErr)
+	}
+	return uint(val), nil
+}
+
+func floatParam(params url.Values, name string, defaultVal float64) (float64, error) {
+	if !params.Has(name) {
+		return defaultVal, nil
+	}
+	val, parseErr := strconv.ParseFloat(params.Get(name), 64)
+	if parseErr != nil {
+		return 0, fmt.Errorf("invalid value %s for param %s: %s", params.Get(name), name, parseErr)
+	}
+	return val, nil
+}
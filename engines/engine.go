@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"github.com/CameronHonis/chess"
 	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/engines/alphabeta"
+	"github.com/CameronHonis/chess-bot-server/engines/humanlike"
 	"github.com/CameronHonis/chess-bot-server/engines/mila"
 	"github.com/CameronHonis/chess-bot-server/engines/random"
 	"github.com/CameronHonis/chess-bot-server/engines/stockfish"
+	"github.com/CameronHonis/chess-bot-server/engines/xboard"
+	"net/url"
 	"os"
 	"os/exec"
 )
@@ -17,10 +21,27 @@ type Engine interface {
 	Terminate()
 }
 
-func EngineFromName(engineName string) (Engine, error) {
+// EngineFromName builds the engine for a bot name, which may carry parameters for the engine
+// (see ParseBotName)
+func EngineFromName(botName string) (EngineV2, error) {
+	engineName, params, parseErr := ParseBotName(botName)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return newEngine(engineName, params)
+}
+
+// newEngine builds the named engine. Engines that only implement Engine are adapted to EngineV2.
+func newEngine(engineName string, params url.Values) (EngineV2, error) {
 	switch engineName {
 	case "random":
-		return &random.Engine{}, nil
+		return AsV2(&random.Engine{})
+	case "alphabeta":
+		maxDepth, depthErr := uintParam(params, "depth", alphabeta.DEFAULT_MAX_DEPTH)
+		if depthErr != nil {
+			return nil, fmt.Errorf("could not make alphabeta engine: %s", depthErr)
+		}
+		return alphabeta.NewEngine(maxDepth), nil
 	case "stockfish":
 		cmd, stockfishErr := StockfishCmd()
 		if stockfishErr != nil {
@@ -43,11 +64,51 @@ func EngineFromName(engineName string) (Engine, error) {
 			return nil, fmt.Errorf("could not make mila engine: %s", engineErr)
 		}
 		return engine, nil
+	case "humanlike":
+		return humanlikeEngine(params)
+	case "xboard":
+		cmd, xboardErr := XboardCmd()
+		if xboardErr != nil {
+			return nil, xboardErr
+		}
+
+		engine, engineErr := xboard.NewEngine(cmd)
+		if engineErr != nil {
+			return nil, fmt.Errorf("could not make xboard engine: %s", engineErr)
+		}
+		return engine, nil
 	default:
 		return nil, fmt.Errorf("unimplemented engine %s", engineName)
 	}
 }
 
+// humanlikeEngine weakens stockfish to the "rating" param. The "temperature" param overrides
+// the temperature derived from the rating, and "candidates" sets how many moves are sampled.
+func humanlikeEngine(params url.Values) (EngineV2, error) {
+	rating, ratingErr := uintParam(params, "rating", humanlike.DEFAULT_RATING)
+	if ratingErr != nil {
+		return nil, fmt.Errorf("could not make humanlike engine: %s", ratingErr)
+	}
+	temperature, temperatureErr := floatParam(params, "temperature", 0)
+	if temperatureErr != nil {
+		return nil, fmt.Errorf("could not make humanlike engine: %s", temperatureErr)
+	}
+	candidates, candidatesErr := uintParam(params, "candidates", humanlike.DEFAULT_CANDIDATES)
+	if candidatesErr != nil {
+		return nil, fmt.Errorf("could not make humanlike engine: %s", candidatesErr)
+	}
+
+	cmd, stockfishErr := StockfishCmd()
+	if stockfishErr != nil {
+		return nil, stockfishErr
+	}
+	engine, engineErr := humanlike.NewEngine(cmd, rating, temperature, candidates)
+	if engineErr != nil {
+		return nil, fmt.Errorf("could not make humanlike engine: %s", engineErr)
+	}
+	return engine, nil
+}
+
 func StockfishCmd() (*exec.Cmd, error) {
 	path, pathExists := os.LookupEnv("STOCKFISH_PATH")
 	if !pathExists {
@@ -65,3 +126,12 @@ func MilaCmd() (*exec.Cmd, error) {
 
 	return exec.Command(path), nil
 }
+
+func XboardCmd() (*exec.Cmd, error) {
+	path, pathExists := os.LookupEnv("XBOARD_ENGINE_PATH")
+	if !pathExists {
+		return nil, fmt.Errorf("xboard engine path not found")
+	}
+
+	return exec.Command(path), nil
+}
@@ -0,0 +1,136 @@
+package engines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/uci_client/cmd_client"
+	"sync"
+)
+
+// EngineV2 extends the Engine lifecycle with cancellation and game events. The context passed to
+// GenerateMove is cancelled as soon as the move is no longer wanted, for example when the
+// opponent resigns or the bot runs out of time.
+type EngineV2 interface {
+	Initialize(ctx context.Context, match *models.Match) error
+	GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error)
+	Terminate()
+
+	// OnOpponentMove is called with the match after the opponent's move was applied
+	OnOpponentMove(match *models.Match, move *chess.Move)
+	// OnMatchResult is called once the match has ended, before the engine is terminated
+	OnMatchResult(match *models.Match)
+	// OnTakeback is called with the match after one or more moves were taken back
+	OnTakeback(match *models.Match)
+	// OnClockUpdate is called whenever the arbitrator reports new clock times
+	OnClockUpdate(match *models.Match)
+}
+
+// CrashError reports an engine that panicked or whose process exited, rather than one that
+// merely failed to move
+type CrashError struct {
+	Detail string
+}
+
+func (e *CrashError) Error() string {
+	return fmt.Sprintf("engine crashed: %s", e.Detail)
+}
+
+// crashFromExit returns a CrashError for a move error caused by the engine's process exiting
+func crashFromExit(err error) (*CrashError, bool) {
+	var exitErr *cmd_client.ExitError
+	if !errors.As(err, &exitErr) {
+		return nil, false
+	}
+	return &CrashError{exitErr.Error()}, true
+}
+
+// AsV2 returns engines that already implement EngineV2 unchanged, and adapts all others
+func AsV2(engine interface{}) (EngineV2, error) {
+	switch e := engine.(type) {
+	case EngineV2:
+		return e, nil
+	case Engine:
+		return &v1Adapter{engine: e}, nil
+	default:
+		return nil, fmt.Errorf("%T is not an engine", engine)
+	}
+}
+
+// v1Adapter runs an Engine as an EngineV2. An Engine cannot be interrupted, so on cancellation
+// its move is abandoned rather than stopped, and game events are ignored.
+type v1Adapter struct {
+	engine Engine
+	// pending holds the result of an abandoned move. The engine is not asked for another move
+	// until it arrives.
+	pending chan *generatedMove
+	mu      sync.Mutex
+}
+
+func (a *v1Adapter) Initialize(ctx context.Context, match *models.Match) error {
+	return a.engine.Initialize(match)
+}
+
+func (a *v1Adapter) GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error) {
+	a.mu.Lock()
+	if a.pending != nil {
+		select {
+		case <-a.pending:
+			a.pending = nil
+		default:
+			a.mu.Unlock()
+			return nil, fmt.Errorf("engine is still searching a previous position")
+		}
+	}
+	a.mu.Unlock()
+
+	resultChan := make(chan *generatedMove, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				resultChan <- &generatedMove{err: &CrashError{fmt.Sprint(r)}}
+			}
+		}()
+		move, moveErr := a.engine.GenerateMove(match)
+		resultChan <- &generatedMove{move, moveErr}
+	}()
+
+	select {
+	case result := <-resultChan:
+		return result.move, result.err
+	case <-ctx.Done():
+		a.mu.Lock()
+		a.pending = resultChan
+		a.mu.Unlock()
+		return nil, fmt.Errorf("move generation cancelled: %s", ctx.Err())
+	}
+}
+
+func (a *v1Adapter) Terminate() {
+	a.engine.Terminate()
+}
+
+func (a *v1Adapter) OnOpponentMove(match *models.Match, move *chess.Move) {}
+
+func (a *v1Adapter) OnMatchResult(match *models.Match) {}
+
+func (a *v1Adapter) OnTakeback(match *models.Match) {}
+
+func (a *v1Adapter) OnClockUpdate(match *models.Match) {}
+
+// Unwrap returns the innermost engine behind any wrappers and the AsV2 adapter, so optional
+// interfaces such as a resource consumer can still be detected
+func Unwrap(engine EngineV2) interface{} {
+	for {
+		switch e := engine.(type) {
+		case *v1Adapter:
+			return e.engine
+		case interface{ Inner() EngineV2 }:
+			engine = e.Inner()
+		default:
+			return engine
+		}
+	}
+}
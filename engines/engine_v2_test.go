@@ -0,0 +1,60 @@
+package engines_test
+
+import (
+	"context"
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	"github.com/CameronHonis/chess-bot-server/engines"
+	"github.com/CameronHonis/chess-bot-server/engines/alphabeta"
+	"github.com/CameronHonis/chess-bot-server/engines/humanlike"
+	"github.com/CameronHonis/chess-bot-server/engines/mila"
+	"github.com/CameronHonis/chess-bot-server/engines/random"
+	"github.com/CameronHonis/chess-bot-server/engines/stockfish"
+	"github.com/CameronHonis/chess-bot-server/engines/xboard"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"os/exec"
+	"time"
+)
+
+var _ = Describe("AsV2", func() {
+	When("the engine implements EngineV2", func() {
+		It("returns the engine unchanged", func() {
+			engine := alphabeta.NewEngine(1)
+			Expect(engines.AsV2(engine)).To(BeIdenticalTo(engine))
+		})
+		It("includes the UCI engines, so their searches can be stopped", func() {
+			stockfishEngine, _ := stockfish.NewEngine(exec.Command("stockfish"))
+			Expect(engines.AsV2(stockfishEngine)).To(BeIdenticalTo(stockfishEngine))
+			milaEngine, _ := mila.NewEngine(exec.Command("mila"))
+			Expect(engines.AsV2(milaEngine)).To(BeIdenticalTo(milaEngine))
+			humanlikeEngine, _ := humanlike.NewEngine(exec.Command("stockfish"), 1200, 0, 0)
+			Expect(engines.AsV2(humanlikeEngine)).To(BeIdenticalTo(humanlikeEngine))
+		})
+		It("includes the xboard engine, so its searches can be interrupted", func() {
+			xboardEngine, _ := xboard.NewEngine(exec.Command("xboard-engine"))
+			Expect(engines.AsV2(xboardEngine)).To(BeIdenticalTo(xboardEngine))
+		})
+	})
+	When("the engine only implements Engine", func() {
+		It("abandons the move once the context is cancelled", func() {
+			engine := v2(&MockEngine{moveStr: "e2e4", delay: time.Second})
+			ctx, cancelCtx := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancelCtx()
+			start := time.Now()
+			Expect(engine.GenerateMove(ctx, builders.NewMatchBuilder().Build())).Error().To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+		})
+		It("can be unwrapped", func() {
+			mockEngine := &MockEngine{}
+			Expect(engines.Unwrap(v2(mockEngine))).To(BeIdenticalTo(mockEngine))
+		})
+	})
+	It("adapts the engines that only implement Engine when they are built by name", func() {
+		engine, engineErr := engines.EngineFromName("random")
+		Expect(engineErr).ToNot(HaveOccurred())
+		Expect(engines.Unwrap(engine)).To(BeAssignableToTypeOf(&random.Engine{}))
+	})
+	It("rejects values that are not engines", func() {
+		Expect(engines.AsV2("stockfish")).Error().To(HaveOccurred())
+	})
+})
@@ -0,0 +1,19 @@
+package engines
+
+// EvalReporter engines expose their evaluation of the position they last moved in, in
+// centipawns from their own perspective. The evaluation is not reported if the engine never
+// searched the position.
+type EvalReporter interface {
+	LastEval() (int, bool)
+}
+
+// LastEval is the evaluation reported by the engine, or by the engine it wraps
+func LastEval(engine EngineV2) (int, bool) {
+	if reporter, ok := engine.(EvalReporter); ok {
+		return reporter.LastEval()
+	}
+	if reporter, ok := Unwrap(engine).(EvalReporter); ok {
+		return reporter.LastEval()
+	}
+	return 0, false
+}
@@ -0,0 +1,249 @@
+package engines
+
+import (
+	"context"
+	"fmt"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_PRIMARY_TIME_FRACTION = 0.25
+	MIN_TIME_SLICE                = 50 * time.Millisecond
+	UNTIMED_TIME_SLICE            = time.Minute
+)
+
+type generatedMove struct {
+	move *chess.Move
+	err  error
+}
+
+// FallbackEngine always produces a legal move. The primary engine is given a slice of the
+// remaining clock, after which the fallback engine is given a slice of what is left. If neither
+// produces a legal move in time, a random legal move is played.
+type FallbackEngine struct {
+	primary      EngineV2
+	newFallback  func() (EngineV2, error)
+	timeFraction float64
+	onFallback   func(reason string, cause error)
+
+	// fallback is built by newFallback the first time the primary engine fails
+	fallback EngineV2
+	// lastMover is the engine that produced the last move, or nil if it was a random move
+	lastMover EngineV2
+	mu        sync.Mutex
+}
+
+// NewFallbackEngine wraps the primary engine. newFallback builds the fallback engine the first
+// time the primary engine fails, so bots whose engine never fails never start one. It may be nil,
+// in which case the random legal move is the only fallback. onFallback is called with the reason
+// and the engine's error each time an engine fails to produce a move.
+func NewFallbackEngine(primary EngineV2, newFallback func() (EngineV2, error), timeFraction float64, onFallback func(reason string, cause error)) *FallbackEngine {
+	if timeFraction <= 0 || timeFraction > 1 {
+		timeFraction = DEFAULT_PRIMARY_TIME_FRACTION
+	}
+	if onFallback == nil {
+		onFallback = func(reason string, cause error) {}
+	}
+	return &FallbackEngine{
+		primary:      primary,
+		newFallback:  newFallback,
+		timeFraction: timeFraction,
+		onFallback:   onFallback,
+	}
+}
+
+func (e *FallbackEngine) Initialize(ctx context.Context, match *models.Match) error {
+	return e.primary.Initialize(ctx, match)
+}
+
+func (e *FallbackEngine) GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error) {
+	start := time.Now()
+	secsRemaining := match.BlackTimeRemainingSec
+	if match.Board.IsWhiteTurn {
+		secsRemaining = match.WhiteTimeRemainingSec
+	}
+	timeRemaining := time.Duration(secsRemaining * float64(time.Second))
+
+	e.setLastMover(nil)
+	move, primaryErr := e.tryEngine(ctx, e.primary, match, e.timeSlice(timeRemaining))
+	if primaryErr == nil {
+		e.setLastMover(e.primary)
+		return move, nil
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("move generation cancelled: %s", ctx.Err())
+	}
+	e.onFallback(fmt.Sprintf("primary engine failed: %s", primaryErr), primaryErr)
+
+	fallbackStart := time.Now()
+	fallbackSlice := e.timeSlice(timeRemaining - time.Since(start))
+	if fallback := e.fallbackEngine(ctx, match, fallbackSlice); fallback != nil {
+		// building the fallback engine the first time it is needed spends part of its slice
+		moveSlice := fallbackSlice - time.Since(fallbackStart)
+		if moveSlice < MIN_TIME_SLICE {
+			moveSlice = MIN_TIME_SLICE
+		}
+		move, fallbackErr := e.tryEngine(ctx, fallback, match, moveSlice)
+		if fallbackErr == nil {
+			e.setLastMover(fallback)
+			return move, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("move generation cancelled: %s", ctx.Err())
+		}
+		e.onFallback(fmt.Sprintf("fallback engine failed: %s", fallbackErr), fallbackErr)
+	}
+
+	return RandomLegalMove(match.Board)
+}
+
+func (e *FallbackEngine) Terminate() {
+	e.forEachEngine(func(engine EngineV2) { engine.Terminate() })
+}
+
+func (e *FallbackEngine) OnOpponentMove(match *models.Match, move *chess.Move) {
+	e.forEachEngine(func(engine EngineV2) { engine.OnOpponentMove(match, move) })
+}
+
+func (e *FallbackEngine) OnMatchResult(match *models.Match) {
+	e.forEachEngine(func(engine EngineV2) { engine.OnMatchResult(match) })
+}
+
+func (e *FallbackEngine) OnTakeback(match *models.Match) {
+	e.forEachEngine(func(engine EngineV2) { engine.OnTakeback(match) })
+}
+
+func (e *FallbackEngine) OnClockUpdate(match *models.Match) {
+	e.forEachEngine(func(engine EngineV2) { engine.OnClockUpdate(match) })
+}
+
+// LastPV is the principal variation reported by whichever engine produced the last move
+func (e *FallbackEngine) LastPV() []string {
+	e.mu.Lock()
+	lastMover := e.lastMover
+	e.mu.Unlock()
+	if reporter, ok := lastMover.(PVReporter); ok {
+		return reporter.LastPV()
+	}
+	if lastMover != nil {
+		if reporter, ok := Unwrap(lastMover).(PVReporter); ok {
+			return reporter.LastPV()
+		}
+	}
+	return nil
+}
+
+// LastEval is the evaluation reported by whichever engine produced the last move
+func (e *FallbackEngine) LastEval() (int, bool) {
+	e.mu.Lock()
+	lastMover := e.lastMover
+	e.mu.Unlock()
+	if lastMover == nil {
+		return 0, false
+	}
+	return LastEval(lastMover)
+}
+
+func (e *FallbackEngine) Primary() EngineV2 {
+	return e.primary
+}
+
+// tryEngine waits up to the time slice for the engine's move, and verifies that it is legal
+func (e *FallbackEngine) tryEngine(ctx context.Context, engine EngineV2, match *models.Match, timeSlice time.Duration) (move *chess.Move, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			move, err = nil, &CrashError{fmt.Sprint(r)}
+		}
+	}()
+	sliceCtx, cancelSliceCtx := context.WithTimeout(ctx, timeSlice)
+	defer cancelSliceCtx()
+
+	move, moveErr := engine.GenerateMove(sliceCtx, match)
+	if moveErr != nil {
+		if sliceCtx.Err() != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("engine did not move within %s", timeSlice)
+		}
+		if crashErr, isCrash := crashFromExit(moveErr); isCrash {
+			return nil, crashErr
+		}
+		return nil, moveErr
+	}
+	if move == nil || !chess.IsLegalMove(match.Board, move) {
+		return nil, fmt.Errorf("engine returned illegal move %v", move)
+	}
+	return move, nil
+}
+
+func (e *FallbackEngine) timeSlice(timeRemaining time.Duration) time.Duration {
+	if timeRemaining <= 0 {
+		// untimed match
+		return UNTIMED_TIME_SLICE
+	}
+	timeSlice := time.Duration(float64(timeRemaining) * e.timeFraction)
+	if timeSlice < MIN_TIME_SLICE {
+		return MIN_TIME_SLICE
+	}
+	return timeSlice
+}
+
+// fallbackEngine returns the fallback engine, building and initializing it within the time
+// slice if this is the first time it is needed. It returns nil if there is no fallback engine,
+// and disables the fallback engine if it cannot be built.
+func (e *FallbackEngine) fallbackEngine(ctx context.Context, match *models.Match, timeSlice time.Duration) EngineV2 {
+	e.mu.Lock()
+	fallback, newFallback := e.fallback, e.newFallback
+	e.newFallback = nil
+	e.mu.Unlock()
+	if fallback != nil || newFallback == nil {
+		return fallback
+	}
+
+	fallback, buildErr := newFallback()
+	if buildErr != nil {
+		e.onFallback(fmt.Sprintf("fallback engine disabled, could not create: %s", buildErr), buildErr)
+		return nil
+	}
+	initCtx, cancelInitCtx := context.WithTimeout(ctx, timeSlice)
+	defer cancelInitCtx()
+	if initErr := fallback.Initialize(initCtx, match); initErr != nil {
+		e.onFallback(fmt.Sprintf("fallback engine disabled, could not init: %s", initErr), initErr)
+		fallback.Terminate()
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fallback = fallback
+	return fallback
+}
+
+func (e *FallbackEngine) setLastMover(engine EngineV2) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastMover = engine
+}
+
+func (e *FallbackEngine) forEachEngine(f func(engine EngineV2)) {
+	e.mu.Lock()
+	fallback := e.fallback
+	e.mu.Unlock()
+	f(e.primary)
+	if fallback != nil {
+		f(fallback)
+	}
+}
+
+func RandomLegalMove(board *chess.Board) (*chess.Move, error) {
+	moves, movesErr := chess.GetLegalMoves(board)
+	if movesErr != nil {
+		return nil, fmt.Errorf("cannot generate move: %s", movesErr)
+	}
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("no legal moves")
+	}
+	return moves[rand.Intn(len(moves))], nil
+}
@@ -0,0 +1,183 @@
+package engines_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/engines"
+	"github.com/CameronHonis/chess-bot-server/uci_client/cmd_client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sync/atomic"
+	"time"
+)
+
+// MockEngine plays a fixed move after a delay, or errors if no move is set
+type MockEngine struct {
+	moveStr     string
+	delay       time.Duration
+	calls       int32
+	isPanicking bool
+	isExiting   bool
+}
+
+func (m *MockEngine) Initialize(match *models.Match) error {
+	return nil
+}
+
+func (m *MockEngine) GenerateMove(match *models.Match) (*chess.Move, error) {
+	atomic.AddInt32(&m.calls, 1)
+	time.Sleep(m.delay)
+	if m.isPanicking {
+		panic("engine bug")
+	}
+	if m.isExiting {
+		return nil, fmt.Errorf("error reading best move: %w", &cmd_client.ExitError{Detail: "exit status 1"})
+	}
+	if m.moveStr == "" {
+		return nil, fmt.Errorf("engine crashed")
+	}
+	return chess.MoveFromLongAlgebraic(m.moveStr, match.Board)
+}
+
+func (m *MockEngine) Terminate() {}
+
+func v2(engine engines.Engine) engines.EngineV2 {
+	engineV2, err := engines.AsV2(engine)
+	Expect(err).ToNot(HaveOccurred())
+	return engineV2
+}
+
+// FallbackTo builds the engine as the fallback, counting how many times it is built
+func FallbackTo(engine engines.Engine, builds *int) func() (engines.EngineV2, error) {
+	return func() (engines.EngineV2, error) {
+		*builds++
+		return v2(engine), nil
+	}
+}
+
+var _ = Describe("FallbackEngine", func() {
+	var match *models.Match
+	var reasons []string
+	var causes []error
+	var builds int
+	onFallback := func(reason string, cause error) {
+		reasons = append(reasons, reason)
+		causes = append(causes, cause)
+	}
+	BeforeEach(func() {
+		reasons = make([]string, 0)
+		causes = make([]error, 0)
+		builds = 0
+		match = builders.NewMatchBuilder().
+			WithTimeControl(&models.TimeControl{InitialTimeSec: 1}).
+			WithTimeRemainingSec(1).
+			Build()
+	})
+	When("the primary engine moves in time", func() {
+		It("plays the primary engine's move", func() {
+			engine := engines.NewFallbackEngine(v2(&MockEngine{moveStr: "e2e4"}), FallbackTo(&MockEngine{moveStr: "d2d4"}, &builds), 0.5, onFallback)
+			move, moveErr := engine.GenerateMove(context.Background(), match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(move.ToLongAlgebraic()).To(Equal("e2e4"))
+			Expect(reasons).To(BeEmpty())
+		})
+		It("never builds the fallback engine", func() {
+			engine := engines.NewFallbackEngine(v2(&MockEngine{moveStr: "e2e4"}), FallbackTo(&MockEngine{moveStr: "d2d4"}, &builds), 0.5, onFallback)
+			Expect(engine.Initialize(context.Background(), match)).To(Succeed())
+			_, _ = engine.GenerateMove(context.Background(), match)
+			Expect(builds).To(BeZero())
+		})
+	})
+	When("the primary engine errors", func() {
+		It("plays the fallback engine's move", func() {
+			engine := engines.NewFallbackEngine(v2(&MockEngine{}), FallbackTo(&MockEngine{moveStr: "d2d4"}, &builds), 0.5, onFallback)
+			move, moveErr := engine.GenerateMove(context.Background(), match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(move.ToLongAlgebraic()).To(Equal("d2d4"))
+			Expect(reasons).To(HaveLen(1))
+		})
+		It("builds the fallback engine only once", func() {
+			engine := engines.NewFallbackEngine(v2(&MockEngine{}), FallbackTo(&MockEngine{moveStr: "d2d4"}, &builds), 0.5, onFallback)
+			_, _ = engine.GenerateMove(context.Background(), match)
+			_, _ = engine.GenerateMove(context.Background(), match)
+			Expect(builds).To(Equal(1))
+		})
+		When("the fallback engine cannot be built", func() {
+			It("plays a random legal move", func() {
+				newFallback := func() (engines.EngineV2, error) {
+					return nil, fmt.Errorf("no such engine")
+				}
+				engine := engines.NewFallbackEngine(v2(&MockEngine{}), newFallback, 0.5, onFallback)
+				move, moveErr := engine.GenerateMove(context.Background(), match)
+				Expect(moveErr).ToNot(HaveOccurred())
+				Expect(chess.IsLegalMove(match.Board, move)).To(BeTrue())
+				Expect(reasons).To(HaveLen(2))
+			})
+		})
+	})
+	When("the primary engine exceeds its time slice", func() {
+		var primary *MockEngine
+		var engine *engines.FallbackEngine
+		BeforeEach(func() {
+			primary = &MockEngine{moveStr: "e2e4", delay: 300 * time.Millisecond}
+			engine = engines.NewFallbackEngine(v2(primary), FallbackTo(&MockEngine{moveStr: "d2d4"}, &builds), 0.1, onFallback)
+		})
+		It("plays the fallback engine's move", func() {
+			move, moveErr := engine.GenerateMove(context.Background(), match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(move.ToLongAlgebraic()).To(Equal("d2d4"))
+		})
+		It("skips the primary engine until its search completes", func() {
+			_, _ = engine.GenerateMove(context.Background(), match)
+			_, _ = engine.GenerateMove(context.Background(), match)
+			Expect(atomic.LoadInt32(&primary.calls)).To(Equal(int32(1)))
+		})
+	})
+	When("the primary engine plays an illegal move", func() {
+		It("falls back", func() {
+			engine := engines.NewFallbackEngine(v2(&MockEngine{moveStr: "e2e5"}), nil, 0.5, onFallback)
+			move, moveErr := engine.GenerateMove(context.Background(), match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(chess.IsLegalMove(match.Board, move)).To(BeTrue())
+			Expect(reasons).To(HaveLen(1))
+		})
+	})
+	When("the primary engine panics", func() {
+		It("reports the crash and falls back", func() {
+			engine := engines.NewFallbackEngine(v2(&MockEngine{isPanicking: true}), FallbackTo(&MockEngine{moveStr: "d2d4"}, &builds), 0.5, onFallback)
+			move, moveErr := engine.GenerateMove(context.Background(), match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(move.ToLongAlgebraic()).To(Equal("d2d4"))
+			Expect(causes).To(HaveLen(1))
+			var crashErr *engines.CrashError
+			Expect(errors.As(causes[0], &crashErr)).To(BeTrue())
+		})
+	})
+	When("the primary engine's process exits", func() {
+		It("reports the crash without asking the engine again, and falls back", func() {
+			primary := &MockEngine{isExiting: true}
+			guarded := engines.NewLegalityGuard(v2(primary), engines.DEFAULT_MOVE_RETRIES, func(*engines.IllegalMoveError) {})
+			engine := engines.NewFallbackEngine(guarded, FallbackTo(&MockEngine{moveStr: "d2d4"}, &builds), 0.5, onFallback)
+			move, moveErr := engine.GenerateMove(context.Background(), match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(move.ToLongAlgebraic()).To(Equal("d2d4"))
+			Expect(atomic.LoadInt32(&primary.calls)).To(Equal(int32(1)))
+			Expect(causes).To(HaveLen(1))
+			var crashErr *engines.CrashError
+			Expect(errors.As(causes[0], &crashErr)).To(BeTrue())
+		})
+	})
+	When("every engine fails", func() {
+		It("plays a random legal move", func() {
+			engine := engines.NewFallbackEngine(v2(&MockEngine{}), FallbackTo(&MockEngine{}, &builds), 0.5, onFallback)
+			move, moveErr := engine.GenerateMove(context.Background(), match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(chess.IsLegalMove(match.Board, move)).To(BeTrue())
+			Expect(reasons).To(HaveLen(2))
+		})
+	})
+})
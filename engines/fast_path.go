@@ -0,0 +1,158 @@
+package engines
+
+import (
+	"context"
+	"fmt"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"sync"
+)
+
+// PVReporter engines expose the principal variation of their last search, starting with the
+// move they played
+type PVReporter interface {
+	LastPV() []string
+}
+
+// FastPathEngine answers moves that need no search. A forced move, the only legal move, is always
+// played at once. With easy moves enabled, if the opponent played the reply the last search
+// expected and the planned answer recaptures the piece that just captured, that answer is
+// played at once too.
+type FastPathEngine struct {
+	engine      EngineV2
+	isEasyMoves bool
+	onFastMove  func(reason string)
+
+	expectedPV []string
+	isLastFast bool
+	mu         sync.Mutex
+}
+
+func NewFastPathEngine(engine EngineV2, isEasyMoves bool, onFastMove func(reason string)) *FastPathEngine {
+	if onFastMove == nil {
+		onFastMove = func(reason string) {}
+	}
+	return &FastPathEngine{
+		engine:      engine,
+		isEasyMoves: isEasyMoves,
+		onFastMove:  onFastMove,
+	}
+}
+
+func (e *FastPathEngine) Initialize(ctx context.Context, match *models.Match) error {
+	e.setExpectedPV(nil)
+	return e.engine.Initialize(ctx, match)
+}
+
+func (e *FastPathEngine) GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error) {
+	legalMoves, legalMovesErr := chess.GetLegalMoves(match.Board)
+	if legalMovesErr != nil {
+		return nil, fmt.Errorf("cannot generate move: %s", legalMovesErr)
+	}
+	if len(legalMoves) == 1 {
+		e.setExpectedPV(nil)
+		e.setIsLastFast(true)
+		e.onFastMove(fmt.Sprintf("forced move %s", legalMoves[0].ToLongAlgebraic()))
+		return legalMoves[0], nil
+	}
+	if e.isEasyMoves {
+		if move := e.easyMove(match, legalMoves); move != nil {
+			e.setIsLastFast(true)
+			e.onFastMove(fmt.Sprintf("easy move %s", move.ToLongAlgebraic()))
+			return move, nil
+		}
+	}
+
+	e.setIsLastFast(false)
+	move, moveErr := e.engine.GenerateMove(ctx, match)
+	if moveErr != nil {
+		e.setExpectedPV(nil)
+		return nil, moveErr
+	}
+	var pv []string
+	if reporter, ok := e.engine.(PVReporter); ok {
+		pv = reporter.LastPV()
+	}
+	if len(pv) == 0 || pv[0] != move.ToLongAlgebraic() {
+		pv = nil
+	}
+	e.setExpectedPV(pv)
+	return move, nil
+}
+
+func (e *FastPathEngine) Terminate() {
+	e.engine.Terminate()
+}
+
+func (e *FastPathEngine) OnOpponentMove(match *models.Match, move *chess.Move) {
+	e.engine.OnOpponentMove(match, move)
+}
+
+func (e *FastPathEngine) OnMatchResult(match *models.Match) {
+	e.engine.OnMatchResult(match)
+}
+
+func (e *FastPathEngine) OnTakeback(match *models.Match) {
+	e.setExpectedPV(nil)
+	e.engine.OnTakeback(match)
+}
+
+func (e *FastPathEngine) OnClockUpdate(match *models.Match) {
+	e.engine.OnClockUpdate(match)
+}
+
+// LastEval is not reported for moves played without searching
+func (e *FastPathEngine) LastEval() (int, bool) {
+	e.mu.Lock()
+	isLastFast := e.isLastFast
+	e.mu.Unlock()
+	if isLastFast {
+		return 0, false
+	}
+	return LastEval(e.engine)
+}
+
+func (e *FastPathEngine) Inner() EngineV2 {
+	return e.engine
+}
+
+// easyMove returns the planned answer to the expected reply, if the opponent played the
+// expected reply by capturing and the planned answer recaptures on the same square
+func (e *FastPathEngine) easyMove(match *models.Match, legalMoves []*chess.Move) *chess.Move {
+	e.mu.Lock()
+	pv := e.expectedPV
+	e.expectedPV = nil
+	e.mu.Unlock()
+
+	lastMove := match.LastMove
+	if len(pv) < 3 || lastMove == nil || lastMove.CapturedPiece == chess.EMPTY {
+		return nil
+	}
+	if lastMove.ToLongAlgebraic() != pv[1] {
+		return nil
+	}
+	for _, move := range legalMoves {
+		if move.ToLongAlgebraic() != pv[2] {
+			continue
+		}
+		if !move.EndSquare.Equal(lastMove.EndSquare) {
+			return nil
+		}
+		// the rest of the line is still expected, so easy moves can chain through a trade
+		e.setExpectedPV(pv[2:])
+		return move
+	}
+	return nil
+}
+
+func (e *FastPathEngine) setIsLastFast(isLastFast bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.isLastFast = isLastFast
+}
+
+func (e *FastPathEngine) setExpectedPV(pv []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expectedPV = pv
+}
@@ -0,0 +1,126 @@
+package engines_test
+
+import (
+	"context"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/engines"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// PVEngine plays the first move of its principal variation
+type PVEngine struct {
+	pv    []string
+	calls int
+}
+
+func (e *PVEngine) Initialize(ctx context.Context, match *models.Match) error {
+	return nil
+}
+
+func (e *PVEngine) GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error) {
+	e.calls++
+	return chess.MoveFromLongAlgebraic(e.pv[0], match.Board)
+}
+
+func (e *PVEngine) Terminate() {}
+
+func (e *PVEngine) OnOpponentMove(match *models.Match, move *chess.Move) {}
+
+func (e *PVEngine) OnMatchResult(match *models.Match) {}
+
+func (e *PVEngine) OnTakeback(match *models.Match) {}
+
+func (e *PVEngine) OnClockUpdate(match *models.Match) {}
+
+func (e *PVEngine) LastPV() []string {
+	return e.pv
+}
+
+func (e *PVEngine) LastEval() (int, bool) {
+	return 25, true
+}
+
+func matchFromFEN(fen string, lastMoveLAlg string) *models.Match {
+	board, boardErr := chess.BoardFromFEN(fen)
+	Expect(boardErr).ToNot(HaveOccurred())
+	match := builders.NewMatchBuilder().WithBoard(board).Build()
+	if lastMoveLAlg != "" {
+		startSquare, _ := chess.SquareFromAlgebraicCoords(lastMoveLAlg[:2])
+		endSquare, _ := chess.SquareFromAlgebraicCoords(lastMoveLAlg[2:4])
+		match.LastMove = &chess.Move{
+			Piece:         board.GetPieceOnSquare(endSquare),
+			StartSquare:   startSquare,
+			EndSquare:     endSquare,
+			CapturedPiece: chess.WHITE_PAWN,
+		}
+	}
+	return match
+}
+
+var _ = Describe("FastPathEngine", func() {
+	When("there is only one legal move", func() {
+		It("plays it without searching", func() {
+			inner := &MockEngine{}
+			engine := engines.NewFastPathEngine(v2(inner), false, nil)
+			match := matchFromFEN("k7/8/8/8/8/8/8/1R5K b - - 0 1", "")
+			move, moveErr := engine.GenerateMove(context.Background(), match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(move.ToLongAlgebraic()).To(Equal("a8a7"))
+			Expect(inner.calls).To(BeZero())
+		})
+		It("reports no eval for the move", func() {
+			engine := engines.NewFastPathEngine(&PVEngine{pv: []string{"e2e4"}}, false, nil)
+			_, moveErr := engine.GenerateMove(context.Background(), matchFromFEN(chess.GetInitBoard().ToFEN(), ""))
+			Expect(moveErr).ToNot(HaveOccurred())
+			eval, isEval := engines.LastEval(engine)
+			Expect(isEval).To(BeTrue())
+			Expect(eval).To(Equal(25))
+
+			_, moveErr = engine.GenerateMove(context.Background(), matchFromFEN("k7/8/8/8/8/8/8/1R5K b - - 0 1", ""))
+			Expect(moveErr).ToNot(HaveOccurred())
+			_, isEval = engines.LastEval(engine)
+			Expect(isEval).To(BeFalse())
+		})
+	})
+	Describe("easy moves", func() {
+		var inner *PVEngine
+		var beforeTrade, afterCapture *models.Match
+		BeforeEach(func() {
+			inner = &PVEngine{pv: []string{"e4d5", "d8d5", "c3d5"}}
+			beforeTrade = matchFromFEN("3qk3/8/8/3p4/4P3/2N5/8/4K3 w - - 0 1", "")
+			afterCapture = matchFromFEN("4k3/8/8/3q4/8/2N5/8/4K3 w - - 0 2", "d8d5")
+		})
+		When("enabled", func() {
+			When("the opponent recaptures as expected", func() {
+				It("plays the planned recapture without searching", func() {
+					engine := engines.NewFastPathEngine(inner, true, nil)
+					Expect(engine.GenerateMove(context.Background(), beforeTrade)).Error().ToNot(HaveOccurred())
+					move, moveErr := engine.GenerateMove(context.Background(), afterCapture)
+					Expect(moveErr).ToNot(HaveOccurred())
+					Expect(move.ToLongAlgebraic()).To(Equal("c3d5"))
+					Expect(inner.calls).To(Equal(1))
+				})
+			})
+			When("the opponent plays something else", func() {
+				It("searches", func() {
+					engine := engines.NewFastPathEngine(inner, true, nil)
+					Expect(engine.GenerateMove(context.Background(), beforeTrade)).Error().ToNot(HaveOccurred())
+					otherReply := matchFromFEN("3qk3/8/8/3P4/8/2N5/8/5K2 w - - 0 2", "e1f1")
+					_, _ = engine.GenerateMove(context.Background(), otherReply)
+					Expect(inner.calls).To(Equal(2))
+				})
+			})
+		})
+		When("disabled", func() {
+			It("searches", func() {
+				engine := engines.NewFastPathEngine(inner, false, nil)
+				Expect(engine.GenerateMove(context.Background(), beforeTrade)).Error().ToNot(HaveOccurred())
+				_, _ = engine.GenerateMove(context.Background(), afterCapture)
+				Expect(inner.calls).To(Equal(2))
+			})
+		})
+	})
+})
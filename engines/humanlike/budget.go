@@ -0,0 +1,60 @@
+package humanlike
+
+import (
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"time"
+)
+
+const (
+	MOVES_TO_GO_GUESS  = 30
+	CLOCK_SAFETY_RATIO = 0.1
+	// MIN_DEPTH_SEARCH_TIME is roughly how long a MultiPV search to MIN_DEPTH takes. Each further
+	// ply is assumed to double it.
+	MIN_DEPTH_SEARCH_TIME = 10 * time.Millisecond
+)
+
+// SearchTime budgets a move from the clock of the side to move: a share of the time left plus
+// most of the increment, but never more than a third of the time left beyond a safety margin.
+// Untimed matches have no budget, so it returns 0.
+func SearchTime(match *models.Match) time.Duration {
+	secsRemaining := match.BlackTimeRemainingSec
+	if match.Board.IsWhiteTurn {
+		secsRemaining = match.WhiteTimeRemainingSec
+	}
+	if secsRemaining <= 0 {
+		return 0
+	}
+	var incrSecs float64
+	if match.TimeControl != nil {
+		incrSecs = float64(match.TimeControl.IncrementSec)
+	}
+
+	budgetSecs := secsRemaining/MOVES_TO_GO_GUESS + incrSecs*0.8
+	maxSecs := secsRemaining * (1 - CLOCK_SAFETY_RATIO) / 3
+	if budgetSecs > maxSecs {
+		budgetSecs = maxSecs
+	}
+	return time.Duration(budgetSecs * float64(time.Second))
+}
+
+// DepthForBudget is the deepest search expected to finish within the budget. A budget of 0
+// places no limit beyond MAX_DEPTH.
+func DepthForBudget(budget time.Duration) uint {
+	if budget <= 0 {
+		return MAX_DEPTH
+	}
+	depth := uint(MIN_DEPTH)
+	for searchTime := MIN_DEPTH_SEARCH_TIME; searchTime*2 <= budget && depth < MAX_DEPTH; searchTime *= 2 {
+		depth++
+	}
+	return depth
+}
+
+// SearchDepth is the depth for the target rating, capped by what the budget allows
+func SearchDepth(rating uint, budget time.Duration) uint {
+	depth := DepthForRating(rating)
+	if budgetDepth := DepthForBudget(budget); budgetDepth < depth {
+		return budgetDepth
+	}
+	return depth
+}
@@ -0,0 +1,49 @@
+package humanlike_test
+
+import (
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/engines/humanlike"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+func MatchWithClock(secsRemaining float64, incrSecs int64) *models.Match {
+	match := builders.NewMatchBuilder().Build()
+	match.TimeControl = &models.TimeControl{InitialTimeSec: 300, IncrementSec: incrSecs}
+	match.WhiteTimeRemainingSec = secsRemaining
+	match.BlackTimeRemainingSec = secsRemaining
+	return match
+}
+
+var _ = Describe("Budget", func() {
+	Describe("SearchTime", func() {
+		It("has no budget for untimed matches", func() {
+			match := MatchWithClock(0, 0)
+			match.TimeControl = nil
+			Expect(humanlike.SearchTime(match)).To(BeZero())
+		})
+		It("spends a share of the remaining time", func() {
+			Expect(humanlike.SearchTime(MatchWithClock(300, 0))).To(Equal(10 * time.Second))
+		})
+		It("spends most of the increment", func() {
+			Expect(humanlike.SearchTime(MatchWithClock(300, 5))).To(Equal(14 * time.Second))
+		})
+		It("never spends more than a third of the time left", func() {
+			Expect(humanlike.SearchTime(MatchWithClock(3, 10))).To(Equal(900 * time.Millisecond))
+		})
+	})
+	Describe("SearchDepth", func() {
+		It("keeps the rating's depth when the budget allows it", func() {
+			Expect(humanlike.SearchDepth(1200, time.Minute)).To(Equal(humanlike.DepthForRating(1200)))
+		})
+		It("keeps the rating's depth in untimed matches", func() {
+			Expect(humanlike.SearchDepth(3000, 0)).To(Equal(humanlike.DepthForRating(3000)))
+		})
+		It("is capped by a short budget", func() {
+			Expect(humanlike.SearchDepth(3000, 100*time.Millisecond)).To(BeNumerically("<", humanlike.MAX_DEPTH))
+			Expect(humanlike.SearchDepth(3000, time.Millisecond)).To(Equal(uint(humanlike.MIN_DEPTH)))
+		})
+	})
+})
@@ -0,0 +1,179 @@
+package humanlike
+
+import (
+	"context"
+	"fmt"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/uci_client"
+	"github.com/CameronHonis/chess-bot-server/uci_client/cmd_client"
+	"math/rand"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const (
+	DEFAULT_RATING     = 1200
+	DEFAULT_CANDIDATES = 5
+)
+
+// Engine weakens an underlying UCI engine by asking for its top candidate moves and sampling
+// one of them, so mistakes are plausible moves rather than random ones. It implements
+// engines.EngineV2 natively, so a cancelled search is stopped with the UCI stop command.
+type Engine struct {
+	cmd    *exec.Cmd
+	client *uci_client.Client
+
+	targetRating uint
+	temperature  float64
+	candidates   uint
+	rng          *rand.Rand
+}
+
+// NewEngine wraps the UCI engine started by cmd. A temperature of 0 derives the temperature
+// from the target rating.
+func NewEngine(cmd *exec.Cmd, targetRating uint, temperature float64, candidates uint) (*Engine, error) {
+	cmdClient, cmdClientErr := cmd_client.ClientFromCmd(cmd)
+	if cmdClientErr != nil {
+		return nil, fmt.Errorf("could not construct CmdClient: %s", cmdClientErr)
+	}
+	if temperature <= 0 {
+		temperature = TemperatureForRating(targetRating)
+	}
+	if candidates == 0 {
+		candidates = DEFAULT_CANDIDATES
+	}
+
+	return &Engine{
+		cmd:          cmd,
+		client:       uci_client.NewUciClient(cmdClient),
+		targetRating: targetRating,
+		temperature:  temperature,
+		candidates:   candidates,
+		rng:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+func (e *Engine) Initialize(ctx context.Context, match *models.Match) error {
+	ctx, cancelCtx := context.WithTimeout(ctx, time.Second)
+	defer cancelCtx()
+
+	startErr := e.cmd.Start()
+	if startErr != nil {
+		return fmt.Errorf("could not start engine: %s", startErr)
+	}
+
+	_, readErr := e.client.CmdClient.ReadLine(ctx)
+	if readErr != nil {
+		return fmt.Errorf("could not read startup msg: %s", readErr)
+	}
+
+	_, initErr := e.client.Init(ctx)
+	if initErr != nil {
+		return initErr
+	}
+
+	if !e.client.IsOption("MultiPV") {
+		return fmt.Errorf("engine does not support option 'MultiPV'")
+	}
+	optErr := e.SetOption(ctx, "MultiPV", strconv.Itoa(int(e.candidates)))
+	if optErr != nil {
+		return fmt.Errorf("error setting option 'MultiPV' to %d: %s", e.candidates, optErr)
+	}
+
+	return nil
+}
+
+func (e *Engine) GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error) {
+	// the clock lets the engine's own time management stop the search before the depth is reached
+	var incrMs uint
+	if match.TimeControl != nil {
+		incrMs = uint(match.TimeControl.IncrementSec * 1000)
+	}
+	searchOpts := uci_client.NewSearchOptionsBuilder().
+		WithDepth(SearchDepth(e.targetRating, SearchTime(match))).
+		WithWhiteMs(uint(match.WhiteTimeRemainingSec * 1000.)).
+		WithBlackMs(uint(match.BlackTimeRemainingSec * 1000.)).
+		WithWhiteIncrMs(incrMs).
+		WithBlackIncrMs(incrMs).
+		Build()
+	setPosErr := e.client.SetPosition(match.Board.ToFEN())
+	if setPosErr != nil {
+		return nil, fmt.Errorf("could not set position: %w", setPosErr)
+	}
+
+	readyCtx, cancelCtx := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancelCtx()
+	for {
+		isReady, isReadyErr := e.client.IsReady(readyCtx)
+		if isReadyErr != nil {
+			return nil, fmt.Errorf("could not read ready state of engine: %w", isReadyErr)
+		}
+		if isReady {
+			break
+		}
+	}
+
+	var secsRemaining float64
+	if match.Board.IsWhiteTurn {
+		secsRemaining = match.WhiteTimeRemainingSec
+	} else {
+		secsRemaining = match.BlackTimeRemainingSec
+	}
+	genMoveCtx, cancelGenMoveCtx := context.WithTimeout(ctx, time.Duration(secsRemaining+1)*time.Second)
+	defer cancelGenMoveCtx()
+
+	result, searchErr := e.client.Search(genMoveCtx, searchOpts)
+	if searchErr != nil {
+		return nil, fmt.Errorf("error reading best move: %w", searchErr)
+	}
+
+	moveLAlg := result.BestMove
+	if candidate := SampleCandidate(result.Candidates(), e.temperature, e.rng); candidate != nil {
+		moveLAlg = candidate.PV[0]
+	}
+	move, moveConvertErr := chess.MoveFromLongAlgebraic(moveLAlg, match.Board)
+	if moveConvertErr != nil {
+		return nil, fmt.Errorf("could not convert %s to move: %s", moveLAlg, moveConvertErr)
+	}
+	return move, nil
+}
+
+func (e *Engine) Terminate() {
+	if endErr := e.client.End(); endErr != nil {
+		fmt.Println("WARN: could not end client: ", endErr)
+	}
+}
+
+func (e *Engine) OnOpponentMove(match *models.Match, move *chess.Move) {}
+
+func (e *Engine) OnMatchResult(match *models.Match) {}
+
+func (e *Engine) OnTakeback(match *models.Match) {}
+
+func (e *Engine) OnClockUpdate(match *models.Match) {}
+
+func (e *Engine) SetOption(ctx context.Context, optName, optValue string) error {
+	return e.client.SetOption(ctx, optName, optValue)
+}
+
+func (e *Engine) TargetRating() uint {
+	return e.targetRating
+}
+
+func (e *Engine) Temperature() float64 {
+	return e.temperature
+}
+
+func (e *Engine) Identity() string {
+	name := e.client.EngineName()
+	if name == "" {
+		name = "uci engine"
+	}
+	return fmt.Sprintf("humanlike %d (%s)", e.targetRating, name)
+}
+
+func (e *Engine) Transcript() []string {
+	return e.client.CmdClient.Transcript()
+}
@@ -0,0 +1,13 @@
+package humanlike_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestHumanlike(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Humanlike Suite")
+}
@@ -0,0 +1,68 @@
+package humanlike
+
+import (
+	"github.com/CameronHonis/chess-bot-server/uci_client"
+	"math"
+	"math/rand"
+)
+
+const (
+	MIN_TEMPERATURE = 5.
+	MAX_TEMPERATURE = 300.
+	MIN_DEPTH       = 4
+	MAX_DEPTH       = 16
+)
+
+// TemperatureForRating maps a target rating to a sampling temperature in centipawns. At a
+// temperature of T, a move that is T centipawns worse than the best move is e times less
+// likely to be played.
+func TemperatureForRating(rating uint) float64 {
+	temperature := (2800. - float64(rating)) / 8.
+	return math.Max(MIN_TEMPERATURE, math.Min(MAX_TEMPERATURE, temperature))
+}
+
+// DepthForRating keeps weaker bots from seeing far enough to refute their own mistakes
+func DepthForRating(rating uint) uint {
+	depth := 1 + rating/250
+	if depth < MIN_DEPTH {
+		return MIN_DEPTH
+	}
+	if depth > MAX_DEPTH {
+		return MAX_DEPTH
+	}
+	return depth
+}
+
+// SampleCandidate picks one of the candidates with a probability given by the softmax of their
+// scores at the given temperature
+func SampleCandidate(candidates []*uci_client.Info, temperature float64, rng *rand.Rand) *uci_client.Info {
+	if len(candidates) == 0 {
+		return nil
+	}
+	if temperature <= 0 {
+		temperature = MIN_TEMPERATURE
+	}
+
+	bestCp := candidates[0].Cp()
+	for _, candidate := range candidates {
+		if candidate.Cp() > bestCp {
+			bestCp = candidate.Cp()
+		}
+	}
+
+	weights := make([]float64, len(candidates))
+	var weightSum float64
+	for i, candidate := range candidates {
+		weights[i] = math.Exp(float64(candidate.Cp()-bestCp) / temperature)
+		weightSum += weights[i]
+	}
+
+	roll := rng.Float64() * weightSum
+	for i, weight := range weights {
+		roll -= weight
+		if roll <= 0 {
+			return candidates[i]
+		}
+	}
+	return candidates[len(candidates)-1]
+}
@@ -0,0 +1,65 @@
+package humanlike_test
+
+import (
+	"github.com/CameronHonis/chess-bot-server/engines/humanlike"
+	"github.com/CameronHonis/chess-bot-server/uci_client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"math/rand"
+)
+
+func Candidate(move string, cp int) *uci_client.Info {
+	return &uci_client.Info{ScoreCp: cp, PV: []string{move}}
+}
+
+func PickCounts(candidates []*uci_client.Info, temperature float64, samples int) map[string]int {
+	rng := rand.New(rand.NewSource(1))
+	countByMove := make(map[string]int)
+	for i := 0; i < samples; i++ {
+		pick := humanlike.SampleCandidate(candidates, temperature, rng)
+		countByMove[pick.PV[0]]++
+	}
+	return countByMove
+}
+
+var _ = Describe("Sampling", func() {
+	Describe("TemperatureForRating", func() {
+		It("is higher for lower ratings", func() {
+			Expect(humanlike.TemperatureForRating(800)).To(BeNumerically(">", humanlike.TemperatureForRating(2000)))
+		})
+		It("is clamped", func() {
+			Expect(humanlike.TemperatureForRating(0)).To(Equal(humanlike.MAX_TEMPERATURE))
+			Expect(humanlike.TemperatureForRating(4000)).To(Equal(humanlike.MIN_TEMPERATURE))
+		})
+	})
+	Describe("SampleCandidate", func() {
+		var candidates []*uci_client.Info
+		BeforeEach(func() {
+			candidates = []*uci_client.Info{
+				Candidate("e2e4", 40),
+				Candidate("d2d4", 30),
+				Candidate("g2g4", -150),
+			}
+		})
+		When("there are no candidates", func() {
+			It("returns nil", func() {
+				Expect(humanlike.SampleCandidate(nil, 100, rand.New(rand.NewSource(1)))).To(BeNil())
+			})
+		})
+		When("the temperature is low", func() {
+			It("almost always picks the best move", func() {
+				counts := PickCounts(candidates, humanlike.MIN_TEMPERATURE, 1000)
+				Expect(counts["e2e4"]).To(BeNumerically(">", 850))
+				Expect(counts["g2g4"]).To(BeZero())
+			})
+		})
+		When("the temperature is high", func() {
+			It("sometimes picks worse moves", func() {
+				counts := PickCounts(candidates, humanlike.MAX_TEMPERATURE, 1000)
+				Expect(counts["d2d4"]).To(BeNumerically(">", 200))
+				Expect(counts["g2g4"]).To(BeNumerically(">", 50))
+				Expect(counts["e2e4"]).To(BeNumerically(">", counts["g2g4"]))
+			})
+		})
+	})
+})
@@ -0,0 +1,164 @@
+package engines
+
+import (
+	"context"
+	"fmt"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"strings"
+)
+
+const DEFAULT_MOVE_RETRIES = 1
+
+// Diagnosable engines expose who they are and their recent protocol io, which is recorded
+// whenever they produce a bad move
+type Diagnosable interface {
+	Identity() string
+	Transcript() []string
+}
+
+// IllegalMoveError is the diagnostic recorded when an engine produces an illegal or unparseable
+// move
+type IllegalMoveError struct {
+	Identity   string
+	FEN        string
+	Move       string
+	Cause      error
+	Transcript []string
+}
+
+func (e *IllegalMoveError) Error() string {
+	var reason string
+	if e.Cause != nil {
+		reason = e.Cause.Error()
+	} else {
+		reason = fmt.Sprintf("illegal move %s", e.Move)
+	}
+	return fmt.Sprintf("%s produced a bad move at %s: %s", e.Identity, e.FEN, reason)
+}
+
+func (e *IllegalMoveError) Unwrap() error {
+	return e.Cause
+}
+
+// Details formats the diagnostic, including the transcript, for logging
+func (e *IllegalMoveError) Details() string {
+	var sb strings.Builder
+	sb.WriteString(e.Error())
+	if len(e.Transcript) > 0 {
+		sb.WriteString("\ntranscript:\n")
+		sb.WriteString(strings.Join(e.Transcript, "\n"))
+	}
+	return sb.String()
+}
+
+// LegalityGuard checks every move against the legal moves in the position. When the engine
+// produces an illegal or unparseable move, it is asked again for up to maxRetries times before
+// the diagnostic is returned as an error.
+type LegalityGuard struct {
+	engine     EngineV2
+	maxRetries uint
+	onBadMove  func(diagnostic *IllegalMoveError)
+}
+
+func NewLegalityGuard(engine EngineV2, maxRetries uint, onBadMove func(diagnostic *IllegalMoveError)) *LegalityGuard {
+	if onBadMove == nil {
+		onBadMove = func(diagnostic *IllegalMoveError) {}
+	}
+	return &LegalityGuard{
+		engine:     engine,
+		maxRetries: maxRetries,
+		onBadMove:  onBadMove,
+	}
+}
+
+func (g *LegalityGuard) Initialize(ctx context.Context, match *models.Match) error {
+	return g.engine.Initialize(ctx, match)
+}
+
+func (g *LegalityGuard) GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error) {
+	legalMoves, legalMovesErr := chess.GetLegalMoves(match.Board)
+	if legalMovesErr != nil {
+		return nil, fmt.Errorf("cannot generate move: %s", legalMovesErr)
+	}
+
+	var diagnostic *IllegalMoveError
+	for attempt := uint(0); attempt <= g.maxRetries; attempt++ {
+		move, moveErr := g.engine.GenerateMove(ctx, match)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("move generation cancelled: %s", ctx.Err())
+		}
+		if moveErr == nil && isMoveIn(move, legalMoves) {
+			return move, nil
+		}
+		if crashErr, isCrash := crashFromExit(moveErr); isCrash {
+			// an engine that has exited cannot be asked again
+			g.onBadMove(g.diagnose(match, move, moveErr))
+			return nil, crashErr
+		}
+
+		diagnostic = g.diagnose(match, move, moveErr)
+		g.onBadMove(diagnostic)
+	}
+	return nil, diagnostic
+}
+
+func (g *LegalityGuard) Terminate() {
+	g.engine.Terminate()
+}
+
+func (g *LegalityGuard) OnOpponentMove(match *models.Match, move *chess.Move) {
+	g.engine.OnOpponentMove(match, move)
+}
+
+func (g *LegalityGuard) OnMatchResult(match *models.Match) {
+	g.engine.OnMatchResult(match)
+}
+
+func (g *LegalityGuard) OnTakeback(match *models.Match) {
+	g.engine.OnTakeback(match)
+}
+
+func (g *LegalityGuard) OnClockUpdate(match *models.Match) {
+	g.engine.OnClockUpdate(match)
+}
+
+func (g *LegalityGuard) LastPV() []string {
+	if reporter, ok := Unwrap(g.engine).(PVReporter); ok {
+		return reporter.LastPV()
+	}
+	return nil
+}
+
+func (g *LegalityGuard) Inner() EngineV2 {
+	return g.engine
+}
+
+func (g *LegalityGuard) diagnose(match *models.Match, move *chess.Move, moveErr error) *IllegalMoveError {
+	diagnostic := &IllegalMoveError{
+		Identity: fmt.Sprintf("%T", Unwrap(g.engine)),
+		FEN:      match.Board.ToFEN(),
+		Cause:    moveErr,
+	}
+	if move != nil {
+		diagnostic.Move = move.ToLongAlgebraic()
+	}
+	if diagnosable, ok := Unwrap(g.engine).(Diagnosable); ok {
+		diagnostic.Identity = diagnosable.Identity()
+		diagnostic.Transcript = diagnosable.Transcript()
+	}
+	return diagnostic
+}
+
+func isMoveIn(move *chess.Move, moves []*chess.Move) bool {
+	if move == nil {
+		return false
+	}
+	moveLAlg := move.ToLongAlgebraic()
+	for _, legalMove := range moves {
+		if legalMove.ToLongAlgebraic() == moveLAlg {
+			return true
+		}
+	}
+	return false
+}
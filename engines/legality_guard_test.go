@@ -0,0 +1,98 @@
+package engines_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/engines"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// ScriptedEngine plays the scripted moves in order, regardless of legality. An empty string
+// makes the engine error instead.
+type ScriptedEngine struct {
+	moveStrs []string
+	calls    int
+}
+
+func (s *ScriptedEngine) Initialize(match *models.Match) error {
+	return nil
+}
+
+func (s *ScriptedEngine) GenerateMove(match *models.Match) (*chess.Move, error) {
+	moveStr := s.moveStrs[s.calls%len(s.moveStrs)]
+	s.calls++
+	if moveStr == "" {
+		return nil, fmt.Errorf("could not parse bestmove")
+	}
+	startSquare, _ := chess.SquareFromAlgebraicCoords(moveStr[:2])
+	endSquare, _ := chess.SquareFromAlgebraicCoords(moveStr[2:4])
+	piece := match.Board.GetPieceOnSquare(startSquare)
+	return &chess.Move{Piece: piece, StartSquare: startSquare, EndSquare: endSquare}, nil
+}
+
+func (s *ScriptedEngine) Terminate() {}
+
+func (s *ScriptedEngine) Identity() string {
+	return "Scripted 1.0"
+}
+
+func (s *ScriptedEngine) Transcript() []string {
+	return []string{"< go", "> bestmove " + s.moveStrs[0]}
+}
+
+var _ = Describe("LegalityGuard", func() {
+	var match *models.Match
+	var diagnostics []*engines.IllegalMoveError
+	onBadMove := func(diagnostic *engines.IllegalMoveError) {
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	BeforeEach(func() {
+		diagnostics = make([]*engines.IllegalMoveError, 0)
+		match = builders.NewMatchBuilder().Build()
+	})
+	When("the engine plays a legal move", func() {
+		It("returns the move", func() {
+			guard := engines.NewLegalityGuard(v2(&ScriptedEngine{moveStrs: []string{"e2e4"}}), 1, onBadMove)
+			move, moveErr := guard.GenerateMove(context.Background(), match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(move.ToLongAlgebraic()).To(Equal("e2e4"))
+			Expect(diagnostics).To(BeEmpty())
+		})
+	})
+	When("the engine plays an illegal move and then a legal move", func() {
+		It("re-queries the engine and returns the legal move", func() {
+			engine := &ScriptedEngine{moveStrs: []string{"e2e5", "d2d4"}}
+			guard := engines.NewLegalityGuard(v2(engine), 1, onBadMove)
+			move, moveErr := guard.GenerateMove(context.Background(), match)
+			Expect(moveErr).ToNot(HaveOccurred())
+			Expect(move.ToLongAlgebraic()).To(Equal("d2d4"))
+			Expect(engine.calls).To(Equal(2))
+		})
+		It("records a diagnostic for the illegal move", func() {
+			guard := engines.NewLegalityGuard(v2(&ScriptedEngine{moveStrs: []string{"e2e5", "d2d4"}}), 1, onBadMove)
+			_, _ = guard.GenerateMove(context.Background(), match)
+			Expect(diagnostics).To(HaveLen(1))
+			Expect(diagnostics[0].Identity).To(Equal("Scripted 1.0"))
+			Expect(diagnostics[0].Move).To(Equal("e2e5"))
+			Expect(diagnostics[0].FEN).To(Equal(match.Board.ToFEN()))
+			Expect(diagnostics[0].Transcript).To(ContainElement("> bestmove e2e5"))
+		})
+	})
+	When("the engine never plays a legal move", func() {
+		It("returns the diagnostic as an error", func() {
+			guard := engines.NewLegalityGuard(v2(&ScriptedEngine{moveStrs: []string{""}}), 1, onBadMove)
+			_, moveErr := guard.GenerateMove(context.Background(), match)
+			Expect(moveErr).To(BeAssignableToTypeOf(&engines.IllegalMoveError{}))
+			Expect(diagnostics).To(HaveLen(2))
+		})
+	})
+	It("can be unwrapped to the guarded engine", func() {
+		engine := &ScriptedEngine{moveStrs: []string{"e2e4"}}
+		guard := engines.NewLegalityGuard(v2(engine), 1, onBadMove)
+		Expect(engines.Unwrap(guard)).To(BeIdenticalTo(engine))
+	})
+})
@@ -8,12 +8,24 @@ import (
 	"github.com/CameronHonis/chess-bot-server/uci_client"
 	"github.com/CameronHonis/chess-bot-server/uci_client/cmd_client"
 	"os/exec"
+	"strconv"
+	"sync"
 	"time"
 )
 
+// Engine drives a Mila binary over UCI. It implements engines.EngineV2 natively, so a cancelled
+// search is stopped with the UCI stop command instead of being left running.
 type Engine struct {
 	cmd    *exec.Cmd
 	client *uci_client.Client
+
+	threads        uint
+	hashMb         uint
+	appliedThreads uint
+	appliedHashMb  uint
+	lastPV         []string
+	lastInfo       *uci_client.Info
+	mu             sync.Mutex
 }
 
 func NewEngine(cmd *exec.Cmd) (*Engine, error) {
@@ -28,8 +40,8 @@ func NewEngine(cmd *exec.Cmd) (*Engine, error) {
 	}, nil
 }
 
-func (e *Engine) Initialize(match *models.Match) error {
-	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Second)
+func (e *Engine) Initialize(ctx context.Context, match *models.Match) error {
+	ctx, cancelCtx := context.WithTimeout(ctx, time.Second)
 	defer cancelCtx()
 
 	startErr := e.cmd.Start()
@@ -47,30 +59,28 @@ func (e *Engine) Initialize(match *models.Match) error {
 		return initErr
 	}
 
-	optErr := e.SetOption(ctx, "Threads", "32")
-	if optErr != nil {
-		return fmt.Errorf("error setting option 'Threads' to 32: %s", optErr)
-	}
-
-	return nil
+	return e.applyResources(ctx)
 }
 
-func (e *Engine) GenerateMove(match *models.Match) (*chess.Move, error) {
+func (e *Engine) GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error) {
 	searchOpts := uci_client.NewSearchOptionsBuilder().
 		WithWhiteMs(uint(match.WhiteTimeRemainingSec * 1000.)).
 		WithBlackMs(uint(match.BlackTimeRemainingSec * 1000.)).
 		Build()
+	if applyErr := e.applyResources(ctx); applyErr != nil {
+		return nil, applyErr
+	}
 	setPosErr := e.client.SetPosition(match.Board.ToFEN())
 	if setPosErr != nil {
-		return nil, fmt.Errorf("could not set position: %s", setPosErr)
+		return nil, fmt.Errorf("could not set position: %w", setPosErr)
 	}
 
-	readyCtx, cancelCtx := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	readyCtx, cancelCtx := context.WithTimeout(ctx, 100*time.Millisecond)
 	defer cancelCtx()
 	for {
 		isReady, isReadyErr := e.client.IsReady(readyCtx)
 		if isReadyErr != nil {
-			return nil, fmt.Errorf("could not read ready state of engine: %s", isReadyErr)
+			return nil, fmt.Errorf("could not read ready state of engine: %w", isReadyErr)
 		}
 		if isReady {
 			break
@@ -83,16 +93,22 @@ func (e *Engine) GenerateMove(match *models.Match) (*chess.Move, error) {
 	} else {
 		secsRemaining = match.BlackTimeRemainingSec
 	}
-	genMoveCtx, cancelGenMoveCtx := context.WithTimeout(context.Background(), time.Duration(secsRemaining+1)*time.Second)
+	genMoveCtx, cancelGenMoveCtx := context.WithTimeout(ctx, time.Duration(secsRemaining+1)*time.Second)
 	defer cancelGenMoveCtx()
 
-	bestMoveLAlg, searchErr := e.client.Go(genMoveCtx, searchOpts)
+	result, searchErr := e.client.Search(genMoveCtx, searchOpts)
 	if searchErr != nil {
-		return nil, fmt.Errorf("error reading best move: %s", searchErr)
+		return nil, fmt.Errorf("error reading best move: %w", searchErr)
 	}
+	e.mu.Lock()
+	e.lastPV = result.PrincipalVariation()
+	e.lastInfo = result.InfoByMultiPV[1]
+	e.mu.Unlock()
+
+	bestMoveLAlg := result.BestMove
 	bestMove, moveConvertErr := chess.MoveFromAlgebraic(bestMoveLAlg, match.Board)
 	if moveConvertErr != nil {
-		return nil, fmt.Errorf("could not convert to move: %s", bestMove)
+		return nil, fmt.Errorf("could not convert %s to move: %s", bestMoveLAlg, moveConvertErr)
 	}
 	return bestMove, nil
 }
@@ -103,6 +119,74 @@ func (e *Engine) Terminate() {
 	}
 }
 
+func (e *Engine) OnOpponentMove(match *models.Match, move *chess.Move) {}
+
+func (e *Engine) OnMatchResult(match *models.Match) {}
+
+func (e *Engine) OnTakeback(match *models.Match) {}
+
+func (e *Engine) OnClockUpdate(match *models.Match) {}
+
+// Identity is the name the engine reported during initialization
+func (e *Engine) Identity() string {
+	if name := e.client.EngineName(); name != "" {
+		return name
+	}
+	return "mila"
+}
+
+func (e *Engine) Transcript() []string {
+	return e.client.CmdClient.Transcript()
+}
+
+// LastPV is the principal variation of the last search, starting with the move played
+func (e *Engine) LastPV() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastPV
+}
+
+// LastEval is the score of the last search's principal variation
+func (e *Engine) LastEval() (int, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lastInfo == nil {
+		return 0, false
+	}
+	return e.lastInfo.Cp(), true
+}
+
 func (e *Engine) SetOption(ctx context.Context, optName, optValue string) error {
 	return e.client.SetOption(ctx, optName, optValue)
 }
+
+// SetResources stages the thread and hash allocation, which is applied before the next search
+func (e *Engine) SetResources(threads uint, hashMb uint) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.threads = threads
+	e.hashMb = hashMb
+}
+
+func (e *Engine) applyResources(ctx context.Context) error {
+	e.mu.Lock()
+	threads, hashMb := e.threads, e.hashMb
+	e.mu.Unlock()
+
+	if threads != 0 && threads != e.appliedThreads && e.client.IsOption("Threads") {
+		optErr := e.SetOption(ctx, "Threads", strconv.Itoa(int(threads)))
+		if optErr != nil {
+			return fmt.Errorf("error setting option 'Threads' to %d: %s", threads, optErr)
+		}
+		e.appliedThreads = threads
+	}
+	if hashMb != 0 && hashMb != e.appliedHashMb && e.client.IsOption("Hash") {
+		optErr := e.SetOption(ctx, "Hash", strconv.Itoa(int(hashMb)))
+		if optErr != nil {
+			return fmt.Errorf("error setting option 'Hash' to %d: %s", hashMb, optErr)
+		}
+		e.appliedHashMb = hashMb
+	}
+	return nil
+}
+
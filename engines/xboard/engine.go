@@ -0,0 +1,204 @@
+package xboard
+
+import (
+	"context"
+	"fmt"
+	"github.com/CameronHonis/chess"
+	"github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/xboard_client"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// INTERRUPT_TIMEOUT bounds how long a cancelled search waits for the engine's move
+const INTERRUPT_TIMEOUT = time.Second
+
+// Engine adapts an engine speaking the XBoard protocol. It implements engines.EngineV2 natively.
+// The engine is kept in force mode between moves. The opponent's moves are passed on as user
+// moves, so the engine sees the game's history. The board is set up from scratch whenever the
+// engine's board has fallen out of step with the match, such as after a takeback.
+type Engine struct {
+	cmd    *exec.Cmd
+	client *xboard_client.Client
+	// board is the position on the engine's board, or nil if it is not known
+	board *chess.Board
+}
+
+func NewEngine(cmd *exec.Cmd) (*Engine, error) {
+	client, clientErr := xboard_client.XboardClientFromCmd(cmd)
+	if clientErr != nil {
+		return nil, fmt.Errorf("could not construct XboardClient: %s", clientErr)
+	}
+
+	return &Engine{
+		cmd:    cmd,
+		client: client,
+	}, nil
+}
+
+func (e *Engine) Initialize(ctx context.Context, match *models.Match) error {
+	startErr := e.cmd.Start()
+	if startErr != nil {
+		return fmt.Errorf("could not start xboard engine: %s", startErr)
+	}
+
+	initCtx, cancelInitCtx := context.WithTimeout(ctx, 2*time.Second)
+	defer cancelInitCtx()
+	if _, initErr := e.client.Init(initCtx); initErr != nil {
+		return initErr
+	}
+	if e.client.Feature("setboard") != "1" {
+		return fmt.Errorf("xboard engine does not support setboard")
+	}
+
+	if newErr := e.client.New(); newErr != nil {
+		return newErr
+	}
+	if forceErr := e.client.Force(); forceErr != nil {
+		return forceErr
+	}
+	if match.TimeControl != nil {
+		levelErr := e.client.Level(0, uint(match.TimeControl.InitialTimeSec), uint(match.TimeControl.IncrementSec))
+		if levelErr != nil {
+			return levelErr
+		}
+	}
+
+	pingCtx, cancelPingCtx := context.WithTimeout(ctx, time.Second)
+	defer cancelPingCtx()
+	return e.client.Ping(pingCtx)
+}
+
+// GenerateMove has the engine play the side to move. A cancelled search is interrupted, so the
+// engine moves at once and goes back to force mode.
+func (e *Engine) GenerateMove(ctx context.Context, match *models.Match) (*chess.Move, error) {
+	if e.board == nil || e.board.ToFEN() != match.Board.ToFEN() {
+		e.board = nil
+		if setBoardErr := e.client.SetBoard(match.Board.ToFEN()); setBoardErr != nil {
+			return nil, fmt.Errorf("could not set board: %w", setBoardErr)
+		}
+		e.board = match.Board
+	}
+
+	var secsRemaining, oppSecsRemaining float64
+	if match.Board.IsWhiteTurn {
+		secsRemaining, oppSecsRemaining = match.WhiteTimeRemainingSec, match.BlackTimeRemainingSec
+	} else {
+		secsRemaining, oppSecsRemaining = match.BlackTimeRemainingSec, match.WhiteTimeRemainingSec
+	}
+	if e.client.Feature("time") == "1" {
+		if timeErr := e.client.Time(uint(secsRemaining * 100)); timeErr != nil {
+			return nil, timeErr
+		}
+		if otimErr := e.client.Otim(uint(oppSecsRemaining * 100)); otimErr != nil {
+			return nil, otimErr
+		}
+	}
+
+	genMoveCtx, cancelGenMoveCtx := context.WithTimeout(ctx, time.Duration(secsRemaining+1)*time.Second)
+	defer cancelGenMoveCtx()
+	moveStr, goErr := e.client.Go(genMoveCtx)
+	if goErr != nil {
+		if ctx.Err() != nil {
+			return nil, e.interrupt(ctx)
+		}
+		e.board = nil
+		_ = e.client.Force()
+		return nil, fmt.Errorf("error reading move: %w", goErr)
+	}
+	// the engine plays on once the opponent moves, unless it is put back in force mode
+	if forceErr := e.client.Force(); forceErr != nil {
+		return nil, forceErr
+	}
+
+	move, moveConvertErr := chess.MoveFromLongAlgebraic(moveStr, match.Board)
+	if moveConvertErr != nil {
+		// some engines ignore the rejected san feature
+		var sanErr error
+		move, sanErr = chess.MoveFromAlgebraic(moveStr, match.Board)
+		if sanErr != nil {
+			e.board = nil
+			return nil, fmt.Errorf("could not convert %s to move: %s", moveStr, moveConvertErr)
+		}
+	}
+	e.board = chess.GetBoardFromMove(match.Board, move)
+	return move, nil
+}
+
+// interrupt stops the cancelled search. The engine's board is no longer known, since the engine
+// may have played the move it was interrupted with.
+func (e *Engine) interrupt(ctx context.Context) error {
+	e.board = nil
+	interruptCtx, cancelInterruptCtx := context.WithTimeout(context.Background(), INTERRUPT_TIMEOUT)
+	defer cancelInterruptCtx()
+	if interruptErr := e.client.Interrupt(interruptCtx); interruptErr != nil {
+		return fmt.Errorf("search cancelled (%s) but could not be interrupted: %s", ctx.Err(), interruptErr)
+	}
+	return fmt.Errorf("search cancelled: %s", ctx.Err())
+}
+
+func (e *Engine) Terminate() {
+	_ = e.client.Quit()
+	if endErr := e.client.End(); endErr != nil {
+		fmt.Println("WARN: could not end client: ", endErr)
+	}
+}
+
+// OnOpponentMove passes the move on to the engine if its board is at the position the move was
+// played from. Otherwise the board is set up before the engine's next move.
+func (e *Engine) OnOpponentMove(match *models.Match, move *chess.Move) {
+	if e.board == nil || move == nil || !chess.IsLegalMove(e.board, move) ||
+		chess.GetBoardFromMove(e.board, move).ToFEN() != match.Board.ToFEN() {
+		e.board = nil
+		return
+	}
+	if moveErr := e.client.UserMove(move.ToLongAlgebraic()); moveErr != nil {
+		e.board = nil
+		return
+	}
+	e.board = match.Board
+}
+
+// OnMatchResult tells the engine how the match ended
+func (e *Engine) OnMatchResult(match *models.Match) {
+	if result, comment, ok := Result(match.Result); ok {
+		_ = e.client.Result(result, comment)
+	}
+}
+
+// OnTakeback has the board set up again before the engine's next move
+func (e *Engine) OnTakeback(match *models.Match) {
+	e.board = nil
+}
+
+// OnClockUpdate does nothing, since the clocks are sent before each move
+func (e *Engine) OnClockUpdate(match *models.Match) {}
+
+// Result converts the match result to the xboard result and its comment, such as "1-0" and
+// "white wins by checkmate". It returns false for matches in progress.
+func Result(matchResult models.MatchResult) (string, string, bool) {
+	comment := strings.ReplaceAll(string(matchResult), "_", " ")
+	switch {
+	case strings.HasPrefix(string(matchResult), "white_wins"):
+		return "1-0", comment, true
+	case strings.HasPrefix(string(matchResult), "black_wins"):
+		return "0-1", comment, true
+	case strings.HasPrefix(string(matchResult), "draw"):
+		return "1/2-1/2", comment, true
+	default:
+		return "", "", false
+	}
+}
+
+// Identity is the name the engine declared with the myname feature
+func (e *Engine) Identity() string {
+	if name := e.client.Feature("myname"); name != "" {
+		return name
+	}
+	return "xboard engine"
+}
+
+func (e *Engine) Transcript() []string {
+	return e.client.CmdClient.Transcript()
+}
@@ -0,0 +1,87 @@
+package game_policy
+
+import (
+	"fmt"
+	"github.com/CameronHonis/chess-bot-server/bot_manager"
+	"github.com/CameronHonis/chess-bot-server/engines"
+	"github.com/CameronHonis/log"
+	. "github.com/CameronHonis/marker"
+	"github.com/CameronHonis/service"
+)
+
+const ENV_GAME_POLICY = "GAME_POLICY"
+
+// GamePolicy decides, by each bot's policy, when the bots accept draws, resign and play
+// rematches, logging every decision
+type GamePolicy struct {
+	service.Service
+	__dependencies__ Marker
+	LogService       log.LoggerServiceI
+
+	__state__ Marker
+}
+
+func NewGamePolicy(config *GamePolicyConfig) *GamePolicy {
+	s := &GamePolicy{}
+	s.Service = *service.NewService(s, config)
+	return s
+}
+
+func (gp *GamePolicy) policy(botName string) *Policy {
+	return gp.Config().(*GamePolicyConfig).Policies().For(botName)
+}
+
+// AcceptsDraw reports whether the bot accepts its opponent's draw offer, going by the eval of its
+// last move, or by its engine's eval if it has not moved yet
+func (gp *GamePolicy) AcceptsDraw(botClient *bot_manager.BotClient) bool {
+	botName := botClient.Challenge().BotName
+	eval := lastEval(botClient)
+	isAccepted := gp.policy(botName).AcceptsDraw(botClient.Record().LastPly(), eval)
+	gp.LogService.Log(ENV_GAME_POLICY, fmt.Sprintf("draw offer in match %s to %s at eval %s: accepted %t",
+		botClient.MatchId(), botName, formatEval(eval), isAccepted))
+	return isAccepted
+}
+
+// ShouldResign reports whether the bot gives up, going by the evals of its moves so far
+func (gp *GamePolicy) ShouldResign(botClient *bot_manager.BotClient) bool {
+	botName := botClient.Challenge().BotName
+	evals := botClient.Record().BotEvals()
+	if !gp.policy(botName).ShouldResign(evals) {
+		return false
+	}
+	gp.LogService.Log(ENV_GAME_POLICY, fmt.Sprintf("%s resigning match %s at eval %s",
+		botName, botClient.MatchId(), formatEval(evals[len(evals)-1])))
+	return true
+}
+
+// AcceptsRematch reports whether the bot accepts its opponent's offer to replay the finished match
+func (gp *GamePolicy) AcceptsRematch(botName string, matchId string) bool {
+	isAccepted := gp.policy(botName).AcceptsRematches
+	gp.LogService.Log(ENV_GAME_POLICY, fmt.Sprintf("rematch offer for match %s to %s: accepted %t",
+		matchId, botName, isAccepted))
+	return isAccepted
+}
+
+// ProposesRematch reports whether the bot challenges its opponent to a rematch once the match
+// ends, given how many games it played the opponent just before
+func (gp *GamePolicy) ProposesRematch(botName string, priorGames uint) bool {
+	return gp.policy(botName).ProposesRematch(priorGames)
+}
+
+// lastEval is the eval of the bot's last move, or its engine's last eval if it has no moves
+func lastEval(botClient *bot_manager.BotClient) *int {
+	if evals := botClient.Record().BotEvals(); len(evals) > 0 {
+		return evals[len(evals)-1]
+	}
+	if cp, ok := engines.LastEval(botClient.Engine()); ok {
+		return &cp
+	}
+	return nil
+}
+
+func formatEval(eval *int) string {
+	if eval == nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("%+d", *eval)
+}
@@ -0,0 +1,21 @@
+package game_policy
+
+import "github.com/CameronHonis/service"
+
+type GamePolicyConfig struct {
+	service.ConfigI
+	policies *Policies
+}
+
+func NewGamePolicyConfig(policies *Policies) *GamePolicyConfig {
+	if policies == nil {
+		policies = DefaultPolicies()
+	}
+	return &GamePolicyConfig{
+		policies: policies,
+	}
+}
+
+func (c *GamePolicyConfig) Policies() *Policies {
+	return c.policies
+}
@@ -0,0 +1,13 @@
+package game_policy_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGamePolicy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GamePolicy Suite")
+}
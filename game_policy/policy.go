@@ -0,0 +1,136 @@
+package game_policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/CameronHonis/chess-bot-server/engines"
+	"os"
+)
+
+// Policy decides how a bot answers its opponent's offers and when it gives up. Evals are in
+// centipawns from the bot's side.
+type Policy struct {
+	// DrawEval is the highest eval at which draw offers are accepted. Without it, every draw offer
+	// is declined.
+	DrawEval *int `json:"drawEval"`
+	// MinDrawPly is the ply before which draw offers are declined
+	MinDrawPly uint `json:"minDrawPly"`
+	// ResignEval is the eval at or below which the bot resigns. Without it, the bot plays on.
+	ResignEval *int `json:"resignEval"`
+	// ResignMoves is how many of the bot's moves in a row must be evaluated at or below
+	// ResignEval before it resigns, and is at least one
+	ResignMoves      uint `json:"resignMoves"`
+	AcceptsRematches bool `json:"acceptsRematches"`
+	// MaxRematches is how many rematches in a row the bot proposes to an opponent after its
+	// matches end
+	MaxRematches uint `json:"maxRematches"`
+}
+
+// AcceptsDraw reports whether a draw offer is accepted at the ply, given the bot's last eval. Draw
+// offers are declined while the eval is unknown.
+func (p *Policy) AcceptsDraw(ply uint, eval *int) bool {
+	if p.DrawEval == nil || eval == nil || ply < p.MinDrawPly {
+		return false
+	}
+	return *eval <= *p.DrawEval
+}
+
+// ShouldResign reports whether the bot gives up, given the evals of its moves in order
+func (p *Policy) ShouldResign(evals []*int) bool {
+	if p.ResignEval == nil {
+		return false
+	}
+	resignMoves := int(p.ResignMoves)
+	if resignMoves == 0 {
+		resignMoves = 1
+	}
+	if len(evals) < resignMoves {
+		return false
+	}
+	for _, eval := range evals[len(evals)-resignMoves:] {
+		if eval == nil || *eval > *p.ResignEval {
+			return false
+		}
+	}
+	return true
+}
+
+// ProposesRematch reports whether the bot proposes a rematch, given how many games it has just
+// played against the opponent before the one that ended
+func (p *Policy) ProposesRematch(priorGames uint) bool {
+	return priorGames < p.MaxRematches
+}
+
+// Policies holds the policy of each bot. Bots without a policy of their own, under their bot name
+// or engine name, take the default.
+type Policies struct {
+	Default *Policy            `json:"default"`
+	Bots    map[string]*Policy `json:"bots"`
+}
+
+// DefaultPolicies is used when no policies are configured. Every bot declines draws and
+// rematches, and plays on until the end.
+func DefaultPolicies() *Policies {
+	return &Policies{
+		Default: &Policy{},
+		Bots:    make(map[string]*Policy),
+	}
+}
+
+func PoliciesFromJSON(policiesJson []byte) (*Policies, error) {
+	policies := DefaultPolicies()
+	if unmarshalErr := json.Unmarshal(policiesJson, policies); unmarshalErr != nil {
+		return nil, fmt.Errorf("could not parse game policies: %s", unmarshalErr)
+	}
+	if vetErr := policies.Vet(); vetErr != nil {
+		return nil, vetErr
+	}
+	return policies, nil
+}
+
+func LoadPolicies(path string) (*Policies, error) {
+	policiesJson, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, fmt.Errorf("could not read game policy file %s: %s", path, readErr)
+	}
+	return PoliciesFromJSON(policiesJson)
+}
+
+func (p *Policies) Vet() error {
+	if p.Default == nil {
+		return fmt.Errorf("missing default game policy")
+	}
+	if vetErr := p.Default.Vet(); vetErr != nil {
+		return fmt.Errorf("invalid default game policy: %s", vetErr)
+	}
+	for botName, policy := range p.Bots {
+		if policy == nil {
+			return fmt.Errorf("missing game policy for %s", botName)
+		}
+		if vetErr := policy.Vet(); vetErr != nil {
+			return fmt.Errorf("invalid game policy for %s: %s", botName, vetErr)
+		}
+	}
+	return nil
+}
+
+// Vet refuses a policy that would resign positions it accepts draws in
+func (p *Policy) Vet() error {
+	if p.DrawEval != nil && p.ResignEval != nil && *p.ResignEval >= *p.DrawEval {
+		return fmt.Errorf("resign eval %d is not below draw eval %d", *p.ResignEval, *p.DrawEval)
+	}
+	return nil
+}
+
+// For returns the policy of the bot, looked up by its bot name and then its engine name
+func (p *Policies) For(botName string) *Policy {
+	if policy, ok := p.Bots[botName]; ok {
+		return policy
+	}
+	if engineName, _, parseErr := engines.ParseBotName(botName); parseErr == nil {
+		if policy, ok := p.Bots[engineName]; ok {
+			return policy
+		}
+	}
+	return p.Default
+}
@@ -0,0 +1,66 @@
+package game_policy_test
+
+import (
+	. "github.com/CameronHonis/chess-bot-server/game_policy"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func Eval(cp int) *int {
+	return &cp
+}
+
+var _ = Describe("Policy", func() {
+	When("the policies are loaded from json", func() {
+		It("parses the default and each bot's policy", func() {
+			policies, loadErr := PoliciesFromJSON([]byte(`{
+				"default": {"drawEval": -50, "minDrawPly": 40},
+				"bots": {"stockfish": {"resignEval": -900, "resignMoves": 3, "acceptsRematches": true}}
+			}`))
+			Expect(loadErr).ToNot(HaveOccurred())
+			Expect(*policies.Default.DrawEval).To(Equal(-50))
+			Expect(policies.Default.MinDrawPly).To(Equal(uint(40)))
+			Expect(policies.Bots["stockfish"].ResignMoves).To(Equal(uint(3)))
+		})
+		It("defaults to playing on", func() {
+			policies, loadErr := PoliciesFromJSON([]byte(`{}`))
+			Expect(loadErr).ToNot(HaveOccurred())
+			Expect(policies.Default.AcceptsDraw(100, Eval(-500))).To(BeFalse())
+			Expect(policies.Default.ShouldResign([]*int{Eval(-5000)})).To(BeFalse())
+			Expect(policies.Default.ProposesRematch(0)).To(BeFalse())
+		})
+		It("rejects policies that resign where they would accept a draw", func() {
+			_, loadErr := PoliciesFromJSON([]byte(`{"default": {"drawEval": -100, "resignEval": -50}}`))
+			Expect(loadErr).To(HaveOccurred())
+		})
+	})
+	It("looks up a bot's policy by bot name, then engine name", func() {
+		policies, _ := PoliciesFromJSON([]byte(`{
+			"bots": {"alphabeta?depth=5": {"maxRematches": 2}, "alphabeta": {"maxRematches": 1}}
+		}`))
+		Expect(policies.For("alphabeta?depth=5").MaxRematches).To(Equal(uint(2)))
+		Expect(policies.For("alphabeta?depth=3").MaxRematches).To(Equal(uint(1)))
+		Expect(policies.For("random")).To(Equal(policies.Default))
+	})
+	It("accepts draws at or below the draw eval, once past the minimum ply", func() {
+		policy := &Policy{DrawEval: Eval(0), MinDrawPly: 20}
+		Expect(policy.AcceptsDraw(30, Eval(-40))).To(BeTrue())
+		Expect(policy.AcceptsDraw(30, Eval(0))).To(BeTrue())
+		Expect(policy.AcceptsDraw(30, Eval(40))).To(BeFalse())
+		Expect(policy.AcceptsDraw(10, Eval(-40))).To(BeFalse())
+		Expect(policy.AcceptsDraw(30, nil)).To(BeFalse())
+	})
+	It("resigns once enough moves in a row are evaluated as lost", func() {
+		policy := &Policy{ResignEval: Eval(-800), ResignMoves: 2}
+		Expect(policy.ShouldResign([]*int{Eval(20), Eval(-900)})).To(BeFalse())
+		Expect(policy.ShouldResign([]*int{Eval(-850), Eval(-900)})).To(BeTrue())
+		Expect(policy.ShouldResign([]*int{nil, Eval(-900)})).To(BeFalse())
+		Expect(policy.ShouldResign([]*int{Eval(-900)})).To(BeFalse())
+	})
+	It("proposes rematches up to the maximum in a row", func() {
+		policy := &Policy{MaxRematches: 2}
+		Expect(policy.ProposesRematch(0)).To(BeTrue())
+		Expect(policy.ProposesRematch(1)).To(BeTrue())
+		Expect(policy.ProposesRematch(2)).To(BeFalse())
+	})
+})
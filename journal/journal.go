@@ -0,0 +1,234 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/log"
+	. "github.com/CameronHonis/marker"
+	"github.com/CameronHonis/service"
+	"os"
+	"sync"
+	"time"
+)
+
+const ENV_JOURNAL = "JOURNAL"
+
+type EntryType string
+
+const (
+	ENTRY_TYPE_CREDS         EntryType = "creds"
+	ENTRY_TYPE_BOT_STARTED   EntryType = "bot_started"
+	ENTRY_TYPE_MATCH_BOUND   EntryType = "match_bound"
+	ENTRY_TYPE_MATCH_UPDATED EntryType = "match_updated"
+	ENTRY_TYPE_BOT_REMOVED   EntryType = "bot_removed"
+)
+
+// Entry is one line of the journal. Bots are identified by the uuid of their challenge, which,
+// unlike their keys, is the same across restarts.
+type Entry struct {
+	Type        EntryType                    `json:"type"`
+	Time        time.Time                    `json:"time"`
+	Creds       *arb_mods.AuthMessageContent `json:"creds,omitempty"`
+	ChallengeId string                       `json:"challengeId,omitempty"`
+	Challenge   *arb_mods.Challenge          `json:"challenge,omitempty"`
+	// Origin and ScheduleEntry record how the bot's game came about
+	Origin        string      `json:"origin,omitempty"`
+	ScheduleEntry string      `json:"scheduleEntry,omitempty"`
+	MatchId       string      `json:"matchId,omitempty"`
+	MatchState    *MatchState `json:"matchState,omitempty"`
+}
+
+// Journal appends every change to the bots and their matches to a JSON lines file, synced to
+// disk before returning, so that the bots can be restored after a restart. Appends are no-ops if
+// no path is configured. The journal replays its appends onto a snapshot of its own, which it
+// compacts the file down to when a bot is removed or the appends since the last compaction
+// outgrow the configured size.
+type Journal struct {
+	service.Service
+	__dependencies__ Marker
+	LogService       log.LoggerServiceI
+
+	__state__ Marker
+	file      *os.File
+	// appendedBytes is how much the journal has grown since it was last compacted
+	appendedBytes int64
+	snapshot      *Snapshot
+	mu            sync.Mutex
+}
+
+func NewJournal(config *JournalConfig) *Journal {
+	j := &Journal{}
+	j.Service = *service.NewService(j, config)
+	return j
+}
+
+// Load replays the journal into a snapshot, then compacts the journal down to the snapshot and
+// opens it for appending. Appends before Load are dropped.
+func (j *Journal) Load() (*Snapshot, error) {
+	path := j.Config().(*JournalConfig).Path()
+	snapshot := NewSnapshot()
+	if path == "" {
+		return snapshot, nil
+	}
+
+	file, openErr := os.Open(path)
+	if openErr != nil && !os.IsNotExist(openErr) {
+		return nil, fmt.Errorf("could not open journal %s: %s", path, openErr)
+	}
+	if file != nil {
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		lineIdx := 0
+		for scanner.Scan() {
+			lineIdx++
+			entry := &Entry{}
+			if unmarshalErr := json.Unmarshal(scanner.Bytes(), entry); unmarshalErr != nil {
+				// the last line is torn if the server crashed while appending it
+				j.LogService.LogRed(ENV_JOURNAL, fmt.Sprintf("skipping unreadable journal line %d: %s", lineIdx, unmarshalErr))
+				continue
+			}
+			snapshot.Apply(entry)
+		}
+		scanErr := scanner.Err()
+		_ = file.Close()
+		if scanErr != nil {
+			return nil, fmt.Errorf("could not read journal %s: %s", path, scanErr)
+		}
+	}
+
+	// the journal keeps its own copy, since the caller is free to read the snapshot while the
+	// journal applies appends
+	liveSnapshot := NewSnapshot()
+	for _, entry := range snapshot.Entries() {
+		liveSnapshot.Apply(entry)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.snapshot = liveSnapshot
+	if compactErr := j.compact(); compactErr != nil {
+		return nil, compactErr
+	}
+	return snapshot, nil
+}
+
+func (j *Journal) RecordCreds(publicKey, privateKey arb_mods.Key) {
+	j.append(&Entry{Type: ENTRY_TYPE_CREDS, Creds: &arb_mods.AuthMessageContent{
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+	}})
+}
+
+func (j *Journal) RecordBotStarted(challenge *arb_mods.Challenge, origin, scheduleEntry string) {
+	j.append(&Entry{Type: ENTRY_TYPE_BOT_STARTED, ChallengeId: challenge.Uuid, Challenge: challenge,
+		Origin: origin, ScheduleEntry: scheduleEntry})
+}
+
+func (j *Journal) RecordMatchBound(challengeId, matchId string) {
+	j.append(&Entry{Type: ENTRY_TYPE_MATCH_BOUND, ChallengeId: challengeId, MatchId: matchId})
+}
+
+func (j *Journal) RecordMatchUpdated(challengeId string, match *arb_mods.Match) {
+	j.append(&Entry{Type: ENTRY_TYPE_MATCH_UPDATED, ChallengeId: challengeId, MatchState: NewMatchState(match)})
+}
+
+// RecordBotRemoved compacts the journal rather than appending, since the bot's entries are the
+// bulk of what the removal would leave behind
+func (j *Journal) RecordBotRemoved(challengeId string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return
+	}
+	j.snapshot.Apply(&Entry{Type: ENTRY_TYPE_BOT_REMOVED, Time: time.Now(), ChallengeId: challengeId})
+	if compactErr := j.compact(); compactErr != nil {
+		j.LogService.LogRed(ENV_JOURNAL, fmt.Sprintf("could not compact journal after removing bot %s: %s", challengeId, compactErr))
+	}
+}
+
+// Close stops appending to the journal
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	closeErr := j.file.Close()
+	j.file = nil
+	return closeErr
+}
+
+func (j *Journal) append(entry *Entry) {
+	entry.Time = time.Now()
+	entryJson, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		j.LogService.LogRed(ENV_JOURNAL, fmt.Sprintf("could not marshal %s entry: %s", entry.Type, marshalErr))
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return
+	}
+	j.snapshot.Apply(entry)
+	writtenBytes, writeErr := j.file.Write(append(entryJson, '\n'))
+	j.appendedBytes += int64(writtenBytes)
+	if writeErr != nil {
+		j.LogService.LogRed(ENV_JOURNAL, fmt.Sprintf("could not write %s entry: %s", entry.Type, writeErr))
+		return
+	}
+	if j.appendedBytes > j.Config().(*JournalConfig).CompactBytes() {
+		if compactErr := j.compact(); compactErr != nil {
+			j.LogService.LogRed(ENV_JOURNAL, fmt.Sprintf("could not compact journal: %s", compactErr))
+		}
+		return
+	}
+	if syncErr := j.file.Sync(); syncErr != nil {
+		j.LogService.LogRed(ENV_JOURNAL, fmt.Sprintf("could not sync %s entry: %s", entry.Type, syncErr))
+	}
+}
+
+// compact atomically replaces the journal with the entries needed to rebuild the journal's
+// snapshot and reopens it for appending. The caller holds mu.
+func (j *Journal) compact() error {
+	path := j.Config().(*JournalConfig).Path()
+	tmpPath := path + ".tmp"
+	tmpFile, createErr := os.Create(tmpPath)
+	if createErr != nil {
+		return fmt.Errorf("could not create journal %s: %s", tmpPath, createErr)
+	}
+	writer := bufio.NewWriter(tmpFile)
+	for _, entry := range j.snapshot.Entries() {
+		entryJson, marshalErr := json.Marshal(entry)
+		if marshalErr != nil {
+			_ = tmpFile.Close()
+			return fmt.Errorf("could not marshal %s entry: %s", entry.Type, marshalErr)
+		}
+		_, _ = writer.Write(append(entryJson, '\n'))
+	}
+	if flushErr := writer.Flush(); flushErr != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("could not write journal %s: %s", tmpPath, flushErr)
+	}
+	if syncErr := tmpFile.Sync(); syncErr != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("could not sync journal %s: %s", tmpPath, syncErr)
+	}
+	_ = tmpFile.Close()
+	if renameErr := os.Rename(tmpPath, path); renameErr != nil {
+		return fmt.Errorf("could not replace journal %s: %s", path, renameErr)
+	}
+
+	file, openErr := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if openErr != nil {
+		return fmt.Errorf("could not open journal %s for appending: %s", path, openErr)
+	}
+	if j.file != nil {
+		_ = j.file.Close()
+	}
+	j.file = file
+	j.appendedBytes = 0
+	return nil
+}
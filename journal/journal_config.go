@@ -0,0 +1,32 @@
+package journal
+
+import "github.com/CameronHonis/service"
+
+// DEFAULT_COMPACT_BYTES is how large the journal may grow between loads before it is compacted
+const DEFAULT_COMPACT_BYTES = 1 << 20
+
+type JournalConfig struct {
+	service.ConfigI
+	path         string
+	compactBytes int64
+}
+
+// NewJournalConfig configures the journal file. An empty path disables the journal. The journal
+// is compacted once appends grow it past compactBytes, or DEFAULT_COMPACT_BYTES if zero.
+func NewJournalConfig(path string, compactBytes int64) *JournalConfig {
+	if compactBytes <= 0 {
+		compactBytes = DEFAULT_COMPACT_BYTES
+	}
+	return &JournalConfig{
+		path:         path,
+		compactBytes: compactBytes,
+	}
+}
+
+func (c *JournalConfig) Path() string {
+	return c.path
+}
+
+func (c *JournalConfig) CompactBytes() int64 {
+	return c.compactBytes
+}
@@ -0,0 +1,151 @@
+package journal_test
+
+import (
+	"github.com/CameronHonis/chess-arbitrator/builders"
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	"github.com/CameronHonis/chess-bot-server/journal"
+	"github.com/CameronHonis/log"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func NewJournal(path string) *journal.Journal {
+	return NewJournalWithCompactBytes(path, 0)
+}
+
+func NewJournalWithCompactBytes(path string, compactBytes int64) *journal.Journal {
+	logService := log.NewLoggerService(log.NewConfigBuilder().WithIsMuted(true).Build())
+	j := journal.NewJournal(journal.NewJournalConfig(path, compactBytes))
+	j.AddDependency(logService)
+	return j
+}
+
+func NewChallenge() *arb_mods.Challenge {
+	return builders.NewChallengeBuilder().
+		WithRandomUuid().
+		WithChallengerKey("player").
+		WithIsChallengerWhite(true).
+		WithTimeControl(&arb_mods.TimeControl{InitialTimeSec: 60}).
+		WithBotName("random").
+		WithIsActive(true).
+		Build()
+}
+
+var _ = Describe("Journal", func() {
+	var path string
+	var j *journal.Journal
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "journal.jsonl")
+		j = NewJournal(path)
+		_, loadErr := j.Load()
+		Expect(loadErr).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		_ = j.Close()
+	})
+	It("restores creds and bots after a restart", func() {
+		challenge := NewChallenge()
+		match := builders.NewMatchBuilder().FromChallenge(challenge).Build()
+		j.RecordCreds("pub", "pri")
+		j.RecordBotStarted(challenge, "challenged", "")
+		j.RecordMatchBound(challenge.Uuid, match.Uuid)
+		j.RecordMatchUpdated(challenge.Uuid, match)
+		Expect(j.Close()).To(Succeed())
+
+		snapshot, loadErr := NewJournal(path).Load()
+		Expect(loadErr).ToNot(HaveOccurred())
+		Expect(snapshot.Creds.PublicKey).To(Equal(arb_mods.Key("pub")))
+		Expect(snapshot.Bots).To(HaveLen(1))
+		Expect(snapshot.Bots[0].Challenge.Uuid).To(Equal(challenge.Uuid))
+		Expect(snapshot.Bots[0].MatchId).To(Equal(match.Uuid))
+		Expect(snapshot.Bots[0].LastMatch.Board.ToFEN()).To(Equal(match.Board.ToFEN()))
+		Expect(snapshot.Bots[0].LastMatch.TimeControl).To(Equal(challenge.TimeControl))
+	})
+	It("records match updates without the full board", func() {
+		challenge := NewChallenge()
+		match := builders.NewMatchBuilder().FromChallenge(challenge).Build()
+		j.RecordBotStarted(challenge, "challenged", "")
+		j.RecordMatchUpdated(challenge.Uuid, match)
+		Expect(j.Close()).To(Succeed())
+
+		journalBytes, _ := os.ReadFile(path)
+		lines := strings.Split(strings.TrimSpace(string(journalBytes)), "\n")
+		Expect(lines[len(lines)-1]).To(ContainSubstring(match.Board.ToFEN()))
+		Expect(lines[len(lines)-1]).ToNot(ContainSubstring("pieces"))
+	})
+	It("drops removed bots and ended matches", func() {
+		removedChallenge, endedChallenge := NewChallenge(), NewChallenge()
+		endedMatch := builders.NewMatchBuilder().FromChallenge(endedChallenge).Build()
+		endedMatch.Result = arb_mods.MATCH_RESULT_WHITE_WINS_BY_RESIGNATION
+		j.RecordBotStarted(removedChallenge, "challenged", "")
+		j.RecordBotStarted(endedChallenge, "challenged", "")
+		j.RecordBotRemoved(removedChallenge.Uuid)
+		j.RecordMatchUpdated(endedChallenge.Uuid, endedMatch)
+		Expect(j.Close()).To(Succeed())
+
+		snapshot, loadErr := NewJournal(path).Load()
+		Expect(loadErr).ToNot(HaveOccurred())
+		Expect(snapshot.Bots).To(BeEmpty())
+	})
+	It("skips a torn last line", func() {
+		j.RecordBotStarted(NewChallenge(), "challenged", "")
+		Expect(j.Close()).To(Succeed())
+		file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		_, _ = file.WriteString(`{"type":"bot_sta`)
+		_ = file.Close()
+
+		snapshot, loadErr := NewJournal(path).Load()
+		Expect(loadErr).ToNot(HaveOccurred())
+		Expect(snapshot.Bots).To(HaveLen(1))
+	})
+	It("compacts the journal when loaded", func() {
+		challenge := NewChallenge()
+		match := builders.NewMatchBuilder().FromChallenge(challenge).Build()
+		j.RecordBotStarted(challenge, "challenged", "")
+		for i := 0; i < 10; i++ {
+			j.RecordMatchUpdated(challenge.Uuid, match)
+		}
+		Expect(j.Close()).To(Succeed())
+
+		compacted := NewJournal(path)
+		_, loadErr := compacted.Load()
+		Expect(loadErr).ToNot(HaveOccurred())
+		defer compacted.Close()
+		journalBytes, _ := os.ReadFile(path)
+		Expect(strings.Count(string(journalBytes), "\n")).To(Equal(3))
+	})
+	It("compacts the journal when a bot is removed", func() {
+		keptChallenge, removedChallenge := NewChallenge(), NewChallenge()
+		j.RecordBotStarted(keptChallenge, "challenged", "")
+		j.RecordBotStarted(removedChallenge, "challenged", "")
+		j.RecordBotRemoved(removedChallenge.Uuid)
+
+		journalBytes, _ := os.ReadFile(path)
+		Expect(strings.Count(string(journalBytes), "\n")).To(Equal(1))
+		Expect(string(journalBytes)).To(ContainSubstring(keptChallenge.Uuid))
+		Expect(string(journalBytes)).ToNot(ContainSubstring(removedChallenge.Uuid))
+	})
+	It("compacts the journal once appends outgrow the compact size", func() {
+		_ = j.Close()
+		j = NewJournalWithCompactBytes(path, 2048)
+		_, loadErr := j.Load()
+		Expect(loadErr).ToNot(HaveOccurred())
+		challenge := NewChallenge()
+		match := builders.NewMatchBuilder().FromChallenge(challenge).Build()
+		j.RecordBotStarted(challenge, "challenged", "")
+		for i := 0; i < 100; i++ {
+			j.RecordMatchUpdated(challenge.Uuid, match)
+		}
+
+		journalBytes, _ := os.ReadFile(path)
+		Expect(len(journalBytes)).To(BeNumerically("<", 4096))
+
+		snapshot, loadErr := NewJournal(path).Load()
+		Expect(loadErr).ToNot(HaveOccurred())
+		Expect(snapshot.Bots).To(HaveLen(1))
+		Expect(snapshot.Bots[0].LastMatch.Uuid).To(Equal(match.Uuid))
+	})
+})
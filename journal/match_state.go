@@ -0,0 +1,51 @@
+package journal
+
+import (
+	"fmt"
+	"github.com/CameronHonis/chess"
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+)
+
+// MatchState is the part of a match update needed to resume the match. The time control and bot
+// name are left out since they come from the bot's challenge, and the board is kept as a FEN.
+type MatchState struct {
+	Uuid                  string               `json:"uuid"`
+	FEN                   string               `json:"fen"`
+	WhiteClientKey        arb_mods.Key         `json:"whiteClientKey"`
+	WhiteTimeRemainingSec float64              `json:"whiteTimeRemainingSec"`
+	BlackClientKey        arb_mods.Key         `json:"blackClientKey"`
+	BlackTimeRemainingSec float64              `json:"blackTimeRemainingSec"`
+	Result                arb_mods.MatchResult `json:"result"`
+}
+
+func NewMatchState(match *arb_mods.Match) *MatchState {
+	return &MatchState{
+		Uuid:                  match.Uuid,
+		FEN:                   match.Board.ToFEN(),
+		WhiteClientKey:        match.WhiteClientKey,
+		WhiteTimeRemainingSec: match.WhiteTimeRemainingSec,
+		BlackClientKey:        match.BlackClientKey,
+		BlackTimeRemainingSec: match.BlackTimeRemainingSec,
+		Result:                match.Result,
+	}
+}
+
+// Match rebuilds the match from the state and the challenge it was made from. Repetitions
+// before the position are not kept in a FEN, so they are lost until the next match update.
+func (s *MatchState) Match(challenge *arb_mods.Challenge) (*arb_mods.Match, error) {
+	board, fenErr := chess.BoardFromFEN(s.FEN)
+	if fenErr != nil {
+		return nil, fmt.Errorf("could not parse match %s board %s: %s", s.Uuid, s.FEN, fenErr)
+	}
+	return &arb_mods.Match{
+		Uuid:                  s.Uuid,
+		Board:                 board,
+		WhiteClientKey:        s.WhiteClientKey,
+		WhiteTimeRemainingSec: s.WhiteTimeRemainingSec,
+		BlackClientKey:        s.BlackClientKey,
+		BlackTimeRemainingSec: s.BlackTimeRemainingSec,
+		TimeControl:           challenge.TimeControl,
+		BotName:               challenge.BotName,
+		Result:                s.Result,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package journal
+
+import (
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	"time"
+)
+
+// BotSnapshot is everything needed to restore a bot: its challenge, which determines its engine,
+// and the latest state of its match, if the match has been seen
+type BotSnapshot struct {
+	Challenge     *arb_mods.Challenge
+	Origin        string
+	ScheduleEntry string
+	MatchId       string
+	LastMatch     *arb_mods.Match
+}
+
+// Snapshot is the state rebuilt by replaying the journal
+type Snapshot struct {
+	Creds *arb_mods.AuthMessageContent
+	// Bots are ordered by when they were started
+	Bots []*BotSnapshot
+}
+
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		Bots: make([]*BotSnapshot, 0),
+	}
+}
+
+// Apply replays the entry onto the snapshot. Bots whose match has ended are dropped.
+func (s *Snapshot) Apply(entry *Entry) {
+	switch entry.Type {
+	case ENTRY_TYPE_CREDS:
+		s.Creds = entry.Creds
+	case ENTRY_TYPE_BOT_STARTED:
+		if entry.Challenge != nil && s.bot(entry.ChallengeId) == nil {
+			s.Bots = append(s.Bots, &BotSnapshot{
+				Challenge:     entry.Challenge,
+				Origin:        entry.Origin,
+				ScheduleEntry: entry.ScheduleEntry,
+			})
+		}
+	case ENTRY_TYPE_MATCH_BOUND:
+		if bot := s.bot(entry.ChallengeId); bot != nil {
+			bot.MatchId = entry.MatchId
+		}
+	case ENTRY_TYPE_MATCH_UPDATED:
+		bot := s.bot(entry.ChallengeId)
+		if bot == nil || entry.MatchState == nil {
+			return
+		}
+		if entry.MatchState.Result != arb_mods.MATCH_RESULT_IN_PROGRESS {
+			s.removeBot(entry.ChallengeId)
+			return
+		}
+		match, matchErr := entry.MatchState.Match(bot.Challenge)
+		if matchErr != nil {
+			return
+		}
+		bot.MatchId = match.Uuid
+		bot.LastMatch = match
+	case ENTRY_TYPE_BOT_REMOVED:
+		s.removeBot(entry.ChallengeId)
+	}
+}
+
+// Entries are the fewest entries that rebuild the snapshot
+func (s *Snapshot) Entries() []*Entry {
+	now := time.Now()
+	entries := make([]*Entry, 0)
+	if s.Creds != nil {
+		entries = append(entries, &Entry{Type: ENTRY_TYPE_CREDS, Time: now, Creds: s.Creds})
+	}
+	for _, bot := range s.Bots {
+		challengeId := bot.Challenge.Uuid
+		entries = append(entries, &Entry{Type: ENTRY_TYPE_BOT_STARTED, Time: now, ChallengeId: challengeId, Challenge: bot.Challenge,
+			Origin: bot.Origin, ScheduleEntry: bot.ScheduleEntry})
+		if bot.MatchId != "" {
+			entries = append(entries, &Entry{Type: ENTRY_TYPE_MATCH_BOUND, Time: now, ChallengeId: challengeId, MatchId: bot.MatchId})
+		}
+		if bot.LastMatch != nil {
+			entries = append(entries, &Entry{Type: ENTRY_TYPE_MATCH_UPDATED, Time: now, ChallengeId: challengeId,
+				MatchState: NewMatchState(bot.LastMatch)})
+		}
+	}
+	return entries
+}
+
+func (s *Snapshot) bot(challengeId string) *BotSnapshot {
+	for _, bot := range s.Bots {
+		if bot.Challenge.Uuid == challengeId {
+			return bot
+		}
+	}
+	return nil
+}
+
+func (s *Snapshot) removeBot(challengeId string) {
+	for idx, bot := range s.Bots {
+		if bot.Challenge.Uuid == challengeId {
+			s.Bots = append(s.Bots[:idx], s.Bots[idx+1:]...)
+			return
+		}
+	}
+}
@@ -2,14 +2,25 @@ package main
 
 import (
 	"github.com/CameronHonis/chess-bot-server/app"
-	"sync"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
 	appService := app.Setup()
+	go drainOnSignal(appService)
+	// Start returns once the server has drained
 	appService.Start()
-	wg.Wait()
+	appService.Stop()
+}
+
+// drainOnSignal lets the matches in progress finish on the first interrupt or termination. A
+// second signal kills the server.
+func drainOnSignal(appService *app.AppService) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+	signal.Stop(signals)
+	appService.ArbitratorClient.Drain()
 }
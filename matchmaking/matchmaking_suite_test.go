@@ -0,0 +1,13 @@
+package matchmaking_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMatchmaking(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Matchmaking Suite")
+}
@@ -0,0 +1,175 @@
+package matchmaking
+
+import (
+	"encoding/json"
+	"fmt"
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+	"os"
+)
+
+type Mode string
+
+const (
+	// MODE_CHALLENGE entries challenge their opponents directly
+	MODE_CHALLENGE Mode = "challenge"
+	// MODE_QUEUE entries join the arbitrator's matchmaking queue
+	MODE_QUEUE Mode = "queue"
+)
+
+// ScheduleEntry keeps Games games of the bot running. In challenge mode, the opponents are
+// challenged in turn, skipping any opponent already playing or challenged by the server. In queue
+// mode, the server joins matchmaking, which the arbitrator allows only one entry at a time.
+type ScheduleEntry struct {
+	Name        string                `json:"name"`
+	BotName     string                `json:"botName"`
+	Mode        Mode                  `json:"mode"`
+	TimeControl *arb_mods.TimeControl `json:"timeControl"`
+	Games       uint                  `json:"games"`
+	Opponents   []mods.PlrClientKey   `json:"opponents"`
+	// Color is "white", "black" or empty for either. It is ignored in queue mode.
+	Color string `json:"color"`
+}
+
+func (e *ScheduleEntry) IsBotWhite() bool {
+	return e.Color == "white"
+}
+
+func (e *ScheduleEntry) IsBotBlack() bool {
+	return e.Color == "black"
+}
+
+// Schedule lists the games the server seeks on its own. Games between two engines hosted by the
+// server are not possible, since the arbitrator does not allow a client to challenge itself.
+type Schedule struct {
+	Entries []*ScheduleEntry `json:"entries"`
+	// nextOppIdxByEntry rotates through each entry's opponents
+	nextOppIdxByEntry map[string]int
+}
+
+func EmptySchedule() *Schedule {
+	return &Schedule{
+		Entries:           make([]*ScheduleEntry, 0),
+		nextOppIdxByEntry: make(map[string]int),
+	}
+}
+
+func ScheduleFromJSON(scheduleJson []byte) (*Schedule, error) {
+	schedule := EmptySchedule()
+	if unmarshalErr := json.Unmarshal(scheduleJson, schedule); unmarshalErr != nil {
+		return nil, fmt.Errorf("could not parse schedule: %s", unmarshalErr)
+	}
+	if vetErr := schedule.Vet(); vetErr != nil {
+		return nil, vetErr
+	}
+	return schedule, nil
+}
+
+func LoadSchedule(path string) (*Schedule, error) {
+	scheduleJson, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, fmt.Errorf("could not read schedule file %s: %s", path, readErr)
+	}
+	return ScheduleFromJSON(scheduleJson)
+}
+
+func (s *Schedule) Vet() error {
+	names := make(map[string]bool)
+	for idx, entry := range s.Entries {
+		if entry.Name == "" {
+			return fmt.Errorf("schedule entry #%d has no name", idx+1)
+		}
+		if names[entry.Name] {
+			return fmt.Errorf("schedule entry name %s is not unique", entry.Name)
+		}
+		names[entry.Name] = true
+		if entry.BotName == "" {
+			return fmt.Errorf("schedule entry %s has no bot name", entry.Name)
+		}
+		if entry.Mode != MODE_CHALLENGE && entry.Mode != MODE_QUEUE {
+			return fmt.Errorf("schedule entry %s has invalid mode %s", entry.Name, entry.Mode)
+		}
+		if entry.Mode == MODE_CHALLENGE && len(entry.Opponents) == 0 {
+			return fmt.Errorf("schedule entry %s challenges no opponents", entry.Name)
+		}
+		if entry.Mode == MODE_QUEUE && entry.TimeControl == nil {
+			return fmt.Errorf("schedule entry %s needs a time control to join matchmaking", entry.Name)
+		}
+		if entry.Color != "" && entry.Color != "white" && entry.Color != "black" {
+			return fmt.Errorf("schedule entry %s has invalid color %s", entry.Name, entry.Color)
+		}
+	}
+	return nil
+}
+
+// Status is what the server is currently doing, as far as the schedule is concerned
+type Status struct {
+	// GamesByEntry counts the pending and running games of each entry
+	GamesByEntry map[string]uint
+	// BusyOpps are the players the server is playing or has challenged
+	BusyOpps map[mods.PlrClientKey]bool
+	IsQueued bool
+}
+
+func NewStatus() *Status {
+	return &Status{
+		GamesByEntry: make(map[string]uint),
+		BusyOpps:     make(map[mods.PlrClientKey]bool),
+	}
+}
+
+// Seek is a game the schedule asks for. OppKey is empty when joining matchmaking.
+type Seek struct {
+	Entry  *ScheduleEntry
+	OppKey mods.PlrClientKey
+}
+
+// Plan returns the seeks that bring every entry up to its number of games, given the status
+func (s *Schedule) Plan(status *Status) []*Seek {
+	seeks := make([]*Seek, 0)
+	isQueued := status.IsQueued
+	busyOpps := make(map[mods.PlrClientKey]bool)
+	for oppKey, isBusy := range status.BusyOpps {
+		busyOpps[oppKey] = isBusy
+	}
+
+	for _, entry := range s.Entries {
+		games := status.GamesByEntry[entry.Name]
+		for games < entry.Games {
+			if entry.Mode == MODE_QUEUE {
+				if isQueued {
+					break
+				}
+				isQueued = true
+				seeks = append(seeks, &Seek{Entry: entry})
+				games++
+				continue
+			}
+
+			oppKey, ok := s.nextIdleOpp(entry, busyOpps)
+			if !ok {
+				break
+			}
+			busyOpps[oppKey] = true
+			seeks = append(seeks, &Seek{Entry: entry, OppKey: oppKey})
+			games++
+		}
+	}
+	return seeks
+}
+
+func (s *Schedule) nextIdleOpp(entry *ScheduleEntry, busyOpps map[mods.PlrClientKey]bool) (mods.PlrClientKey, bool) {
+	if s.nextOppIdxByEntry == nil {
+		s.nextOppIdxByEntry = make(map[string]int)
+	}
+	startIdx := s.nextOppIdxByEntry[entry.Name]
+	for offset := 0; offset < len(entry.Opponents); offset++ {
+		oppIdx := (startIdx + offset) % len(entry.Opponents)
+		oppKey := entry.Opponents[oppIdx]
+		if !busyOpps[oppKey] {
+			s.nextOppIdxByEntry[entry.Name] = oppIdx + 1
+			return oppKey, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,117 @@
+package matchmaking_test
+
+import (
+	arb_mods "github.com/CameronHonis/chess-arbitrator/models"
+	. "github.com/CameronHonis/chess-bot-server/matchmaking"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schedule", func() {
+	When("the schedule is loaded from json", func() {
+		It("parses the entries", func() {
+			schedule, loadErr := ScheduleFromJSON([]byte(`{"entries": [{
+				"name": "sparring", "botName": "stockfish", "mode": "challenge", "games": 2,
+				"timeControl": {"initialTimeSec": 300, "incrementSec": 2}, "opponents": ["alice"], "color": "white"
+			}]}`))
+			Expect(loadErr).ToNot(HaveOccurred())
+			Expect(schedule.Entries).To(HaveLen(1))
+			Expect(schedule.Entries[0].Games).To(Equal(uint(2)))
+			Expect(schedule.Entries[0].TimeControl.InitialTimeSec).To(Equal(int64(300)))
+			Expect(schedule.Entries[0].IsBotWhite()).To(BeTrue())
+		})
+		It("rejects unknown modes", func() {
+			_, loadErr := ScheduleFromJSON([]byte(`{"entries": [{"name": "a", "botName": "stockfish", "mode": "tournament"}]}`))
+			Expect(loadErr).To(HaveOccurred())
+		})
+		It("rejects challenge entries without opponents", func() {
+			_, loadErr := ScheduleFromJSON([]byte(`{"entries": [{"name": "a", "botName": "stockfish", "mode": "challenge"}]}`))
+			Expect(loadErr).To(HaveOccurred())
+		})
+		It("rejects queue entries without a time control", func() {
+			_, loadErr := ScheduleFromJSON([]byte(`{"entries": [{"name": "a", "botName": "stockfish", "mode": "queue"}]}`))
+			Expect(loadErr).To(HaveOccurred())
+		})
+		It("rejects duplicate entry names", func() {
+			_, loadErr := ScheduleFromJSON([]byte(`{"entries": [
+				{"name": "a", "botName": "stockfish", "mode": "challenge", "opponents": ["alice"]},
+				{"name": "a", "botName": "mila", "mode": "challenge", "opponents": ["bob"]}
+			]}`))
+			Expect(loadErr).To(HaveOccurred())
+		})
+	})
+	Describe("Plan", func() {
+		var schedule *Schedule
+		var status *Status
+		BeforeEach(func() {
+			schedule = EmptySchedule()
+			status = NewStatus()
+		})
+		When("a challenge entry is short of games", func() {
+			BeforeEach(func() {
+				schedule.Entries = append(schedule.Entries, &ScheduleEntry{
+					Name:      "sparring",
+					BotName:   "stockfish",
+					Mode:      MODE_CHALLENGE,
+					Games:     2,
+					Opponents: []arb_mods.Key{"alice", "bob", "carol"},
+				})
+			})
+			It("challenges idle opponents up to the number of games", func() {
+				seeks := schedule.Plan(status)
+				Expect(seeks).To(HaveLen(2))
+				Expect(seeks[0].OppKey).To(Equal(arb_mods.Key("alice")))
+				Expect(seeks[1].OppKey).To(Equal(arb_mods.Key("bob")))
+			})
+			It("skips busy opponents", func() {
+				status.BusyOpps["alice"] = true
+				seeks := schedule.Plan(status)
+				Expect(seeks).To(HaveLen(2))
+				Expect(seeks[0].OppKey).To(Equal(arb_mods.Key("bob")))
+				Expect(seeks[1].OppKey).To(Equal(arb_mods.Key("carol")))
+			})
+			It("counts the games already running", func() {
+				status.GamesByEntry["sparring"] = 1
+				Expect(schedule.Plan(status)).To(HaveLen(1))
+			})
+			It("rotates through the opponents across plans", func() {
+				status.GamesByEntry["sparring"] = 1
+				Expect(schedule.Plan(status)[0].OppKey).To(Equal(arb_mods.Key("alice")))
+				Expect(schedule.Plan(status)[0].OppKey).To(Equal(arb_mods.Key("bob")))
+				Expect(schedule.Plan(status)[0].OppKey).To(Equal(arb_mods.Key("carol")))
+				Expect(schedule.Plan(status)[0].OppKey).To(Equal(arb_mods.Key("alice")))
+			})
+			It("plans nothing when every opponent is busy", func() {
+				for _, oppKey := range []arb_mods.Key{"alice", "bob", "carol"} {
+					status.BusyOpps[oppKey] = true
+				}
+				Expect(schedule.Plan(status)).To(BeEmpty())
+			})
+		})
+		When("queue entries are short of games", func() {
+			BeforeEach(func() {
+				timeControl := &arb_mods.TimeControl{InitialTimeSec: 60}
+				schedule.Entries = append(schedule.Entries,
+					&ScheduleEntry{Name: "bullet", BotName: "stockfish", Mode: MODE_QUEUE, Games: 3, TimeControl: timeControl},
+					&ScheduleEntry{Name: "bullet-mila", BotName: "mila", Mode: MODE_QUEUE, Games: 1, TimeControl: timeControl},
+				)
+			})
+			It("joins matchmaking for one game at a time", func() {
+				seeks := schedule.Plan(status)
+				Expect(seeks).To(HaveLen(1))
+				Expect(seeks[0].Entry.Name).To(Equal("bullet"))
+				Expect(seeks[0].OppKey).To(BeEmpty())
+			})
+			It("does not join matchmaking while queued", func() {
+				status.IsQueued = true
+				Expect(schedule.Plan(status)).To(BeEmpty())
+			})
+			It("moves on to the next entry once an entry is full", func() {
+				status.GamesByEntry["bullet"] = 3
+				seeks := schedule.Plan(status)
+				Expect(seeks).To(HaveLen(1))
+				Expect(seeks[0].Entry.Name).To(Equal("bullet-mila"))
+			})
+		})
+	})
+})
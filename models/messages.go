@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	mainMods "github.com/CameronHonis/chess-arbitrator/models"
+)
+
+type DeclineReason string
+
+const (
+	DECLINE_REASON_AT_CAPACITY            DeclineReason = "at_capacity"
+	DECLINE_REASON_ENGINE_AT_CAPACITY     DeclineReason = "engine_at_capacity"
+	DECLINE_REASON_CHALLENGER_AT_CAPACITY DeclineReason = "challenger_at_capacity"
+	DECLINE_REASON_HOST_OVERLOADED        DeclineReason = "host_overloaded"
+	DECLINE_REASON_BOT_UNAVAILABLE        DeclineReason = "bot_unavailable"
+	DECLINE_REASON_POLICY                 DeclineReason = "policy"
+	DECLINE_REASON_DRAINING               DeclineReason = "draining"
+	DECLINE_REASON_READ_ONLY              DeclineReason = "read_only"
+)
+
+// DeclineChallengeWithReasonMessageContent is sent as a CONTENT_TYPE_DECLINE_CHALLENGE message.
+// The arbitrator only reads the challenger key, but the reason reaches the challenger's client.
+type DeclineChallengeWithReasonMessageContent struct {
+	ChallengerClientKey mainMods.Key  `json:"challengerClientKey"`
+	Reason              DeclineReason `json:"reason"`
+	Detail              string        `json:"detail"`
+}
+
+// The arbitrator has no draw offers, aborts or rematches yet, so their content types are defined
+// here. Arbitrators that do not know them drop the messages, and the game carries on as if they
+// had not been sent.
+const (
+	CONTENT_TYPE_OFFER_DRAW      mainMods.ContentType = "OFFER_DRAW"
+	CONTENT_TYPE_ACCEPT_DRAW     mainMods.ContentType = "ACCEPT_DRAW"
+	CONTENT_TYPE_DECLINE_DRAW    mainMods.ContentType = "DECLINE_DRAW"
+	CONTENT_TYPE_ABORT_MATCH     mainMods.ContentType = "ABORT_MATCH"
+	CONTENT_TYPE_OFFER_REMATCH   mainMods.ContentType = "OFFER_REMATCH"
+	CONTENT_TYPE_ACCEPT_REMATCH  mainMods.ContentType = "ACCEPT_REMATCH"
+	CONTENT_TYPE_DECLINE_REMATCH mainMods.ContentType = "DECLINE_REMATCH"
+)
+
+// DrawMessageContent is the content of draw offers and their answers
+type DrawMessageContent struct {
+	MatchId string `json:"matchId"`
+}
+
+type AbortMessageContent struct {
+	MatchId string `json:"matchId"`
+}
+
+// RematchMessageContent is the content of rematch offers and their answers, naming the finished
+// match to be replayed
+type RematchMessageContent struct {
+	MatchId string `json:"matchId"`
+}
+
+// UnmarshalToMessage parses a message like the arbitrator's UnmarshalToMessage, and also knows
+// the content types defined here
+func UnmarshalToMessage(msgJson []byte) (*mainMods.Message, error) {
+	var envelope struct {
+		ContentType mainMods.ContentType `json:"contentType"`
+		Content     json.RawMessage      `json:"content"`
+	}
+	if jsonParseErr := json.Unmarshal(msgJson, &envelope); jsonParseErr != nil {
+		return nil, fmt.Errorf("could not unmarshal message %s: %s", string(msgJson), jsonParseErr)
+	}
+	content := newLocalContent(envelope.ContentType)
+	if content == nil {
+		return mainMods.UnmarshalToMessage(msgJson)
+	}
+	if contentParseErr := json.Unmarshal(envelope.Content, content); contentParseErr != nil {
+		return nil, fmt.Errorf("could not unmarshal %s content: %s", envelope.ContentType, contentParseErr)
+	}
+	var msg mainMods.Message
+	_ = json.Unmarshal(msgJson, &msg)
+	msg.Content = content
+	return &msg, nil
+}
+
+// newLocalContent returns the content struct for a content type defined here, or nil for the
+// arbitrator's own
+func newLocalContent(contentType mainMods.ContentType) interface{} {
+	switch contentType {
+	case CONTENT_TYPE_OFFER_DRAW, CONTENT_TYPE_ACCEPT_DRAW, CONTENT_TYPE_DECLINE_DRAW:
+		return &DrawMessageContent{}
+	case CONTENT_TYPE_ABORT_MATCH:
+		return &AbortMessageContent{}
+	case CONTENT_TYPE_OFFER_REMATCH, CONTENT_TYPE_ACCEPT_REMATCH, CONTENT_TYPE_DECLINE_REMATCH:
+		return &RematchMessageContent{}
+	default:
+		return nil
+	}
+}
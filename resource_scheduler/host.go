@@ -0,0 +1,56 @@
+package resource_scheduler
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+func HostThreads() uint {
+	return uint(runtime.NumCPU())
+}
+
+// HostMemoryMb reads the total memory of the host from /proc/meminfo, so it is only
+// supported on linux hosts
+func HostMemoryMb() (uint, error) {
+	file, openErr := os.Open("/proc/meminfo")
+	if openErr != nil {
+		return 0, fmt.Errorf("could not open meminfo: %s", openErr)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		memKb, parseErr := strconv.ParseUint(fields[1], 10, 64)
+		if parseErr != nil {
+			return 0, fmt.Errorf("could not parse MemTotal %s: %s", fields[1], parseErr)
+		}
+		return uint(memKb / 1024), nil
+	}
+	return 0, fmt.Errorf("MemTotal not found in meminfo")
+}
+
+// HostLoad reads the one minute load average from /proc/loadavg, divided by the number of
+// threads on the host, so 1 means every thread is busy
+func HostLoad() (float64, error) {
+	contents, readErr := os.ReadFile("/proc/loadavg")
+	if readErr != nil {
+		return 0, fmt.Errorf("could not read loadavg: %s", readErr)
+	}
+	fields := strings.Fields(string(contents))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("loadavg is empty")
+	}
+	load, parseErr := strconv.ParseFloat(fields[0], 64)
+	if parseErr != nil {
+		return 0, fmt.Errorf("could not parse load %s: %s", fields[0], parseErr)
+	}
+	return load / float64(HostThreads()), nil
+}
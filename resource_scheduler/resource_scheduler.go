@@ -0,0 +1,168 @@
+package resource_scheduler
+
+import (
+	"fmt"
+	mods "github.com/CameronHonis/chess-bot-server/models"
+	"github.com/CameronHonis/log"
+	. "github.com/CameronHonis/marker"
+	"github.com/CameronHonis/service"
+	"math"
+	"sort"
+	"sync"
+)
+
+const ENV_RESOURCE_SCHEDULER = "RESOURCE_SCHEDULER"
+
+// ResourceConsumer is any engine that can be told how many search threads and how much
+// hash memory it may use. Implementations should defer applying the resources until they
+// are not searching.
+type ResourceConsumer interface {
+	SetResources(threads uint, hashMb uint)
+}
+
+type Allocation struct {
+	Threads uint
+	HashMb  uint
+}
+
+type consumerEntry struct {
+	consumer      ResourceConsumer
+	secsRemaining float64
+	allocation    Allocation
+}
+
+// ResourceScheduler splits the host's thread and hash budget across all active engines.
+// The budget is rebalanced whenever an engine is registered or unregistered, and on clock
+// updates if low clocks are favored.
+type ResourceScheduler struct {
+	service.Service
+	__dependencies__ Marker
+	LogService       log.LoggerServiceI
+
+	__state__     Marker
+	entryByBotKey map[mods.BotClientKey]*consumerEntry
+	mu            sync.Mutex
+}
+
+func NewResourceScheduler(config *ResourceSchedulerConfig) *ResourceScheduler {
+	s := &ResourceScheduler{
+		entryByBotKey: make(map[mods.BotClientKey]*consumerEntry),
+		mu:            sync.Mutex{},
+	}
+	s.Service = *service.NewService(s, config)
+	return s
+}
+
+func (rs *ResourceScheduler) Register(key mods.BotClientKey, consumer ResourceConsumer) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.entryByBotKey[key] = &consumerEntry{
+		consumer:      consumer,
+		secsRemaining: math.Inf(1),
+	}
+	rs.rebalance()
+}
+
+func (rs *ResourceScheduler) Unregister(key mods.BotClientKey) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if _, ok := rs.entryByBotKey[key]; !ok {
+		return
+	}
+	delete(rs.entryByBotKey, key)
+	rs.rebalance()
+}
+
+func (rs *ResourceScheduler) UpdateClock(key mods.BotClientKey, secsRemaining float64) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	entry, ok := rs.entryByBotKey[key]
+	if !ok {
+		return
+	}
+	entry.secsRemaining = secsRemaining
+	if rs.config().FavorLowClock() {
+		rs.rebalance()
+	}
+}
+
+func (rs *ResourceScheduler) Allocation(key mods.BotClientKey) (Allocation, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	entry, ok := rs.entryByBotKey[key]
+	if !ok {
+		return Allocation{}, fmt.Errorf("no resources allocated for bot %s", key)
+	}
+	return entry.allocation, nil
+}
+
+func (rs *ResourceScheduler) config() *ResourceSchedulerConfig {
+	return rs.Config().(*ResourceSchedulerConfig)
+}
+
+// rebalance expects the lock to be held by the caller
+func (rs *ResourceScheduler) rebalance() {
+	if len(rs.entryByBotKey) == 0 {
+		return
+	}
+	config := rs.config()
+
+	keys := make([]mods.BotClientKey, 0, len(rs.entryByBotKey))
+	for key := range rs.entryByBotKey {
+		keys = append(keys, key)
+	}
+	// lowest clocks first, so any leftover threads go to the most time-pressured engines
+	sort.Slice(keys, func(i, j int) bool {
+		iSecs := rs.entryByBotKey[keys[i]].secsRemaining
+		jSecs := rs.entryByBotKey[keys[j]].secsRemaining
+		if iSecs == jSecs {
+			return keys[i] < keys[j]
+		}
+		return iSecs < jSecs
+	})
+
+	weights := make([]float64, len(keys))
+	var weightSum float64
+	for i := range keys {
+		weights[i] = 1.
+		if config.FavorLowClock() && i == 0 && len(keys) > 1 && !math.IsInf(rs.entryByBotKey[keys[i]].secsRemaining, 1) {
+			weights[i] = config.LowClockWeight()
+		}
+		weightSum += weights[i]
+	}
+
+	threadsLeft := int(config.TotalThreads())
+	threadsByIdx := make([]uint, len(keys))
+	for i := range keys {
+		threads := int(math.Floor(float64(config.TotalThreads()) * weights[i] / weightSum))
+		if threads < 1 {
+			threads = 1
+		}
+		threadsByIdx[i] = uint(threads)
+		threadsLeft -= threads
+	}
+	for i := 0; threadsLeft > 0; i = (i + 1) % len(keys) {
+		threadsByIdx[i]++
+		threadsLeft--
+	}
+
+	hashMb := config.TotalHashMb() / uint(len(keys))
+	if hashMb < 1 {
+		hashMb = 1
+	}
+
+	for i, key := range keys {
+		entry := rs.entryByBotKey[key]
+		allocation := Allocation{
+			Threads: threadsByIdx[i],
+			HashMb:  hashMb,
+		}
+		if allocation == entry.allocation {
+			continue
+		}
+		entry.allocation = allocation
+		entry.consumer.SetResources(allocation.Threads, allocation.HashMb)
+		rs.LogService.Log(ENV_RESOURCE_SCHEDULER, fmt.Sprintf("allocated %d threads and %dMB hash to bot %s",
+			allocation.Threads, allocation.HashMb, key))
+	}
+}
@@ -0,0 +1,38 @@
+package resource_scheduler
+
+import "github.com/CameronHonis/service"
+
+type ResourceSchedulerConfig struct {
+	service.ConfigI
+	totalThreads   uint
+	totalHashMb    uint
+	favorLowClock  bool
+	lowClockWeight float64
+}
+
+func NewResourceSchedulerConfig(totalThreads, totalHashMb uint, favorLowClock bool) *ResourceSchedulerConfig {
+	return &ResourceSchedulerConfig{
+		totalThreads:   totalThreads,
+		totalHashMb:    totalHashMb,
+		favorLowClock:  favorLowClock,
+		lowClockWeight: 2.,
+	}
+}
+
+func (c *ResourceSchedulerConfig) TotalThreads() uint {
+	return c.totalThreads
+}
+
+func (c *ResourceSchedulerConfig) TotalHashMb() uint {
+	return c.totalHashMb
+}
+
+// FavorLowClock determines whether the engine with the least time remaining on its clock
+// receives a larger share of the threads
+func (c *ResourceSchedulerConfig) FavorLowClock() bool {
+	return c.favorLowClock
+}
+
+func (c *ResourceSchedulerConfig) LowClockWeight() float64 {
+	return c.lowClockWeight
+}
@@ -0,0 +1,13 @@
+package resource_scheduler_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestResourceScheduler(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ResourceScheduler Suite")
+}
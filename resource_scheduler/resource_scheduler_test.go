@@ -0,0 +1,104 @@
+package resource_scheduler_test
+
+import (
+	"github.com/CameronHonis/chess-bot-server/resource_scheduler"
+	"github.com/CameronHonis/log"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type MockConsumer struct {
+	threads uint
+	hashMb  uint
+}
+
+func (c *MockConsumer) SetResources(threads uint, hashMb uint) {
+	c.threads = threads
+	c.hashMb = hashMb
+}
+
+func NewScheduler(threads, hashMb uint, favorLowClock bool) *resource_scheduler.ResourceScheduler {
+	logService := log.NewLoggerService(log.NewConfigBuilder().WithIsMuted(true).Build())
+	scheduler := resource_scheduler.NewResourceScheduler(resource_scheduler.NewResourceSchedulerConfig(threads, hashMb, favorLowClock))
+	scheduler.AddDependency(logService)
+	return scheduler
+}
+
+var _ = Describe("ResourceScheduler", func() {
+	var scheduler *resource_scheduler.ResourceScheduler
+	var consumerA, consumerB *MockConsumer
+	BeforeEach(func() {
+		consumerA = &MockConsumer{}
+		consumerB = &MockConsumer{}
+	})
+	Describe("::Register", func() {
+		BeforeEach(func() {
+			scheduler = NewScheduler(8, 256, false)
+		})
+		When("only one engine is registered", func() {
+			It("gives the engine the entire budget", func() {
+				scheduler.Register("a", consumerA)
+				Expect(consumerA.threads).To(Equal(uint(8)))
+				Expect(consumerA.hashMb).To(Equal(uint(256)))
+			})
+		})
+		When("a second engine is registered", func() {
+			It("splits the budget evenly", func() {
+				scheduler.Register("a", consumerA)
+				scheduler.Register("b", consumerB)
+				Expect(consumerA.threads).To(Equal(uint(4)))
+				Expect(consumerB.threads).To(Equal(uint(4)))
+				Expect(consumerA.hashMb).To(Equal(uint(128)))
+				Expect(consumerB.hashMb).To(Equal(uint(128)))
+			})
+		})
+		When("there are more engines than threads", func() {
+			It("gives every engine at least one thread", func() {
+				scheduler = NewScheduler(1, 256, false)
+				scheduler.Register("a", consumerA)
+				scheduler.Register("b", consumerB)
+				Expect(consumerA.threads).To(Equal(uint(1)))
+				Expect(consumerB.threads).To(Equal(uint(1)))
+			})
+		})
+	})
+	Describe("::Unregister", func() {
+		BeforeEach(func() {
+			scheduler = NewScheduler(8, 256, false)
+			scheduler.Register("a", consumerA)
+			scheduler.Register("b", consumerB)
+		})
+		It("returns the freed budget to the remaining engines", func() {
+			scheduler.Unregister("b")
+			Expect(consumerA.threads).To(Equal(uint(8)))
+			Expect(consumerA.hashMb).To(Equal(uint(256)))
+			Expect(scheduler.Allocation("b")).Error().To(HaveOccurred())
+		})
+	})
+	Describe("::UpdateClock", func() {
+		When("low clocks are favored", func() {
+			BeforeEach(func() {
+				scheduler = NewScheduler(9, 256, true)
+				scheduler.Register("a", consumerA)
+				scheduler.Register("b", consumerB)
+			})
+			It("gives more threads to the engine with the lowest clock", func() {
+				scheduler.UpdateClock("a", 60)
+				scheduler.UpdateClock("b", 5)
+				Expect(consumerB.threads).To(Equal(uint(6)))
+				Expect(consumerA.threads).To(Equal(uint(3)))
+			})
+		})
+		When("low clocks are not favored", func() {
+			BeforeEach(func() {
+				scheduler = NewScheduler(8, 256, false)
+				scheduler.Register("a", consumerA)
+				scheduler.Register("b", consumerB)
+			})
+			It("does not rebalance", func() {
+				scheduler.UpdateClock("b", 5)
+				Expect(consumerA.threads).To(Equal(consumerB.threads))
+			})
+		})
+	})
+})
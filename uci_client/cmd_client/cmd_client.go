@@ -13,8 +13,20 @@ import (
 
 const PRINT_IO = true
 
+// MAX_TRANSCRIPT_LINES bounds how much recent io is kept for diagnostics
+const MAX_TRANSCRIPT_LINES = 200
+
 type ByteDump []byte
 
+// ExitError reports that the cmd exited while the client was still using it
+type ExitError struct {
+	Detail string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("process exited: %s", e.Detail)
+}
+
 // String trims all null bytes from end of string
 func (b ByteDump) String() string {
 	var n = 0
@@ -102,7 +114,12 @@ type Client struct {
 	__dynamic__ marker.Marker // should always require mutex lock to manipulate internally
 	_isReading  bool
 	_lines      []string
-	mu          sync.Mutex
+	_transcript []string
+	// _exitErr is set once the cmd's stdout closes, since the cmd has then exited
+	_exitErr *ExitError
+	mu       sync.Mutex
+	waitOnce sync.Once
+	waitErr  error
 }
 
 func DefaultClient(cmd *exec.Cmd, r io.ReadCloser, w io.WriteCloser) *Client {
@@ -140,7 +157,8 @@ func ClientFromCmd(cmd *exec.Cmd) (*Client, error) {
 }
 
 // ReadLine is a blocking read on the next line from Stdout. If the context expires, ReadLine
-// will return an error indicating as such.
+// will return an error indicating as such. Once the cmd has exited and every line it wrote has
+// been read, ReadLine returns an ExitError.
 func (cc *Client) ReadLine(ctx context.Context) (string, error) {
 	if !cc.isReading() {
 		go cc.readLines(context.Background())
@@ -154,21 +172,40 @@ func (cc *Client) ReadLine(ctx context.Context) (string, error) {
 			if popErr == nil {
 				return line, nil
 			}
+			if exitErr := cc.exitErr(); exitErr != nil {
+				return "", exitErr
+			}
 			time.Sleep(time.Millisecond)
 		}
 	}
 }
 
+// WriteLine writes the line to Stdin. Writing to a cmd that has exited returns an ExitError.
 func (cc *Client) WriteLine(s string) error {
+	if exitErr := cc.exitErr(); exitErr != nil {
+		return exitErr
+	}
 	if cc.flushOnWrite() {
 		cc.flushLines()
 	}
 
+	cc.record("< " + s)
 	line := fmt.Sprintf("%s\n", s)
 	_, err := cc.stdin.Write([]byte(line))
+	if err != nil && cc.cmd != nil {
+		// the cmd closes its end of Stdin when it exits, possibly before Stdout is seen to close
+		return &ExitError{fmt.Sprintf("could not write to stdin: %s", err)}
+	}
 	return err
 }
 
+// Transcript returns the most recent lines written to ("< ") and read from ("> ") the cmd
+func (cc *Client) Transcript() []string {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return append([]string{}, cc._transcript...)
+}
+
 func (cc *Client) SetBufSize(size uint) {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
@@ -185,7 +222,12 @@ func (cc *Client) FlushReader() {
 	cc.flushLines()
 }
 
+// End kills the cmd, unless it has already exited, and waits for it
 func (cc *Client) End() error {
+	if cc.exitErr() != nil {
+		_ = cc.stdin.Close()
+		return nil
+	}
 	if killErr := cc.cmd.Process.Kill(); killErr != nil {
 		return fmt.Errorf("could not kill process: %s", killErr)
 	}
@@ -195,12 +237,20 @@ func (cc *Client) End() error {
 	if stdoutCloseErr := cc.stdout.Close(); stdoutCloseErr != nil {
 		return fmt.Errorf("could not close stdout while closing process: %s", stdoutCloseErr)
 	}
-	if waitErr := cc.cmd.Wait(); waitErr != nil {
+	if waitErr := cc.wait(); waitErr != nil {
 		return fmt.Errorf("could not wait for process to close: %s", waitErr)
 	}
 	return nil
 }
 
+// wait waits for the cmd to exit, only calling Wait once however often it is called
+func (cc *Client) wait() error {
+	cc.waitOnce.Do(func() {
+		cc.waitErr = cc.cmd.Wait()
+	})
+	return cc.waitErr
+}
+
 func (cc *Client) IsRunning() bool {
 	return cc.cmd.ProcessState == nil
 }
@@ -211,13 +261,20 @@ func (cc *Client) readLines(ctx context.Context) {
 	}
 	cc.setIsReading(true)
 
-	br := &BlockingReader{cc.stdout, ctx}
+	// the stdout of a cmd only ends once the cmd exits, while other readers may just be empty
+	var r io.Reader = &BlockingReader{cc.stdout, ctx}
+	if cc.cmd != nil {
+		r = cc.stdout
+	}
 	var carryLine string
 
 	for {
 		p := make(ByteDump, cc.readBufSize())
-		n, err := br.Read(p)
-		if err != nil {
+		n, err := r.Read(p)
+		if n == 0 && err != nil {
+			if cc.cmd != nil {
+				cc.setExited(err)
+			}
 			break
 		}
 		lines := strings.Split(p.String(), "\n")
@@ -246,12 +303,45 @@ func (cc *Client) readLines(ctx context.Context) {
 	cc.setIsReading(false)
 }
 
+// setExited records that the cmd exited, once every line it wrote has been read
+func (cc *Client) setExited(readErr error) {
+	detail := fmt.Sprintf("stdout closed: %s", readErr)
+	if waitErr := cc.wait(); waitErr != nil {
+		detail = waitErr.Error()
+	} else if cc.cmd.ProcessState != nil {
+		detail = cc.cmd.ProcessState.String()
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc._exitErr == nil {
+		cc._exitErr = &ExitError{detail}
+	}
+}
+
+func (cc *Client) exitErr() *ExitError {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc._exitErr
+}
+
 func (cc *Client) pushLines(lines ...string) {
+	for _, line := range lines {
+		cc.record("> " + line)
+	}
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
 	cc._lines = append(cc._lines, lines...)
 }
 
+func (cc *Client) record(line string) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc._transcript = append(cc._transcript, line)
+	if len(cc._transcript) > MAX_TRANSCRIPT_LINES {
+		cc._transcript = cc._transcript[len(cc._transcript)-MAX_TRANSCRIPT_LINES:]
+	}
+}
+
 func (cc *Client) popLine() (string, error) {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"errors"
 	"github.com/CameronHonis/chess-bot-server/uci_client/cmd_client"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"os/exec"
 	"sync"
 	"time"
 )
@@ -129,4 +131,36 @@ var _ = Describe("Client", func() {
 			})
 		})
 	})
+	Describe("Transcript", func() {
+		BeforeEach(func() {
+			readerWriter.WriteLine("readyok")
+		})
+		It("records reads and writes in order", func() {
+			Expect(cmdClient.ReadLine(ctx)).To(Equal("readyok"))
+			Expect(cmdClient.WriteLine("isready")).To(Succeed())
+			Expect(cmdClient.Transcript()[:2]).To(Equal([]string{"> readyok", "< isready"}))
+		})
+	})
+	When("the cmd exits", func() {
+		var exitingClient *cmd_client.Client
+		BeforeEach(func() {
+			cmd := exec.Command("sh", "-c", "echo bye; exit 3")
+			var clientErr error
+			exitingClient, clientErr = cmd_client.ClientFromCmd(cmd)
+			Expect(clientErr).ToNot(HaveOccurred())
+			Expect(cmd.Start()).To(Succeed())
+		})
+		It("reads the lines written before exiting, then reports the exit", func() {
+			readCtx, cancelReadCtx := context.WithTimeout(context.Background(), time.Second)
+			defer cancelReadCtx()
+			Expect(exitingClient.ReadLine(readCtx)).To(Equal("bye"))
+			_, readErr := exitingClient.ReadLine(readCtx)
+			var exitErr *cmd_client.ExitError
+			Expect(errors.As(readErr, &exitErr)).To(BeTrue())
+			Expect(exitErr.Detail).To(ContainSubstring("exit status 3"))
+			Expect(errors.As(exitingClient.WriteLine("isready"), &exitErr)).To(BeTrue())
+			Expect(exitingClient.IsRunning()).To(BeFalse())
+			Expect(exitingClient.End()).To(Succeed())
+		})
+	})
 })
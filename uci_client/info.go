@@ -0,0 +1,113 @@
+package uci_client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const MATE_SCORE_CP = 100_000
+
+// Info holds the fields of a single "info" line that are useful for picking between moves
+type Info struct {
+	Depth        uint
+	MultiPV      uint
+	ScoreCp      int
+	IsMateScore  bool
+	MateIn       int // moves until mate, negative if the engine is getting mated
+	IsBoundScore bool
+	Nodes        uint64
+	TimeMs       uint
+	PV           []string
+}
+
+// Cp returns the score in centipawns, mapping mate scores to values just beyond any
+// material score, so they still sort correctly
+func (i *Info) Cp() int {
+	if !i.IsMateScore {
+		return i.ScoreCp
+	}
+	if i.MateIn >= 0 {
+		return MATE_SCORE_CP - i.MateIn
+	}
+	return -MATE_SCORE_CP - i.MateIn
+}
+
+// ParseInfo parses an "info" line of a search. Lines without a principal variation (such as
+// "info string" or "currmove" lines) return a nil Info without an error.
+func ParseInfo(line string) (*Info, error) {
+	tokens := strings.Fields(line)
+	if len(tokens) == 0 || tokens[0] != "info" {
+		return nil, fmt.Errorf("not an info line: %s", line)
+	}
+
+	info := &Info{MultiPV: 1}
+	hasScore := false
+	for i := 1; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "string":
+			return nil, nil
+		case "depth":
+			val, err := nextUint(tokens, &i)
+			if err != nil {
+				return nil, err
+			}
+			info.Depth = uint(val)
+		case "multipv":
+			val, err := nextUint(tokens, &i)
+			if err != nil {
+				return nil, err
+			}
+			info.MultiPV = uint(val)
+		case "nodes":
+			val, err := nextUint(tokens, &i)
+			if err != nil {
+				return nil, err
+			}
+			info.Nodes = val
+		case "time":
+			val, err := nextUint(tokens, &i)
+			if err != nil {
+				return nil, err
+			}
+			info.TimeMs = uint(val)
+		case "score":
+			if i+2 >= len(tokens) {
+				return nil, fmt.Errorf("malformed score in info line: %s", line)
+			}
+			val, err := strconv.Atoi(tokens[i+2])
+			if err != nil {
+				return nil, fmt.Errorf("malformed score in info line: %s", line)
+			}
+			if tokens[i+1] == "mate" {
+				info.IsMateScore = true
+				info.MateIn = val
+			} else {
+				info.ScoreCp = val
+			}
+			hasScore = true
+			i += 2
+		case "lowerbound", "upperbound":
+			info.IsBoundScore = true
+		case "pv":
+			info.PV = tokens[i+1:]
+			i = len(tokens)
+		}
+	}
+	if !hasScore || len(info.PV) == 0 {
+		return nil, nil
+	}
+	return info, nil
+}
+
+func nextUint(tokens []string, i *int) (uint64, error) {
+	if *i+1 >= len(tokens) {
+		return 0, fmt.Errorf("missing value for %s", tokens[*i])
+	}
+	*i++
+	val, err := strconv.ParseUint(tokens[*i], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed value for %s: %s", tokens[*i-1], tokens[*i])
+	}
+	return val, nil
+}
@@ -6,14 +6,20 @@ import (
 	"github.com/CameronHonis/chess-bot-server/uci_client/cmd_client"
 	"github.com/CameronHonis/set"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 )
 
+// STOP_TIMEOUT bounds how long a cancelled search waits for the engine's final bestmove
+const STOP_TIMEOUT = time.Second
+
 // Client represents a client for any engine supporting UCI (Universal Chess Interface)
 // This interface is outlined [here](https://www.stmintz.com/ccc/index.php?id=141612)
 type Client struct {
 	CmdClient *cmd_client.Client
 	opts      *set.Set[string]
+	name      string
 }
 
 func NewUciClient(client *cmd_client.Client) *Client {
@@ -37,7 +43,7 @@ func (c *Client) Init(ctx context.Context) (*set.Set[string], error) {
 	c.CmdClient.SetFlushOnWrite(true)
 	writeErr := c.CmdClient.WriteLine("uci")
 	if writeErr != nil {
-		return nil, fmt.Errorf("could not write to uci CmdClient: %s", writeErr)
+		return nil, fmt.Errorf("could not write to uci CmdClient: %w", writeErr)
 	}
 
 	for {
@@ -45,6 +51,9 @@ func (c *Client) Init(ctx context.Context) (*set.Set[string], error) {
 		if readErr != nil {
 			return nil, fmt.Errorf("could not read output after init: %s", readErr)
 		}
+		if strings.HasPrefix(resp, "id name ") {
+			c.name = strings.TrimPrefix(resp, "id name ")
+		}
 		if strings.HasPrefix(resp, "option name") {
 			optionDetails := resp[len("option name "):]
 			optionName := strings.Split(optionDetails, " ")[0]
@@ -57,31 +66,50 @@ func (c *Client) Init(ctx context.Context) (*set.Set[string], error) {
 	return c.opts.Copy(), nil
 }
 
+// EngineName is the name the engine reported during Init, if any
+func (c *Client) EngineName() string {
+	return c.name
+}
+
 func (c *Client) IsOption(optName string) bool {
 	return c.opts.Has(optName)
 }
 
+// SetOption sets the option and confirms it with isready. The engine reports a rejected option
+// with a line before readyok, which is returned as the error. Info lines are not rejections.
 func (c *Client) SetOption(ctx context.Context, optName string, optVal string) error {
 	writeErr := c.CmdClient.WriteLine(fmt.Sprintf("setoption name %s value %s", optName, optVal))
 	if writeErr != nil {
-		return fmt.Errorf("could not write to uci CmdClient: %s", writeErr)
+		return fmt.Errorf("could not write to uci CmdClient: %w", writeErr)
+	}
+	writeErr = c.CmdClient.WriteLine("isready")
+	if writeErr != nil {
+		return fmt.Errorf("could not write to uci CmdClient: %w", writeErr)
 	}
 
-	resp, readErr := c.CmdClient.ReadLine(ctx) // Only expect set config errors to be received here
-	if readErr != nil {
-		if _, ok := readErr.(*cmd_client.ReaderTimeout); ok {
-			return nil
-		} else {
-			return fmt.Errorf("cannot read output while setting option: %s", readErr)
+	rejection := make([]string, 0)
+	for {
+		resp, readErr := c.CmdClient.ReadLine(ctx)
+		if readErr != nil {
+			return fmt.Errorf("could not confirm option %s: %w", optName, readErr)
+		}
+		if resp == "readyok" {
+			break
+		}
+		if resp != "" && !strings.HasPrefix(resp, "info") {
+			rejection = append(rejection, resp)
 		}
 	}
-	return fmt.Errorf("cannot set option: %s", resp)
+	if len(rejection) > 0 {
+		return fmt.Errorf("cannot set option: %s", strings.Join(rejection, "; "))
+	}
+	return nil
 }
 
 func (c *Client) SetPosition(fen string) error {
 	writeErr := c.CmdClient.WriteLine(fmt.Sprintf("position fen %s", fen))
 	if writeErr != nil {
-		return fmt.Errorf("could not write to uci CmdClient: %s", writeErr)
+		return fmt.Errorf("could not write to uci CmdClient: %w", writeErr)
 	}
 	return nil
 }
@@ -89,7 +117,7 @@ func (c *Client) SetPosition(fen string) error {
 func (c *Client) IsReady(ctx context.Context) (bool, error) {
 	writeErr := c.CmdClient.WriteLine("isready")
 	if writeErr != nil {
-		return false, fmt.Errorf("could not write to uci CmdClient %s", writeErr)
+		return false, fmt.Errorf("could not write to uci CmdClient %w", writeErr)
 	}
 
 	resp, readErr := c.CmdClient.ReadLine(ctx)
@@ -100,27 +128,112 @@ func (c *Client) IsReady(ctx context.Context) (bool, error) {
 }
 
 func (c *Client) Go(ctx context.Context, opts *SearchOptions) (string, error) {
+	result, searchErr := c.Search(ctx, opts)
+	if searchErr != nil {
+		return "", searchErr
+	}
+	return result.BestMove, nil
+}
+
+// SearchResult is the outcome of a search, along with the latest exact info line reported for
+// each principal variation (more than one if the MultiPV option is set)
+type SearchResult struct {
+	BestMove      string
+	PonderMove    string
+	InfoByMultiPV map[uint]*Info
+}
+
+// Candidates returns the latest info of each principal variation, best first
+func (sr *SearchResult) Candidates() []*Info {
+	candidates := make([]*Info, 0, len(sr.InfoByMultiPV))
+	for _, info := range sr.InfoByMultiPV {
+		candidates = append(candidates, info)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].MultiPV < candidates[j].MultiPV
+	})
+	return candidates
+}
+
+// PrincipalVariation is the line the engine expects after its best move, falling back to the
+// best and ponder moves if no info line covered the best move
+func (sr *SearchResult) PrincipalVariation() []string {
+	if info, ok := sr.InfoByMultiPV[1]; ok && len(info.PV) > 0 && info.PV[0] == sr.BestMove {
+		return append([]string{}, info.PV...)
+	}
+	if sr.PonderMove != "" {
+		return []string{sr.BestMove, sr.PonderMove}
+	}
+	return []string{sr.BestMove}
+}
+
+// Search behaves like Go, but also collects the info lines reported during the search
+func (c *Client) Search(ctx context.Context, opts *SearchOptions) (*SearchResult, error) {
 	cmd, cmdErr := searchOptionsToCmdStr(opts)
 	if cmdErr != nil {
-		return "", fmt.Errorf("cannot generate search command: %s", cmdErr)
+		return nil, fmt.Errorf("cannot generate search command: %s", cmdErr)
 	}
 
 	writeErr := c.CmdClient.WriteLine(cmd)
 	if writeErr != nil {
-		return "", fmt.Errorf("could not write to uci CmdClient %s", writeErr)
+		return nil, fmt.Errorf("could not write to uci CmdClient %w", writeErr)
 	}
 
+	result := &SearchResult{
+		InfoByMultiPV: make(map[uint]*Info),
+	}
 	for {
 		resp, readErr := c.CmdClient.ReadLine(ctx)
 		if readErr != nil {
-			return "", fmt.Errorf("read error while listening for best move: %s", readErr)
+			if ctx.Err() == nil {
+				return nil, fmt.Errorf("read error while listening for best move: %w", readErr)
+			}
+			stopCtx, cancelStopCtx := context.WithTimeout(context.Background(), STOP_TIMEOUT)
+			stopErr := c.Stop(stopCtx)
+			cancelStopCtx()
+			if stopErr != nil {
+				return nil, fmt.Errorf("search cancelled (%s) but could not be stopped: %s", ctx.Err(), stopErr)
+			}
+			return nil, fmt.Errorf("search cancelled: %s", ctx.Err())
+		}
+		if strings.HasPrefix(resp, "info") {
+			info, _ := ParseInfo(resp)
+			if info != nil && !info.IsBoundScore {
+				result.InfoByMultiPV[info.MultiPV] = info
+			}
 		}
 		if strings.HasPrefix(resp, "bestmove") {
 			bestMoveDetails := strings.Split(resp, " ")
 			if len(bestMoveDetails) <= 1 {
-				return "", fmt.Errorf("malformed bestmove response: %s", resp)
+				return nil, fmt.Errorf("malformed bestmove response: %s", resp)
 			}
-			return bestMoveDetails[1], nil
+			result.BestMove = bestMoveDetails[1]
+			if len(bestMoveDetails) >= 4 && bestMoveDetails[2] == "ponder" {
+				result.PonderMove = bestMoveDetails[3]
+			}
+			return result, nil
+		}
+	}
+}
+
+// Stop ends the running search and discards its output up to and including the bestmove the
+// engine still sends, so the next command is not answered by the abandoned search
+func (c *Client) Stop(ctx context.Context) error {
+	// the bestmove may already be buffered, so it must not be flushed by this write
+	c.CmdClient.SetFlushOnWrite(false)
+	writeErr := c.CmdClient.WriteLine("stop")
+	c.CmdClient.SetFlushOnWrite(true)
+	if writeErr != nil {
+		return fmt.Errorf("could not write to uci CmdClient %w", writeErr)
+	}
+
+	for {
+		resp, readErr := c.CmdClient.ReadLine(ctx)
+		if readErr != nil {
+			return fmt.Errorf("read error while waiting for the stopped search's best move: %w", readErr)
+		}
+		if strings.HasPrefix(resp, "bestmove") {
+			return nil
 		}
 	}
 }
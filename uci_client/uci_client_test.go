@@ -65,6 +65,10 @@ func (m *MockReaderWriter) Write(p []byte) (int, error) {
 				"uciok\n"))
 		}
 	case "setoption name Threads value 2\n":
+	case "setoption name Threads value 3\n":
+		resp = func(w io.Writer) {
+			_, _ = w.Write([]byte("info string Using 3 threads\n"))
+		}
 	case "setoption name Threads value asdf\n":
 		resp = func(w io.Writer) {
 			_, _ = w.Write([]byte("terminate called after throwing an instance of 'std::invalid_argument'\n" +
@@ -73,7 +77,7 @@ func (m *MockReaderWriter) Write(p []byte) (int, error) {
 		}
 	case "setoption name NotAnOption value some-value\n":
 		resp = func(w io.Writer) {
-			_, _ = w.Write([]byte("No such option: NotAnOption"))
+			_, _ = w.Write([]byte("No such option: NotAnOption\n"))
 		}
 	case "position fen rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1\n":
 	case "isready\n":
@@ -269,6 +273,14 @@ func (m *MockReaderWriter) Write(p []byte) (int, error) {
 				//time.Sleep(1 * time.Millisecond)
 			}
 		}
+	case "go depth 30\n":
+		resp = func(w io.Writer) {
+			_, _ = w.Write([]byte("info depth 1 seldepth 2 multipv 1 score cp -1 nodes 20 nps 20000 hashfull 0 tbhits 0 time 1 pv d2d4\n"))
+		}
+	case "stop\n":
+		resp = func(w io.Writer) {
+			_, _ = w.Write([]byte("bestmove d2d4\n"))
+		}
 	default:
 		resp = func(w io.Writer) {
 			comm := strings.Split(contents, " ")[0]
@@ -328,6 +340,10 @@ var _ = Describe("UciClient", func() {
 				Expect(uciClient.IsOption("Ponder")).To(BeTrue())
 				Expect(uciClient.IsOption("NotAnOption")).ToNot(BeTrue())
 			})
+			It("saves the engine name", func() {
+				_, _ = uciClient.Init(ctx)
+				Expect(uciClient.EngineName()).To(Equal("Stockfish dev-20240314-fb07281f"))
+			})
 			It("returns the configurable options", func() {
 				opts, _ := uciClient.Init(ctx)
 				Expect(opts).ToNot(BeNil())
@@ -363,6 +379,9 @@ var _ = Describe("UciClient", func() {
 				It("does not return an error", func() {
 					Expect(uciClient.SetOption(ctx, "Threads", "2")).To(Succeed())
 				})
+				It("does not treat info lines as a rejection", func() {
+					Expect(uciClient.SetOption(ctx, "Threads", "3")).To(Succeed())
+				})
 			})
 			When("the value is invalid", func() {
 				It("returns an error", func() {
@@ -420,4 +439,97 @@ var _ = Describe("UciClient", func() {
 			})
 		})
 	})
+
+	Describe("::Search", func() {
+		var ctx context.Context
+		var cancelCtx context.CancelFunc
+		var opts *uci_client.SearchOptions
+		BeforeEach(func() {
+			cmdClient := MockCmdClient(10 * time.Millisecond)
+			uciClient = uci_client.NewUciClient(cmdClient)
+			initCtx, cancelInitCtx := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			_, err := uciClient.Init(initCtx)
+			Expect(err).ToNot(HaveOccurred())
+			cancelInitCtx()
+
+			ctx, cancelCtx = context.WithTimeout(context.Background(), 1*time.Second)
+
+			opts = uci_client.NewSearchOptionsBuilder().WithWhiteMs(100000).Build()
+		})
+		AfterEach(func() {
+			cancelCtx()
+		})
+		It("returns the best and ponder moves", func() {
+			result, err := uciClient.Search(ctx, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.BestMove).To(Equal("d2d4"))
+			Expect(result.PonderMove).To(Equal("d7d5"))
+		})
+		It("keeps the latest exact info line of the principal variation", func() {
+			result, err := uciClient.Search(ctx, opts)
+			Expect(err).ToNot(HaveOccurred())
+			candidates := result.Candidates()
+			Expect(candidates).To(HaveLen(1))
+			Expect(candidates[0].Depth).To(Equal(uint(31)))
+			Expect(candidates[0].ScoreCp).To(Equal(31))
+			Expect(candidates[0].PV[0]).To(Equal("d2d4"))
+		})
+		When("the context is cancelled before the best move", func() {
+			var searchCtx context.Context
+			var cancelSearchCtx context.CancelFunc
+			BeforeEach(func() {
+				searchCtx, cancelSearchCtx = context.WithTimeout(ctx, 50*time.Millisecond)
+				opts = uci_client.NewSearchOptionsBuilder().WithDepth(30).Build()
+			})
+			AfterEach(func() {
+				cancelSearchCtx()
+			})
+			It("returns an error", func() {
+				Expect(uciClient.Search(searchCtx, opts)).Error().To(HaveOccurred())
+			})
+			It("stops the engine", func() {
+				_, _ = uciClient.Search(searchCtx, opts)
+				Expect(uciClient.CmdClient.Transcript()).To(ContainElement("< stop"))
+			})
+			It("consumes the stopped search's best move", func() {
+				_, _ = uciClient.Search(searchCtx, opts)
+				Expect(uciClient.IsReady(ctx)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("ParseInfo", func() {
+		When("the line has a centipawn score and pv", func() {
+			It("parses the fields", func() {
+				info, err := uci_client.ParseInfo("info depth 5 seldepth 3 multipv 2 score cp -30 nodes 121 nps 121000 time 1 pv d2d4 a7a6")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Depth).To(Equal(uint(5)))
+				Expect(info.MultiPV).To(Equal(uint(2)))
+				Expect(info.Cp()).To(Equal(-30))
+				Expect(info.Nodes).To(Equal(uint64(121)))
+				Expect(info.PV).To(Equal([]string{"d2d4", "a7a6"}))
+			})
+		})
+		When("the line has a mate score", func() {
+			It("ranks shorter mates higher", func() {
+				mateIn1, _ := uci_client.ParseInfo("info depth 3 score mate 1 pv h5f7")
+				mateIn3, _ := uci_client.ParseInfo("info depth 3 score mate 3 pv h5f7")
+				matedIn2, _ := uci_client.ParseInfo("info depth 3 score mate -2 pv h5f7")
+				Expect(mateIn1.Cp()).To(BeNumerically(">", mateIn3.Cp()))
+				Expect(matedIn2.Cp()).To(BeNumerically("<", -10000))
+			})
+		})
+		When("the line has no pv", func() {
+			It("returns nil without an error", func() {
+				info, err := uci_client.ParseInfo("info depth 25 currmove d2d4 currmovenumber 1")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info).To(BeNil())
+			})
+		})
+		When("the line is not an info line", func() {
+			It("returns an error", func() {
+				Expect(uci_client.ParseInfo("bestmove d2d4")).Error().To(HaveOccurred())
+			})
+		})
+	})
 })
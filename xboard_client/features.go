@@ -0,0 +1,67 @@
+package xboard_client
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Feature struct {
+	Name  string
+	Value string
+}
+
+// defaultValueByFeature holds the protocol defaults for features that change how the client
+// talks to the engine
+var defaultValueByFeature = map[string]string{
+	"ping":      "0",
+	"setboard":  "0",
+	"playother": "0",
+	"san":       "0",
+	"usermove":  "0",
+	"time":      "1",
+	"draw":      "1",
+	"sigint":    "1",
+	"sigterm":   "1",
+	"reuse":     "1",
+	"analyze":   "1",
+	"colors":    "1",
+	"done":      "",
+}
+
+func DefaultFeatureValue(name string) string {
+	return defaultValueByFeature[name]
+}
+
+// ParseFeatures parses a line like `feature ping=1 setboard=1 myname="Some Engine 1.0"`
+func ParseFeatures(line string) ([]*Feature, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "feature"))
+	features := make([]*Feature, 0)
+	for rest != "" {
+		eqIdx := strings.Index(rest, "=")
+		if eqIdx <= 0 {
+			return nil, fmt.Errorf("malformed feature line: %s", line)
+		}
+		name := rest[:eqIdx]
+		rest = rest[eqIdx+1:]
+
+		var value string
+		if strings.HasPrefix(rest, "\"") {
+			closeIdx := strings.Index(rest[1:], "\"")
+			if closeIdx < 0 {
+				return nil, fmt.Errorf("unterminated string in feature line: %s", line)
+			}
+			value = rest[1 : closeIdx+1]
+			rest = rest[closeIdx+2:]
+		} else {
+			valueEnd := strings.Index(rest, " ")
+			if valueEnd < 0 {
+				valueEnd = len(rest)
+			}
+			value = rest[:valueEnd]
+			rest = rest[valueEnd:]
+		}
+		features = append(features, &Feature{name, value})
+		rest = strings.TrimSpace(rest)
+	}
+	return features, nil
+}
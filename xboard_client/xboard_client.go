@@ -0,0 +1,253 @@
+package xboard_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/CameronHonis/chess-bot-server/uci_client/cmd_client"
+	"os/exec"
+	"strings"
+)
+
+// Client represents a client for any engine supporting version 2 of the XBoard protocol (CECP)
+// This interface is outlined [here](https://www.gnu.org/software/xboard/engine-intf.html)
+type Client struct {
+	CmdClient *cmd_client.Client
+	features  map[string]string
+	pingCount int
+}
+
+func NewXboardClient(client *cmd_client.Client) *Client {
+	return &Client{
+		CmdClient: client,
+		features:  make(map[string]string),
+	}
+}
+
+func XboardClientFromCmd(cmd *exec.Cmd) (*Client, error) {
+	cmdClient, cmdClientErr := cmd_client.ClientFromCmd(cmd)
+	if cmdClientErr != nil {
+		return nil, fmt.Errorf("could not make CmdClient: %s", cmdClientErr)
+	}
+	return NewXboardClient(cmdClient), nil
+}
+
+// Init tells the engine to use the xboard protocol and negotiates features. Engines that do
+// not send "done=0" are given until the context expires to send their features, at which point
+// the protocol defaults are assumed. It returns the features the engine declared.
+func (c *Client) Init(ctx context.Context) (map[string]string, error) {
+	c.CmdClient.SetFlushOnWrite(true)
+	if writeErr := c.CmdClient.WriteLine("xboard"); writeErr != nil {
+		return nil, fmt.Errorf("could not write to xboard CmdClient: %w", writeErr)
+	}
+	c.CmdClient.SetFlushOnWrite(false)
+	if writeErr := c.CmdClient.WriteLine("protover 2"); writeErr != nil {
+		return nil, fmt.Errorf("could not write to xboard CmdClient: %w", writeErr)
+	}
+
+	isAwaitingDone := false
+	for {
+		resp, readErr := c.CmdClient.ReadLine(ctx)
+		if readErr != nil {
+			if _, ok := readErr.(*cmd_client.ReaderTimeout); ok && !isAwaitingDone {
+				break
+			}
+			return nil, fmt.Errorf("could not read features: %s", readErr)
+		}
+		if !strings.HasPrefix(resp, "feature ") {
+			continue
+		}
+
+		features, parseErr := ParseFeatures(resp)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		isDone := false
+		for _, feature := range features {
+			if feature.Name == "done" {
+				isAwaitingDone = feature.Value == "0"
+				isDone = feature.Value == "1"
+				continue
+			}
+			if replyErr := c.replyToFeature(feature); replyErr != nil {
+				return nil, replyErr
+			}
+		}
+		if isDone {
+			break
+		}
+	}
+	c.CmdClient.SetFlushOnWrite(true)
+
+	features := make(map[string]string, len(c.features))
+	for name, value := range c.features {
+		features[name] = value
+	}
+	return features, nil
+}
+
+// Feature returns the value the engine declared for a feature, or the protocol default
+func (c *Client) Feature(name string) string {
+	if value, ok := c.features[name]; ok {
+		return value
+	}
+	return DefaultFeatureValue(name)
+}
+
+func (c *Client) New() error {
+	return c.write("new")
+}
+
+// Force stops the engine from playing either side, so the board can be set up freely
+func (c *Client) Force() error {
+	return c.write("force")
+}
+
+func (c *Client) SetBoard(fen string) error {
+	if c.Feature("setboard") != "1" {
+		return fmt.Errorf("engine does not support setboard")
+	}
+	return c.write(fmt.Sprintf("setboard %s", fen))
+}
+
+// Level sets a conventional or incremental time control
+func (c *Client) Level(movesPerSession uint, baseSecs uint, incrSecs uint) error {
+	baseStr := fmt.Sprintf("%d", baseSecs/60)
+	if baseSecs%60 != 0 {
+		baseStr = fmt.Sprintf("%d:%02d", baseSecs/60, baseSecs%60)
+	}
+	return c.write(fmt.Sprintf("level %d %s %d", movesPerSession, baseStr, incrSecs))
+}
+
+// Time sets the engine's clock, in centiseconds
+func (c *Client) Time(centis uint) error {
+	return c.write(fmt.Sprintf("time %d", centis))
+}
+
+// Otim sets the opponent's clock, in centiseconds
+func (c *Client) Otim(centis uint) error {
+	return c.write(fmt.Sprintf("otim %d", centis))
+}
+
+// UserMove sends the opponent's move in coordinate notation
+func (c *Client) UserMove(move string) error {
+	if c.Feature("usermove") == "1" {
+		return c.write(fmt.Sprintf("usermove %s", move))
+	}
+	return c.write(move)
+}
+
+// Go tells the engine to play the side to move, and waits for its move
+func (c *Client) Go(ctx context.Context) (string, error) {
+	if writeErr := c.write("go"); writeErr != nil {
+		return "", writeErr
+	}
+	return c.ReadMove(ctx)
+}
+
+// Interrupt makes the engine stop thinking and move at once, and puts it in force mode. The move
+// is read and discarded, so that it is not taken for the reply to a later command.
+func (c *Client) Interrupt(ctx context.Context) error {
+	// lines read before the interrupt may hold the move, so they are kept
+	c.CmdClient.SetFlushOnWrite(false)
+	writeErr := c.write("?")
+	c.CmdClient.SetFlushOnWrite(true)
+	if writeErr != nil {
+		return writeErr
+	}
+	_, readErr := c.ReadMove(ctx)
+	if forceErr := c.Force(); forceErr != nil {
+		return forceErr
+	}
+	var gameEndErr *GameEndError
+	if readErr != nil && !errors.As(readErr, &gameEndErr) {
+		return readErr
+	}
+	return nil
+}
+
+// ReadMove waits for the engine's next move. If the engine claims a result or resigns instead,
+// a GameEndError is returned.
+func (c *Client) ReadMove(ctx context.Context) (string, error) {
+	for {
+		resp, readErr := c.CmdClient.ReadLine(ctx)
+		if readErr != nil {
+			return "", fmt.Errorf("read error while listening for move: %w", readErr)
+		}
+		tokens := strings.Fields(resp)
+		if len(tokens) == 0 {
+			continue
+		}
+		switch {
+		case tokens[0] == "move" && len(tokens) >= 2:
+			return tokens[1], nil
+		case resp == "resign" || tokens[0] == "tellics" && len(tokens) >= 2 && tokens[1] == "resign":
+			return "", &GameEndError{Result: "resign"}
+		case tokens[0] == "1-0" || tokens[0] == "0-1" || tokens[0] == "1/2-1/2":
+			return "", &GameEndError{Result: tokens[0], Comment: strings.TrimSpace(strings.TrimPrefix(resp, tokens[0]))}
+		case strings.HasPrefix(resp, "Illegal move") || strings.HasPrefix(resp, "Error"):
+			return "", fmt.Errorf("engine rejected command: %s", resp)
+		}
+	}
+}
+
+// Result informs the engine of the game result, such as "1-0 {White mates}"
+func (c *Client) Result(result string, comment string) error {
+	return c.write(fmt.Sprintf("result %s {%s}", result, comment))
+}
+
+// Ping waits for the engine to finish processing all previous commands, if the engine supports
+// the ping feature
+func (c *Client) Ping(ctx context.Context) error {
+	if c.Feature("ping") != "1" {
+		return nil
+	}
+	c.pingCount++
+	if writeErr := c.write(fmt.Sprintf("ping %d", c.pingCount)); writeErr != nil {
+		return writeErr
+	}
+	expected := fmt.Sprintf("pong %d", c.pingCount)
+	for {
+		resp, readErr := c.CmdClient.ReadLine(ctx)
+		if readErr != nil {
+			return fmt.Errorf("read error while waiting for %s: %w", expected, readErr)
+		}
+		if resp == expected {
+			return nil
+		}
+	}
+}
+
+func (c *Client) Quit() error {
+	return c.write("quit")
+}
+
+func (c *Client) End() error {
+	return c.CmdClient.End()
+}
+
+func (c *Client) write(cmd string) error {
+	if writeErr := c.CmdClient.WriteLine(cmd); writeErr != nil {
+		return fmt.Errorf("could not write to xboard CmdClient: %w", writeErr)
+	}
+	return nil
+}
+
+// replyToFeature accepts every feature except san, so moves are always exchanged in
+// coordinate notation
+func (c *Client) replyToFeature(feature *Feature) error {
+	if feature.Name == "san" && feature.Value == "1" {
+		return c.write(fmt.Sprintf("rejected %s", feature.Name))
+	}
+	c.features[feature.Name] = feature.Value
+	return c.write(fmt.Sprintf("accepted %s", feature.Name))
+}
+
+type GameEndError struct {
+	Result  string
+	Comment string
+}
+
+func (e *GameEndError) Error() string {
+	return fmt.Sprintf("engine ended the game: %s %s", e.Result, e.Comment)
+}
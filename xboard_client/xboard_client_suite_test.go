@@ -0,0 +1,13 @@
+package xboard_client_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestXboardClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "XboardClient Suite")
+}
@@ -0,0 +1,187 @@
+package xboard_client_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/CameronHonis/chess-bot-server/uci_client/cmd_client"
+	"github.com/CameronHonis/chess-bot-server/xboard_client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockEngine replies to each command with the configured response, if any
+type MockEngine struct {
+	buf          *bytes.Buffer
+	respByCmd    map[string]string
+	receivedCmds []string
+	mu           sync.Mutex
+}
+
+func NewMockEngine(respByCmd map[string]string) *MockEngine {
+	return &MockEngine{
+		buf:       &bytes.Buffer{},
+		respByCmd: respByCmd,
+	}
+}
+
+func (m *MockEngine) Read(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.buf.Read(p)
+}
+
+func (m *MockEngine) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cmd := strings.TrimSuffix(string(p), "\n")
+	m.receivedCmds = append(m.receivedCmds, cmd)
+	if resp, ok := m.respByCmd[cmd]; ok {
+		m.buf.WriteString(resp)
+	}
+	return len(p), nil
+}
+
+func (m *MockEngine) Close() error {
+	return nil
+}
+
+func (m *MockEngine) ReceivedCmds() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.receivedCmds...)
+}
+
+func defaultResponses() map[string]string {
+	return map[string]string{
+		"protover 2": "feature ping=1 setboard=1 san=1 myname=\"Mock Engine 1.0\" done=1\n",
+		"ping 1":     "pong 1\n",
+		"go":         "1 20 0 100 e2e4\nmove e2e4\n",
+	}
+}
+
+var _ = Describe("XboardClient", func() {
+	var mockEngine *MockEngine
+	var client *xboard_client.Client
+	var ctx context.Context
+	var cancelCtx context.CancelFunc
+	BeforeEach(func() {
+		ctx, cancelCtx = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	})
+	AfterEach(func() {
+		cancelCtx()
+	})
+	initClient := func(respByCmd map[string]string) {
+		mockEngine = NewMockEngine(respByCmd)
+		client = xboard_client.NewXboardClient(cmd_client.DefaultClient(nil, mockEngine, mockEngine))
+	}
+
+	Describe("::Init", func() {
+		When("the engine declares its features", func() {
+			BeforeEach(func() {
+				initClient(defaultResponses())
+			})
+			It("returns the accepted features", func() {
+				features, err := client.Init(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(features).To(HaveKeyWithValue("ping", "1"))
+				Expect(features).To(HaveKeyWithValue("myname", "Mock Engine 1.0"))
+			})
+			It("rejects san moves", func() {
+				_, _ = client.Init(ctx)
+				Expect(client.Feature("san")).To(Equal("0"))
+				Expect(mockEngine.ReceivedCmds()).To(ContainElement("rejected san"))
+				Expect(mockEngine.ReceivedCmds()).To(ContainElement("accepted setboard"))
+			})
+		})
+		When("the engine does not declare any features", func() {
+			BeforeEach(func() {
+				initClient(map[string]string{})
+			})
+			It("assumes the protocol defaults", func() {
+				Expect(client.Init(ctx)).Error().ToNot(HaveOccurred())
+				Expect(client.Feature("ping")).To(Equal("0"))
+				Expect(client.Feature("time")).To(Equal("1"))
+			})
+		})
+		When("the engine never finishes declaring its features", func() {
+			BeforeEach(func() {
+				initClient(map[string]string{"protover 2": "feature done=0\n"})
+			})
+			It("returns an error", func() {
+				Expect(client.Init(ctx)).Error().To(HaveOccurred())
+			})
+		})
+	})
+	Describe("::Go", func() {
+		When("the engine replies with a move", func() {
+			BeforeEach(func() {
+				initClient(defaultResponses())
+				Expect(client.Init(ctx)).Error().ToNot(HaveOccurred())
+			})
+			It("returns the move", func() {
+				Expect(client.Go(ctx)).To(Equal("e2e4"))
+			})
+		})
+		When("the engine resigns", func() {
+			BeforeEach(func() {
+				respByCmd := defaultResponses()
+				respByCmd["go"] = "resign\n"
+				initClient(respByCmd)
+				Expect(client.Init(ctx)).Error().ToNot(HaveOccurred())
+			})
+			It("returns a GameEndError", func() {
+				_, err := client.Go(ctx)
+				Expect(err).To(BeAssignableToTypeOf(&xboard_client.GameEndError{}))
+			})
+		})
+	})
+	Describe("::Interrupt", func() {
+		BeforeEach(func() {
+			respByCmd := defaultResponses()
+			respByCmd["?"] = "move d2d4\n"
+			initClient(respByCmd)
+			Expect(client.Init(ctx)).Error().ToNot(HaveOccurred())
+		})
+		It("reads the move played at once and puts the engine in force mode", func() {
+			Expect(client.Interrupt(ctx)).To(Succeed())
+			cmds := mockEngine.ReceivedCmds()
+			Expect(cmds[len(cmds)-2:]).To(Equal([]string{"?", "force"}))
+			Expect(client.Go(ctx)).To(Equal("e2e4"))
+		})
+	})
+	Describe("::Ping", func() {
+		BeforeEach(func() {
+			initClient(defaultResponses())
+			Expect(client.Init(ctx)).Error().ToNot(HaveOccurred())
+		})
+		It("waits for the matching pong", func() {
+			Expect(client.Ping(ctx)).To(Succeed())
+		})
+	})
+	Describe("::Level", func() {
+		BeforeEach(func() {
+			initClient(defaultResponses())
+		})
+		It("formats the base time in minutes and seconds", func() {
+			Expect(client.Level(0, 90, 2)).To(Succeed())
+			Expect(mockEngine.ReceivedCmds()).To(ContainElement("level 0 1:30 2"))
+		})
+	})
+	Describe("ParseFeatures", func() {
+		It("parses quoted and unquoted values", func() {
+			features, err := xboard_client.ParseFeatures("feature ping=1 myname=\"Some Engine 1.0\" done=1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(features).To(Equal([]*xboard_client.Feature{
+				{Name: "ping", Value: "1"},
+				{Name: "myname", Value: "Some Engine 1.0"},
+				{Name: "done", Value: "1"},
+			}))
+		})
+		It("errors on an unterminated string", func() {
+			Expect(xboard_client.ParseFeatures("feature myname=\"Some Engine")).Error().To(HaveOccurred())
+		})
+	})
+})